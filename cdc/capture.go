@@ -26,7 +26,9 @@ import (
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/cdc/roles"
 	"github.com/pingcap/ticdc/pkg/flags"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb/config"
 	tidbkv "github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/store"
 	"github.com/pingcap/tidb/store/tikv"
@@ -44,11 +46,13 @@ const (
 	ownerRunInterval    = time.Millisecond * 500
 	cfWatcherRetryDelay = time.Millisecond * 500
 	captureSessionTTL   = 3
+	drainCheckInterval  = time.Second
 )
 
 // Capture represents a Capture server, it monitors the changefeed information in etcd and schedules Task on it.
 type Capture struct {
 	pdEndpoints  []string
+	credential   *security.Credential
 	etcdClient   kv.CDCEtcdClient
 	ownerManager roles.Manager
 	ownerWorker  *ownerImpl
@@ -56,6 +60,10 @@ type Capture struct {
 	processors map[string]*processor
 	procLock   sync.Mutex
 
+	// cancelCampaign stops campaigning the owner, the owner is resigned if the capture is the owner
+	cancelCampaign context.CancelFunc
+	campaignLock   sync.Mutex
+
 	info *model.CaptureInfo
 
 	// session keeps alive between the capture and etcd
@@ -63,9 +71,14 @@ type Capture struct {
 }
 
 // NewCapture returns a new Capture instance
-func NewCapture(pdEndpoints []string) (c *Capture, err error) {
+func NewCapture(pdEndpoints []string, credential *security.Credential, advertiseAddr string, gcTTL int64) (c *Capture, err error) {
+	tlsConfig, err := credential.ToTLSConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	etcdCli, err := clientv3.New(clientv3.Config{
 		Endpoints:   pdEndpoints,
+		TLS:         tlsConfig,
 		DialTimeout: 5 * time.Second,
 		DialOptions: []grpc.DialOption{
 			grpc.WithConnectParams(grpc.ConnectParams{
@@ -90,14 +103,15 @@ func NewCapture(pdEndpoints []string) (c *Capture, err error) {
 	cli := kv.NewCDCEtcdClient(etcdCli)
 	id := uuid.New().String()
 	info := &model.CaptureInfo{
-		ID: id,
+		ID:            id,
+		AdvertiseAddr: advertiseAddr,
 	}
 
-	log.Info("creating capture", zap.String("capture-id", id))
+	log.Info("creating capture", zap.String("capture-id", id), zap.String("advertise-addr", advertiseAddr))
 
 	manager := roles.NewOwnerManager(cli, id, kv.CaptureOwnerKey)
 
-	worker, err := NewOwner(pdEndpoints, cli, manager)
+	worker, err := NewOwner(pdEndpoints, credential, cli, manager, gcTTL)
 	if err != nil {
 		return nil, errors.Annotate(err, "new owner failed")
 	}
@@ -105,6 +119,7 @@ func NewCapture(pdEndpoints []string) (c *Capture, err error) {
 	c = &Capture{
 		processors:   make(map[string]*processor),
 		pdEndpoints:  pdEndpoints,
+		credential:   credential,
 		etcdClient:   cli,
 		session:      sess,
 		ownerManager: manager,
@@ -139,7 +154,11 @@ func (c *Capture) Start(ctx context.Context) (err error) {
 		return errors.Trace(err)
 	}
 
-	err = c.ownerManager.CampaignOwner(ctx)
+	campaignCtx, cancelCampaign := context.WithCancel(ctx)
+	c.campaignLock.Lock()
+	c.cancelCampaign = cancelCampaign
+	c.campaignLock.Unlock()
+	err = c.ownerManager.CampaignOwner(campaignCtx)
 	if err != nil {
 		return errors.Annotate(err, "CampaignOwner")
 	}
@@ -151,7 +170,7 @@ func (c *Capture) Start(ctx context.Context) (err error) {
 	})
 
 	rl := rate.NewLimiter(0.1, 5)
-	watcher := NewChangeFeedWatcher(c.info.ID, c.pdEndpoints, c.etcdClient)
+	watcher := NewChangeFeedWatcher(c.info.ID, c.pdEndpoints, c.credential, c.etcdClient)
 	errg.Go(func() error {
 		for {
 			if !rl.Allow() {
@@ -170,6 +189,117 @@ func (c *Capture) Start(ctx context.Context) (err error) {
 	return errg.Wait()
 }
 
+// Drain moves all the tables on the capture to the other captures before the
+// capture exits. The capture is marked as draining so the owner moves its tables
+// out, and it stops campaigning the owner, which resigns the owner if it is.
+// Drain returns after all the tables are taken over or ctx is done.
+func (c *Capture) Drain(ctx context.Context) error {
+	_, captures, err := c.etcdClient.GetCaptures(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	hasOthers := false
+	for _, info := range captures {
+		if info.ID != c.info.ID && !info.Draining {
+			hasOthers = true
+			break
+		}
+	}
+	if !hasOthers {
+		log.Info("no other capture to take over the tables, skip draining", zap.String("capture-id", c.info.ID))
+		return nil
+	}
+
+	info := *c.info
+	info.Draining = true
+	err = c.etcdClient.PutCaptureInfo(ctx, &info, c.session.Lease())
+	if err != nil {
+		return errors.Annotate(err, "mark capture as draining")
+	}
+	c.campaignLock.Lock()
+	if c.cancelCampaign != nil {
+		c.cancelCampaign()
+	}
+	c.campaignLock.Unlock()
+	log.Info("start draining capture", zap.String("capture-id", c.info.ID))
+
+	// the tables to be taken over, mapping from changefeed ID to table IDs
+	tables := make(map[model.ChangeFeedID][]uint64)
+	_, changefeeds, err := c.etcdClient.GetChangeFeeds(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for id := range changefeeds {
+		_, status, err := c.etcdClient.GetTaskStatus(ctx, id, c.info.ID)
+		if err != nil {
+			if errors.Cause(err) == model.ErrTaskStatusNotExists {
+				continue
+			}
+			return errors.Trace(err)
+		}
+		for _, table := range status.TableInfos {
+			tables[id] = append(tables[id], table.ID)
+		}
+	}
+
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	for {
+		for id, tableIDs := range tables {
+			done, err := c.isTakenOver(ctx, id, tableIDs)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if done {
+				delete(tables, id)
+			}
+		}
+		if len(tables) == 0 {
+			log.Info("capture is drained", zap.String("capture-id", c.info.ID))
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Annotatef(ctx.Err(), "wait for %d changefeeds to take over the tables", len(tables))
+		case <-ticker.C:
+		}
+	}
+}
+
+// isTakenOver checks whether all the tables of the changefeed are dispatched to
+// the other captures, or the changefeed doesn't need to be replicated any more.
+func (c *Capture) isTakenOver(ctx context.Context, changefeedID string, tableIDs []uint64) (bool, error) {
+	info, err := c.etcdClient.GetChangeFeedInfo(ctx, changefeedID)
+	if err != nil {
+		if errors.Cause(err) == model.ErrChangeFeedNotExists {
+			return true, nil
+		}
+		return false, errors.Trace(err)
+	}
+	if info.AdminJobType == model.AdminStop || info.AdminJobType == model.AdminRemove {
+		return true, nil
+	}
+	statuses, err := c.etcdClient.GetAllTaskStatus(ctx, changefeedID)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	dispatched := make(map[uint64]struct{})
+	for captureID, status := range statuses {
+		if captureID == c.info.ID {
+			continue
+		}
+		for _, table := range status.TableInfos {
+			dispatched[table.ID] = struct{}{}
+		}
+	}
+	for _, tableID := range tableIDs {
+		if _, ok := dispatched[tableID]; !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 // Cleanup cleans all dynamic resources
 func (c *Capture) Cleanup() {
 	c.procLock.Lock()
@@ -190,12 +320,21 @@ func (c *Capture) register(ctx context.Context) error {
 	return errors.Trace(c.etcdClient.PutCaptureInfo(ctx, c.info, c.session.Lease()))
 }
 
-func createTiStore(urls string) (tidbkv.Storage, error) {
+func createTiStore(urls string, credential *security.Credential) (tidbkv.Storage, error) {
 	urlv, err := flags.NewURLsValue(urls)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 
+	// The tikv driver reads the security config from the global config of TiDB
+	if credential.IsTLSEnabled() {
+		conf := *config.GetGlobalConfig()
+		conf.Security.ClusterSSLCA = credential.CAPath
+		conf.Security.ClusterSSLCert = credential.CertPath
+		conf.Security.ClusterSSLKey = credential.KeyPath
+		config.StoreGlobalConfig(&conf)
+	}
+
 	// Ignore error if it is already registered.
 	_ = store.Register("tikv", tikv.Driver{})
 
@@ -17,6 +17,7 @@ import (
 	"testing"
 
 	"github.com/pingcap/check"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/util/codec"
 )
@@ -30,7 +31,7 @@ var _ = check.Suite(&codecSuite{})
 
 func (s *codecSuite) TestDecodeRecordKey(c *check.C) {
 	recordPrefix := tablecodec.GenTableRecordPrefix(12345)
-	key := tablecodec.EncodeRecordKey(recordPrefix, 67890)
+	key := tablecodec.EncodeRecordKey(recordPrefix, kv.IntHandle(67890))
 	key, tableID, err := decodeTableID(key)
 	c.Assert(err, check.IsNil)
 	c.Assert(tableID, check.Equals, int64(12345))
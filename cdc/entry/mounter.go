@@ -26,12 +26,14 @@ import (
 	timodel "github.com/pingcap/parser/model"
 	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/ticdc/cdc/model"
+	tidbkv "github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/types"
 	"go.uber.org/zap"
 )
 
 type baseKVEntry struct {
+	StartTs  uint64
 	Ts       uint64
 	TableID  int64
 	RecordID int64
@@ -41,6 +43,10 @@ type baseKVEntry struct {
 type rowKVEntry struct {
 	baseKVEntry
 	Row map[int64]types.Datum
+
+	// PreRowExist is true if PreRow holds the row value before this change
+	PreRowExist bool
+	PreRow      map[int64]types.Datum
 }
 
 type indexKVEntry struct {
@@ -93,18 +99,52 @@ type Mounter interface {
 	Output() <-chan *model.RowChangedEvent
 }
 
+// OldValueReader is used to read the value of a key before it is changed
+type OldValueReader interface {
+	// Get returns the value of the key at the specified ts,
+	// it returns nil if the key does not exist
+	Get(ctx context.Context, key []byte, ts uint64) ([]byte, error)
+}
+
+type storageOldValueReader struct {
+	storage tidbkv.Storage
+}
+
+// NewOldValueReader creates an OldValueReader which reads old values from the snapshot of storage
+func NewOldValueReader(storage tidbkv.Storage) OldValueReader {
+	return &storageOldValueReader{storage: storage}
+}
+
+func (r *storageOldValueReader) Get(ctx context.Context, key []byte, ts uint64) ([]byte, error) {
+	snapshot, err := r.storage.GetSnapshot(tidbkv.NewVersion(ts))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	value, err := snapshot.Get(ctx, key)
+	if err != nil {
+		if tidbkv.IsErrNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Trace(err)
+	}
+	return value, nil
+}
+
 type mounterImpl struct {
 	schemaStorage   *Storage
 	rawRowChangedCh <-chan *model.RawKVEntry
 	output          chan *model.RowChangedEvent
+	// oldValueReader is nil if the old value is disabled
+	oldValueReader OldValueReader
 }
 
-// NewMounter creates a mounter
-func NewMounter(rawRowChangedCh <-chan *model.RawKVEntry, schemaStorage *Storage) Mounter {
+// NewMounter creates a mounter, oldValueReader can be nil if the old value is not needed
+func NewMounter(rawRowChangedCh <-chan *model.RawKVEntry, schemaStorage *Storage, oldValueReader OldValueReader) Mounter {
 	return &mounterImpl{
 		schemaStorage:   schemaStorage,
 		rawRowChangedCh: rawRowChangedCh,
 		output:          make(chan *model.RowChangedEvent),
+		oldValueReader:  oldValueReader,
 	}
 }
 
@@ -142,7 +182,7 @@ func (m *mounterImpl) Run(ctx context.Context) error {
 			return errors.Cause(err)
 		}
 
-		event, err := m.unmarshalAndMountRowChanged(rawRow)
+		event, err := m.unmarshalAndMountRowChanged(ctx, rawRow)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -157,7 +197,7 @@ func (m *mounterImpl) Output() <-chan *model.RowChangedEvent {
 	return m.output
 }
 
-func (m *mounterImpl) unmarshalAndMountRowChanged(raw *model.RawKVEntry) (*model.RowChangedEvent, error) {
+func (m *mounterImpl) unmarshalAndMountRowChanged(ctx context.Context, raw *model.RawKVEntry) (*model.RowChangedEvent, error) {
 	if !bytes.HasPrefix(raw.Key, tablePrefix) {
 		return nil, nil
 	}
@@ -166,6 +206,7 @@ func (m *mounterImpl) unmarshalAndMountRowChanged(raw *model.RawKVEntry) (*model
 		return nil, errors.Trace(err)
 	}
 	baseInfo := baseKVEntry{
+		StartTs: raw.StartTs,
 		Ts:      raw.Ts,
 		TableID: tableID,
 		Delete:  raw.OpType == model.OpTypeDelete,
@@ -179,6 +220,12 @@ func (m *mounterImpl) unmarshalAndMountRowChanged(raw *model.RawKVEntry) (*model
 		if rowKV == nil {
 			return nil, nil
 		}
+		if m.oldValueReader != nil {
+			err = m.fetchPreRow(ctx, raw.Key, rowKV)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
 		return m.mountRowKVEntry(rowKV)
 	case bytes.HasPrefix(key, indexPrefix):
 		indexKV, err := m.unmarshalIndexKVEntry(key, raw.Value, baseInfo)
@@ -222,6 +269,32 @@ func (m *mounterImpl) unmarshalRowKVEntry(restKey []byte, rawValue []byte, base
 	}, nil
 }
 
+// fetchPreRow reads the value of the row before the transaction which changes it.
+// The snapshot is read at StartTs-1, so the read never meets the lock of the
+// transaction itself, and all the versions committed before the transaction
+// started are visible to it. Each row costs a point get to TiKV, which is
+// why it is only done when the old value is enabled in the changefeed config.
+func (m *mounterImpl) fetchPreRow(ctx context.Context, rawKey []byte, row *rowKVEntry) error {
+	preValue, err := m.oldValueReader.Get(ctx, rawKey, row.StartTs-1)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if preValue == nil {
+		return nil
+	}
+	tableInfo, exist := m.schemaStorage.TableByID(row.TableID)
+	if !exist {
+		return errors.NotFoundf("table in schema storage, id: %d", row.TableID)
+	}
+	preRow, err := decodeRow(preValue, row.RecordID, tableInfo)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	row.PreRowExist = true
+	row.PreRow = preRow
+	return nil
+}
+
 func (m *mounterImpl) unmarshalIndexKVEntry(restKey []byte, rawValue []byte, base baseKVEntry) (*indexKVEntry, error) {
 	indexID, indexValue, err := decodeIndexKey(restKey)
 	if err != nil {
@@ -283,16 +356,62 @@ func (m *mounterImpl) mountRowKVEntry(row *rowKVEntry) (*model.RowChangedEvent,
 		return nil, errors.NotFoundf("table in schema storage, id: %d", row.TableID)
 	}
 
-	if row.Delete && !tableInfo.PKIsHandle {
-		return nil, nil
+	event := &model.RowChangedEvent{
+		StartTs:      row.StartTs,
+		Ts:           row.Ts,
+		Resolved:     false,
+		Schema:       tableName.Schema,
+		Table:        tableName.Table,
+		IndieMarkCol: tableInfo.IndieMarkCol,
+		TableInfo:    tableInfo.TableInfo,
 	}
 
-	datumsNum := 1
-	if !row.Delete {
+	var err error
+	if row.Delete {
+		event.Type = model.DeleteDMLType
+		if row.PreRowExist {
+			event.PreColumns, err = mountColumns(tableInfo, row.PreRow, true)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return event, nil
+		}
+		// without the old value, only the handle is known,
+		// the delete event of a table whose pk is not handle is derived from the index kv
+		if !tableInfo.PKIsHandle {
+			return nil, nil
+		}
+		event.PreColumns, err = mountColumns(tableInfo, row.Row, false)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return event, nil
+	}
+
+	event.Type = model.InsertDMLType
+	event.Columns, err = mountColumns(tableInfo, row.Row, true)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if row.PreRowExist {
+		event.Type = model.UpdateDMLType
+		event.PreColumns, err = mountColumns(tableInfo, row.PreRow, true)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return event, nil
+}
+
+// mountColumns formats the datums of a row to columns,
+// the missing columns are filled by the default value if fillDefault is true
+func mountColumns(tableInfo *TableInfo, row map[int64]types.Datum, fillDefault bool) (map[string]*model.Column, error) {
+	datumsNum := len(row)
+	if fillDefault {
 		datumsNum = len(tableInfo.Columns)
 	}
 	values := make(map[string]*model.Column, datumsNum)
-	for index, colValue := range row.Row {
+	for index, colValue := range row {
 		colInfo, exist := tableInfo.GetColumnInfo(index)
 		if !exist {
 			return nil, errors.NotFoundf("column info, colID: %d", index)
@@ -312,15 +431,7 @@ func (m *mounterImpl) mountRowKVEntry(row *rowKVEntry) (*model.RowChangedEvent,
 		}
 	}
 
-	event := &model.RowChangedEvent{
-		Ts:           row.Ts,
-		Resolved:     false,
-		Schema:       tableName.Schema,
-		Table:        tableName.Table,
-		IndieMarkCol: tableInfo.IndieMarkCol,
-	}
-
-	if !row.Delete {
+	if fillDefault {
 		for _, col := range tableInfo.Columns {
 			_, ok := values[col.Name.O]
 			if !ok && tableInfo.IsColWritable(col) {
@@ -332,9 +443,7 @@ func (m *mounterImpl) mountRowKVEntry(row *rowKVEntry) (*model.RowChangedEvent,
 			}
 		}
 	}
-	event.Delete = row.Delete
-	event.Columns = values
-	return event, nil
+	return values, nil
 }
 
 func (m *mounterImpl) mountIndexKVEntry(idx *indexKVEntry) (*model.RowChangedEvent, error) {
@@ -342,6 +451,10 @@ func (m *mounterImpl) mountIndexKVEntry(idx *indexKVEntry) (*model.RowChangedEve
 	if !idx.Delete {
 		return nil, nil
 	}
+	// the delete event is mounted from the old value of the row kv if the old value is enabled
+	if m.oldValueReader != nil {
+		return nil, nil
+	}
 	tableInfo, tableName, exist := m.fetchTableInfo(idx.TableID)
 	if !exist {
 		if m.schemaStorage.IsTruncateTableID(idx.TableID) {
@@ -377,13 +490,15 @@ func (m *mounterImpl) mountIndexKVEntry(idx *indexKVEntry) (*model.RowChangedEve
 		}
 	}
 	return &model.RowChangedEvent{
+		StartTs:      idx.StartTs,
 		Ts:           idx.Ts,
 		Resolved:     false,
 		Schema:       tableName.Schema,
 		Table:        tableName.Table,
 		IndieMarkCol: tableInfo.IndieMarkCol,
-		Delete:       true,
-		Columns:      values,
+		Type:         model.DeleteDMLType,
+		PreColumns:   values,
+		TableInfo:    tableInfo.TableInfo,
 	}, nil
 }
 
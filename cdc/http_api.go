@@ -0,0 +1,532 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/roles"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"go.etcd.io/etcd/clientv3/concurrency"
+	"go.uber.org/zap"
+)
+
+const (
+	apiV1Prefix           = "/api/v1"
+	apiV1ChangefeedPrefix = apiV1Prefix + "/changefeeds/"
+
+	// forwardFromHeader is set when the request is forwarded to the owner
+	forwardFromHeader     = "X-Cdc-Forward-From"
+	forwardRequestTimeout = 10 * time.Second
+)
+
+// APIError is the structured error returned by the REST API
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ChangefeedConfig is the request body to create or update a changefeed,
+// the empty fields are not updated in an update request.
+type ChangefeedConfig struct {
+	ID       string              `json:"changefeed-id"`
+	SinkURI  string              `json:"sink-uri"`
+	StartTs  uint64              `json:"start-ts"`
+	TargetTs uint64              `json:"target-ts"`
+	SortDir  string              `json:"sort-dir"`
+	Opts     map[string]string   `json:"opts"`
+	Config   *util.ReplicaConfig `json:"config"`
+}
+
+// ChangefeedDetail holds the info and status of a changefeed
+type ChangefeedDetail struct {
+	ID     string                  `json:"id"`
+	Info   *model.ChangeFeedInfo   `json:"info"`
+	Status *model.ChangeFeedStatus `json:"status"`
+}
+
+// MoveTableRequest is the request body to move a table to the target capture
+type MoveTableRequest struct {
+	TableID         uint64 `json:"table-id"`
+	TargetCaptureID string `json:"target-capture-id"`
+}
+
+// CaptureDetail holds the info of a capture
+type CaptureDetail struct {
+	ID            string `json:"id"`
+	AdvertiseAddr string `json:"address"`
+	IsOwner       bool   `json:"is-owner"`
+	Draining      bool   `json:"draining"`
+}
+
+func (s *Server) registerAPIV1(serverMux *http.ServeMux) {
+	serverMux.HandleFunc(apiV1Prefix+"/captures", s.handleAPICaptures)
+	serverMux.HandleFunc(apiV1Prefix+"/processors", s.handleAPIProcessors)
+	serverMux.HandleFunc(apiV1Prefix+"/changefeeds", s.handleAPIChangefeeds)
+	serverMux.HandleFunc(apiV1ChangefeedPrefix, s.handleAPIChangefeed)
+}
+
+func writeAPIError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, APIError{Code: code, Message: err.Error()})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	writeAPIError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s is not allowed", req.Method))
+}
+
+// writeEtcdError writes the error of accessing etcd, the not exist errors are responded as 404
+// and the conflicts of writing the changefeed info are responded as 409
+func writeEtcdError(w http.ResponseWriter, err error) {
+	switch errors.Cause(err) {
+	case model.ErrChangeFeedNotExists, model.ErrTaskStatusNotExists, model.ErrTaskPositionNotExists,
+		model.ErrCaptureNotExist:
+		writeAPIError(w, http.StatusNotFound, err)
+	case model.ErrChangeFeedExists, model.ErrChangeFeedInfoConflict:
+		writeAPIError(w, http.StatusConflict, err)
+	default:
+		writeAPIError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// parseChangefeedPath parses the changefeed id and the sub resource from
+// the path in the format of `/api/v1/changefeeds/{id}[/{sub}]`
+func parseChangefeedPath(path string) (id string, sub string, ok bool) {
+	if !strings.HasPrefix(path, apiV1ChangefeedPrefix) {
+		return "", "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(path, apiV1ChangefeedPrefix), "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return parts[0], "", true
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return parts[0], parts[1], true
+	}
+	return "", "", false
+}
+
+func (s *Server) handleAPICaptures(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeMethodNotAllowed(w, req)
+		return
+	}
+	ctx := req.Context()
+	_, captures, err := s.capture.etcdClient.GetCaptures(ctx)
+	if err != nil {
+		writeEtcdError(w, err)
+		return
+	}
+	ownerID, err := roles.GetOwnerID(ctx, s.capture.etcdClient, kv.CaptureOwnerKey)
+	if err != nil && errors.Cause(err) != concurrency.ErrElectionNoLeader {
+		writeEtcdError(w, err)
+		return
+	}
+	details := make([]*CaptureDetail, 0, len(captures))
+	for _, c := range captures {
+		details = append(details, &CaptureDetail{
+			ID:            c.ID,
+			AdvertiseAddr: c.AdvertiseAddr,
+			IsOwner:       c.ID == ownerID,
+			Draining:      c.Draining,
+		})
+	}
+	writeData(w, details)
+}
+
+func (s *Server) handleAPIProcessors(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeMethodNotAllowed(w, req)
+		return
+	}
+	_, processors, err := s.capture.etcdClient.GetAllProcessors(req.Context())
+	if err != nil {
+		writeEtcdError(w, err)
+		return
+	}
+	writeData(w, processors)
+}
+
+func (s *Server) handleAPIChangefeeds(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		s.listChangefeeds(w, req)
+	case http.MethodPost:
+		s.createChangefeed(w, req)
+	default:
+		writeMethodNotAllowed(w, req)
+	}
+}
+
+func (s *Server) handleAPIChangefeed(w http.ResponseWriter, req *http.Request) {
+	id, sub, ok := parseChangefeedPath(req.URL.Path)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, errors.Errorf("invalid path %s", req.URL.Path))
+		return
+	}
+	switch {
+	case sub == "" && req.Method == http.MethodGet:
+		s.getChangefeed(w, req, id)
+	case sub == "" && req.Method == http.MethodPut:
+		s.updateChangefeed(w, req, id)
+	case sub == "" && req.Method == http.MethodDelete:
+		s.adminChangefeed(w, req, id, model.AdminRemove)
+	case sub == "tables" && req.Method == http.MethodGet:
+		s.getChangefeedTables(w, req, id)
+	case sub == "pause" && req.Method == http.MethodPost:
+		s.adminChangefeed(w, req, id, model.AdminStop)
+	case sub == "resume" && req.Method == http.MethodPost:
+		s.adminChangefeed(w, req, id, model.AdminResume)
+	case sub == "move-table" && req.Method == http.MethodPost:
+		s.moveTable(w, req, id)
+	case sub == "" || sub == "tables" || sub == "pause" || sub == "resume" || sub == "move-table":
+		writeMethodNotAllowed(w, req)
+	default:
+		writeAPIError(w, http.StatusNotFound, errors.Errorf("invalid path %s", req.URL.Path))
+	}
+}
+
+func (s *Server) getChangefeedDetail(req *http.Request, id string) (*ChangefeedDetail, error) {
+	info, err := s.capture.etcdClient.GetChangeFeedInfo(req.Context(), id)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	status, err := s.capture.etcdClient.GetChangeFeedStatus(req.Context(), id)
+	if err != nil && errors.Cause(err) != model.ErrChangeFeedNotExists {
+		return nil, errors.Trace(err)
+	}
+	return &ChangefeedDetail{ID: id, Info: info, Status: status}, nil
+}
+
+func (s *Server) listChangefeeds(w http.ResponseWriter, req *http.Request) {
+	_, raw, err := s.capture.etcdClient.GetChangeFeeds(req.Context())
+	if err != nil {
+		writeEtcdError(w, err)
+		return
+	}
+	details := make([]*ChangefeedDetail, 0, len(raw))
+	for id := range raw {
+		detail, err := s.getChangefeedDetail(req, id)
+		if err != nil {
+			// the changefeed may be removed after listed
+			if errors.Cause(err) == model.ErrChangeFeedNotExists {
+				continue
+			}
+			writeEtcdError(w, err)
+			return
+		}
+		details = append(details, detail)
+	}
+	writeData(w, details)
+}
+
+func (s *Server) getChangefeed(w http.ResponseWriter, req *http.Request, id string) {
+	detail, err := s.getChangefeedDetail(req, id)
+	if err != nil {
+		writeEtcdError(w, err)
+		return
+	}
+	writeData(w, detail)
+}
+
+func (s *Server) getChangefeedTables(w http.ResponseWriter, req *http.Request, id string) {
+	if _, err := s.capture.etcdClient.GetChangeFeedInfo(req.Context(), id); err != nil {
+		writeEtcdError(w, err)
+		return
+	}
+	statuses, err := s.capture.etcdClient.GetAllTaskStatus(req.Context(), id)
+	if err != nil {
+		writeEtcdError(w, err)
+		return
+	}
+	tables := make(map[model.CaptureID][]*model.ProcessTableInfo, len(statuses))
+	for captureID, status := range statuses {
+		tables[captureID] = status.TableInfos
+	}
+	writeData(w, tables)
+}
+
+func decodeChangefeedConfig(req *http.Request) (*ChangefeedConfig, error) {
+	cfg := new(ChangefeedConfig)
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, errors.Annotate(err, "invalid changefeed config")
+	}
+	return cfg, nil
+}
+
+func (s *Server) createChangefeed(w http.ResponseWriter, req *http.Request) {
+	cfg, err := decodeChangefeedConfig(req)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if cfg.SinkURI == "" {
+		writeAPIError(w, http.StatusBadRequest, errors.New("sink-uri is required"))
+		return
+	}
+	if err := sink.ValidateSinkURI(cfg.SinkURI); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if cfg.ID == "" {
+		cfg.ID = uuid.New().String()
+	}
+	ctx := req.Context()
+	if cfg.StartTs == 0 {
+		ts, logical, err := s.capture.ownerWorker.pdClient.GetTS(ctx)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, errors.Annotate(err, "get ts from pd"))
+			return
+		}
+		cfg.StartTs = oracle.ComposeTS(ts, logical)
+	}
+	if err := s.capture.etcdClient.VerifyStartTs(ctx, cfg.StartTs); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if cfg.TargetTs != 0 && cfg.TargetTs <= cfg.StartTs {
+		writeAPIError(w, http.StatusBadRequest, errors.Errorf("target-ts %d must be larger than start-ts %d", cfg.TargetTs, cfg.StartTs))
+		return
+	}
+	if cfg.Config == nil {
+		cfg.Config = new(util.ReplicaConfig)
+	}
+	if _, err := util.NewFilter(cfg.Config); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errors.Annotate(err, "invalid filter config"))
+		return
+	}
+	if cfg.Opts == nil {
+		cfg.Opts = make(map[string]string)
+	}
+	info := &model.ChangeFeedInfo{
+		SinkURI:    cfg.SinkURI,
+		Opts:       cfg.Opts,
+		CreateTime: time.Now(),
+		StartTs:    cfg.StartTs,
+		TargetTs:   cfg.TargetTs,
+		SortDir:    cfg.SortDir,
+		Config:     cfg.Config,
+	}
+	if err := s.capture.etcdClient.CreateChangeFeedInfo(ctx, info, cfg.ID); err != nil {
+		writeEtcdError(w, err)
+		return
+	}
+	log.Info("create changefeed by api", zap.String("changefeed", cfg.ID), zap.Reflect("info", info))
+	writeJSON(w, http.StatusCreated, &ChangefeedDetail{ID: cfg.ID, Info: info})
+}
+
+func (s *Server) updateChangefeed(w http.ResponseWriter, req *http.Request, id string) {
+	cfg, err := decodeChangefeedConfig(req)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if cfg.ID != "" && cfg.ID != id {
+		writeAPIError(w, http.StatusBadRequest, errors.New("changefeed-id can't be updated"))
+		return
+	}
+	if cfg.StartTs != 0 || cfg.TargetTs != 0 || cfg.SortDir != "" {
+		writeAPIError(w, http.StatusBadRequest, errors.New("only sink-uri, opts and config can be updated"))
+		return
+	}
+	ctx := req.Context()
+	info, modRevision, err := s.capture.etcdClient.GetChangeFeedInfoWithRevision(ctx, id)
+	if err != nil {
+		writeEtcdError(w, err)
+		return
+	}
+	if info.AdminJobType != model.AdminStop {
+		writeAPIError(w, http.StatusBadRequest, errors.Errorf("changefeed %s must be paused before updating", id))
+		return
+	}
+	if cfg.SinkURI != "" {
+		if err := sink.ValidateSinkURI(cfg.SinkURI); err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		info.SinkURI = cfg.SinkURI
+	}
+	if cfg.Opts != nil {
+		info.Opts = cfg.Opts
+	}
+	if cfg.Config != nil {
+		if _, err := util.NewFilter(cfg.Config); err != nil {
+			writeAPIError(w, http.StatusBadRequest, errors.Annotate(err, "invalid filter config"))
+			return
+		}
+		info.Config = cfg.Config
+	}
+	if _, err := s.capture.etcdClient.UpdateChangeFeedInfo(ctx, info, id, modRevision); err != nil {
+		writeEtcdError(w, err)
+		return
+	}
+	log.Info("update changefeed by api", zap.String("changefeed", id), zap.Reflect("info", info))
+	writeData(w, &ChangefeedDetail{ID: id, Info: info})
+}
+
+// adminChangefeed applies the admin job to the changefeed by the owner,
+// the request is forwarded to the owner if the capture is not the owner.
+func (s *Server) adminChangefeed(w http.ResponseWriter, req *http.Request, id string, job model.AdminJobType) {
+	info, err := s.capture.etcdClient.GetChangeFeedInfo(req.Context(), id)
+	if err != nil {
+		writeEtcdError(w, err)
+		return
+	}
+	if err := info.VerifyAdminJob(job); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errors.Annotatef(err, "changefeed %s", id))
+		return
+	}
+	if !s.capture.ownerManager.IsOwner() {
+		s.forwardToOwner(w, req)
+		return
+	}
+	err = s.capture.ownerWorker.EnqueueJob(model.AdminJob{CfID: id, Type: job})
+	if err != nil {
+		if errors.Cause(err) == concurrency.ErrElectionNotLeader {
+			writeAPIError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, commonResp{Status: true, Message: fmt.Sprintf("%s %s", job, id)})
+}
+
+// moveTable moves a table of the changefeed to the target capture by the owner,
+// the request is forwarded to the owner if the capture is not the owner.
+func (s *Server) moveTable(w http.ResponseWriter, req *http.Request, id string) {
+	// the body is forwarded as is, so it's decoded by the owner only
+	if !s.capture.ownerManager.IsOwner() {
+		s.forwardToOwner(w, req)
+		return
+	}
+	moveReq := new(MoveTableRequest)
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(moveReq); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errors.Annotate(err, "invalid move table request"))
+		return
+	}
+	ctx := req.Context()
+	info, err := s.capture.etcdClient.GetChangeFeedInfo(ctx, id)
+	if err != nil {
+		writeEtcdError(w, err)
+		return
+	}
+	if err := info.VerifyAdminJob(model.AdminMoveTable); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errors.Annotatef(err, "changefeed %s", id))
+		return
+	}
+	if _, err := s.capture.etcdClient.GetCaptureInfo(ctx, moveReq.TargetCaptureID); err != nil {
+		writeEtcdError(w, errors.Annotatef(err, "target capture %s", moveReq.TargetCaptureID))
+		return
+	}
+	err = s.enqueueJobAndWait(ctx, model.AdminJob{
+		CfID:            id,
+		Type:            model.AdminMoveTable,
+		TableID:         moveReq.TableID,
+		TargetCaptureID: moveReq.TargetCaptureID,
+	})
+	if err != nil {
+		switch errors.Cause(err) {
+		case concurrency.ErrElectionNotLeader:
+			writeAPIError(w, http.StatusServiceUnavailable, err)
+		case context.DeadlineExceeded:
+			writeAPIError(w, http.StatusGatewayTimeout, err)
+		default:
+			writeAPIError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+	// the table is removed from the source capture, and it will be dispatched
+	// to the target capture asynchronously
+	writeJSON(w, http.StatusAccepted, commonResp{
+		Status:  true,
+		Message: fmt.Sprintf("move table %d of %s to %s", moveReq.TableID, id, moveReq.TargetCaptureID),
+	})
+}
+
+// forwardToOwner forwards the request to the status server of the owner
+func (s *Server) forwardToOwner(w http.ResponseWriter, req *http.Request) {
+	if from := req.Header.Get(forwardFromHeader); from != "" {
+		writeAPIError(w, http.StatusServiceUnavailable,
+			errors.Errorf("the request forwarded from %s is received by a non-owner capture", from))
+		return
+	}
+	ctx := req.Context()
+	ownerID, err := roles.GetOwnerID(ctx, s.capture.etcdClient, kv.CaptureOwnerKey)
+	if err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, errors.Annotate(err, "get owner"))
+		return
+	}
+	owner, err := s.capture.etcdClient.GetCaptureInfo(ctx, ownerID)
+	if err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, errors.Annotate(err, "get owner info"))
+		return
+	}
+	if owner.AdvertiseAddr == "" {
+		writeAPIError(w, http.StatusServiceUnavailable, errors.Errorf("the address of owner %s is unknown", ownerID))
+		return
+	}
+
+	tlsConfig, err := s.opts.credential.ToTLSConfig()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	scheme := "http"
+	client := &http.Client{Timeout: forwardRequestTimeout}
+	if tlsConfig != nil {
+		scheme = "https"
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+	url := fmt.Sprintf("%s://%s%s", scheme, owner.AdvertiseAddr, req.URL.RequestURI())
+	forwardReq, err := http.NewRequest(req.Method, url, req.Body)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	forwardReq = forwardReq.WithContext(ctx)
+	forwardReq.Header = req.Header.Clone()
+	forwardReq.Header.Set(forwardFromHeader, s.capture.info.ID)
+
+	resp, err := client.Do(forwardReq)
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, errors.Annotatef(err, "forward request to owner %s", ownerID))
+		return
+	}
+	defer resp.Body.Close()
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		log.Warn("copy forwarded response failed", zap.String("owner", ownerID), zap.Error(err))
+	}
+}
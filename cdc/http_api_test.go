@@ -0,0 +1,82 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+type httpAPISuite struct{}
+
+var _ = check.Suite(&httpAPISuite{})
+
+func (s *httpAPISuite) TestParseChangefeedPath(c *check.C) {
+	testCases := []struct {
+		path string
+		id   string
+		sub  string
+		ok   bool
+	}{
+		{path: "/api/v1/changefeeds/test-cf", id: "test-cf", ok: true},
+		{path: "/api/v1/changefeeds/test-cf/tables", id: "test-cf", sub: "tables", ok: true},
+		{path: "/api/v1/changefeeds/test-cf/pause", id: "test-cf", sub: "pause", ok: true},
+		{path: "/api/v1/changefeeds/", ok: false},
+		{path: "/api/v1/changefeeds/test-cf/", ok: false},
+		{path: "/api/v1/changefeeds/test-cf/tables/1", ok: false},
+		{path: "/api/v1/captures", ok: false},
+	}
+	for _, tc := range testCases {
+		id, sub, ok := parseChangefeedPath(tc.path)
+		c.Assert(ok, check.Equals, tc.ok, check.Commentf("path: %s", tc.path))
+		c.Assert(id, check.Equals, tc.id)
+		c.Assert(sub, check.Equals, tc.sub)
+	}
+}
+
+func (s *httpAPISuite) TestWriteAPIError(c *check.C) {
+	testCases := []struct {
+		err  error
+		code int
+	}{
+		{err: errors.Annotate(model.ErrChangeFeedNotExists, "get changefeed"), code: http.StatusNotFound},
+		{err: errors.Annotate(model.ErrChangeFeedExists, "create changefeed"), code: http.StatusConflict},
+		{err: errors.Annotate(model.ErrChangeFeedInfoConflict, "update changefeed"), code: http.StatusConflict},
+		{err: errors.New("etcd is unavailable"), code: http.StatusInternalServerError},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		writeEtcdError(w, tc.err)
+		c.Assert(w.Code, check.Equals, tc.code)
+		c.Assert(w.Header().Get("Content-Type"), check.Equals, "application/json")
+		var apiErr APIError
+		c.Assert(json.Unmarshal(w.Body.Bytes(), &apiErr), check.IsNil)
+		c.Assert(apiErr, check.DeepEquals, APIError{Code: tc.code, Message: tc.err.Error()})
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/changefeeds/test-cf", nil)
+	(&Server{}).handleAPIChangefeed(w, req)
+	c.Assert(w.Code, check.Equals, http.StatusMethodNotAllowed)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/changefeeds/test-cf/unknown", nil)
+	(&Server{}).handleAPIChangefeed(w, req)
+	c.Assert(w.Code, check.Equals, http.StatusNotFound)
+}
@@ -14,8 +14,10 @@
 package cdc
 
 import (
+	"context"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/ticdc/cdc/model"
@@ -23,8 +25,14 @@ import (
 )
 
 const (
-	opVarAdminJob     = "admin-job"
-	opVarChangefeedID = "cf-id"
+	opVarAdminJob        = "admin-job"
+	opVarChangefeedID    = "cf-id"
+	opVarTableID         = "table-id"
+	opVarTargetCaptureID = "target-cp-id"
+
+	// adminJobResultTimeout is the max duration waiting for the owner to handle
+	// an admin job, it's shorter than forwardRequestTimeout.
+	adminJobResultTimeout = 5 * time.Second
 )
 
 type commonResp struct {
@@ -76,3 +84,47 @@ func (s *Server) handleChangefeedAdmin(w http.ResponseWriter, req *http.Request)
 	err = s.capture.ownerWorker.EnqueueJob(job)
 	handleOwnerResp(w, err)
 }
+
+func (s *Server) handleMoveTable(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusBadRequest, errors.New("this api only supports POST method"))
+		return
+	}
+	err := req.ParseForm()
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	tableIDStr := req.Form.Get(opVarTableID)
+	tableID, err := strconv.ParseUint(tableIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.Errorf("invalid table id: %s", tableIDStr))
+		return
+	}
+	job := model.AdminJob{
+		CfID:            req.Form.Get(opVarChangefeedID),
+		Type:            model.AdminMoveTable,
+		TableID:         tableID,
+		TargetCaptureID: req.Form.Get(opVarTargetCaptureID),
+	}
+	err = s.enqueueJobAndWait(req.Context(), job)
+	handleOwnerResp(w, err)
+}
+
+// enqueueJobAndWait enqueues the admin job to the owner and waits until the
+// owner handles it, the error of handling the job is returned.
+func (s *Server) enqueueJobAndWait(ctx context.Context, job model.AdminJob) error {
+	result := make(chan error, 1)
+	job.Result = result
+	if err := s.capture.ownerWorker.EnqueueJob(job); err != nil {
+		return errors.Trace(err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, adminJobResultTimeout)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+		return errors.Annotatef(ctx.Err(), "wait for the owner to %s", job.Type)
+	case err := <-result:
+		return errors.Trace(err)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"net/http/pprof"
 	"os"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/prometheus/client_golang/prometheus"
@@ -32,7 +33,7 @@ import (
 
 const defaultStatusPort = 8300
 
-func (s *Server) startStatusHTTP() {
+func (s *Server) startStatusHTTP() error {
 	serverMux := http.NewServeMux()
 
 	serverMux.HandleFunc("/debug/pprof/", pprof.Index)
@@ -45,19 +46,33 @@ func (s *Server) startStatusHTTP() {
 	serverMux.HandleFunc("/debug/info", s.handleDebugInfo)
 	serverMux.HandleFunc("/capture/owner/resign", s.handleResignOwner)
 	serverMux.HandleFunc("/capture/owner/admin", s.handleChangefeedAdmin)
+	serverMux.HandleFunc("/capture/owner/move_table", s.handleMoveTable)
+	s.registerAPIV1(serverMux)
 
 	prometheus.DefaultGatherer = registry
 	serverMux.Handle("/metrics", promhttp.Handler())
 
 	addr := fmt.Sprintf("%s:%d", s.opts.statusHost, s.opts.statusPort)
 	s.statusServer = &http.Server{Addr: addr, Handler: serverMux}
-	log.Info("status http server is running", zap.String("addr", addr))
+	tlsConfig, err := s.opts.credential.ToTLSConfigWithVerify()
+	if err != nil {
+		return errors.Annotate(err, "create tls config for status server")
+	}
+	s.statusServer.TLSConfig = tlsConfig
+	log.Info("status http server is running", zap.String("addr", addr), zap.Bool("tls", tlsConfig != nil))
 	go func() {
-		err := s.statusServer.ListenAndServe()
+		var err error
+		if tlsConfig != nil {
+			// the certificates are loaded in the tls config
+			err = s.statusServer.ListenAndServeTLS("", "")
+		} else {
+			err = s.statusServer.ListenAndServe()
+		}
 		if err != nil && err != http.ErrServerClosed {
 			log.Error("status server error", zap.Error(err))
 		}
 	}()
+	return nil
 }
 
 // status of cdc server
@@ -119,6 +134,10 @@ func writeError(w http.ResponseWriter, statusCode int, err error) {
 }
 
 func writeData(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, http.StatusOK, data)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	js, err := json.MarshalIndent(data, "", " ")
 	if err != nil {
 		log.Error("invalid json data", zap.Reflect("data", data), zap.Error(err))
@@ -126,7 +145,7 @@ func writeData(w http.ResponseWriter, data interface{}) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(statusCode)
 	_, err = w.Write(js)
 	if err != nil {
 		log.Error("fail to write data", zap.Error(err))
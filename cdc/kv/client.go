@@ -25,9 +25,10 @@ import (
 	"github.com/pingcap/kvproto/pkg/cdcpb"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/log"
-	pd "github.com/pingcap/pd/client"
+	pd "github.com/pingcap/pd/v4/client"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/pkg/retry"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	tidbkv "github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/store/tikv"
@@ -54,6 +55,8 @@ type singleRegionInfo struct {
 	ts           uint64
 	failStoreIDs map[uint64]struct{}
 	rpcCtx       *tikv.RPCContext
+	// state is set when the request of the region is sent
+	state *regionFeedState
 }
 
 func newSingleRegionInfo(verID tikv.RegionVerID, span util.Span, ts uint64, rpcCtx *tikv.RPCContext) singleRegionInfo {
@@ -112,26 +115,164 @@ func (m *syncRegionInfoMap) takeAll() map[uint64]singleRegionInfo {
 	return oldMap
 }
 
+func (m *syncRegionInfoMap) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.regionInfoMap)
+}
+
+// eventFeedStream is an EventFeed stream to a store and the regions requested by it.
+// After a stream is replaced by a new one, it's closed once all of its regions stop.
+type eventFeedStream struct {
+	client cdcpb.ChangeData_EventFeedClient
+	ctx    context.Context
+	cancel context.CancelFunc
+	// pendingRegions are the regions waiting for the first response
+	pendingRegions *syncRegionInfoMap
+	// activeRegions is the number of the region feeds started by the stream and not stopped yet
+	activeRegions int32
+	retired       int32
+}
+
+func newEventFeedStream(ctx context.Context, cancel context.CancelFunc, client cdcpb.ChangeData_EventFeedClient) *eventFeedStream {
+	return &eventFeedStream{
+		client:         client,
+		ctx:            ctx,
+		cancel:         cancel,
+		pendingRegions: newSyncRegionInfoMap(),
+	}
+}
+
+func (s *eventFeedStream) isRetired() bool {
+	return atomic.LoadInt32(&s.retired) > 0
+}
+
+// retire marks that no more regions will be requested by the stream
+func (s *eventFeedStream) retire() {
+	atomic.StoreInt32(&s.retired, 1)
+	s.closeIfIdle()
+}
+
+func (s *eventFeedStream) regionStarted() {
+	atomic.AddInt32(&s.activeRegions, 1)
+}
+
+func (s *eventFeedStream) regionStopped() {
+	atomic.AddInt32(&s.activeRegions, -1)
+	s.closeIfIdle()
+}
+
+func (s *eventFeedStream) closeIfIdle() {
+	if s.isRetired() && atomic.LoadInt32(&s.activeRegions) == 0 && s.pendingRegions.len() == 0 {
+		s.cancel()
+	}
+}
+
+// regionFeedState is the state of the feed to a region
+type regionFeedState struct {
+	span         util.Span
+	checkpointTs uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newRegionFeedState(ctx context.Context, span util.Span, ts uint64) *regionFeedState {
+	ctx, cancel := context.WithCancel(ctx)
+	return &regionFeedState{
+		span:         span,
+		checkpointTs: ts,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+func (s *regionFeedState) getCheckpointTs() uint64 {
+	return atomic.LoadUint64(&s.checkpointTs)
+}
+
+func (s *regionFeedState) isStopped() bool {
+	return s.ctx.Err() != nil
+}
+
+// regionFeedStates records the feeds of the regions in an EventFeed.
+// After some regions are merged, their spans are divided to the same region,
+// and the feeds to the region are merged into one to avoid the overlapping feeds.
+type regionFeedStates struct {
+	mu     sync.Mutex
+	states map[uint64]*regionFeedState
+}
+
+func newRegionFeedStates() *regionFeedStates {
+	return &regionFeedStates{
+		states: make(map[uint64]*regionFeedState),
+	}
+}
+
+// add records the feed of the region, and returns the existing feed of the region if any
+func (s *regionFeedStates) add(regionID uint64, state *regionFeedState) (*regionFeedState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.states[regionID]
+	s.states[regionID] = state
+	return old, ok
+}
+
+// remove removes the feed of the region if it's the recorded one
+func (s *regionFeedStates) remove(regionID uint64, state *regionFeedState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.states[regionID] == state {
+		delete(s.states, regionID)
+	}
+}
+
+// len returns the number of the region feeds
+func (s *regionFeedStates) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.states)
+}
+
+// mergeSpan returns the smallest span covering both of the spans
+func mergeSpan(lhs, rhs util.Span) util.Span {
+	merged := lhs
+	if util.StartCompare(rhs.Start, merged.Start) < 0 {
+		merged.Start = rhs.Start
+	}
+	if util.EndCompare(rhs.End, merged.End) > 0 {
+		merged.End = rhs.End
+	}
+	return merged
+}
+
 type connArray struct {
 	target string
 	index  uint32
 	v      []*grpc.ClientConn
 }
 
-func newConnArray(ctx context.Context, maxSize uint, addr string) (*connArray, error) {
+func newConnArray(ctx context.Context, maxSize uint, addr string, credential *security.Credential) (*connArray, error) {
 	a := &connArray{
 		target: addr,
 		index:  0,
 		v:      make([]*grpc.ClientConn, maxSize),
 	}
-	err := a.Init(ctx)
+	err := a.Init(ctx, credential)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 	return a, nil
 }
 
-func (a *connArray) Init(ctx context.Context) error {
+func (a *connArray) Init(ctx context.Context, credential *security.Credential) error {
+	grpcTLSOption, err := credential.ToGRPCDialOption()
+	if err != nil {
+		return errors.Trace(err)
+	}
 	for i := range a.v {
 		ctx, cancel := context.WithTimeout(ctx, dialTimeout)
 
@@ -140,7 +281,7 @@ func (a *connArray) Init(ctx context.Context) error {
 			a.target,
 			grpc.WithInitialWindowSize(grpcInitialWindowSize),
 			grpc.WithInitialConnWindowSize(grpcInitialConnWindowSize),
-			grpc.WithInsecure(),
+			grpcTLSOption,
 			grpc.WithConnectParams(grpc.ConnectParams{
 				Backoff: gbackoff.Config{
 					BaseDelay:  time.Second,
@@ -187,38 +328,50 @@ func (a *connArray) Close() {
 
 // CDCClient to get events from TiKV
 type CDCClient struct {
-	pd pd.Client
+	pd         pd.Client
+	credential *security.Credential
 
 	clusterID uint64
 
 	mu struct {
 		sync.Mutex
 		conns map[string]*connArray
+		// feeds records the region feeds of the running EventFeeds
+		feeds map[*regionFeedStates]struct{}
 	}
 
 	regionCache *tikv.RegionCache
 }
 
 // NewCDCClient creates a CDCClient instance
-func NewCDCClient(pd pd.Client) (c *CDCClient, err error) {
+func NewCDCClient(pd pd.Client, credential *security.Credential) (c *CDCClient, err error) {
 	clusterID := pd.GetClusterID(context.Background())
 	log.Info("get clusterID", zap.Uint64("id", clusterID))
 
 	c = &CDCClient{
 		clusterID:   clusterID,
 		pd:          pd,
+		credential:  credential,
 		regionCache: tikv.NewRegionCache(pd),
-		mu: struct {
-			sync.Mutex
-			conns map[string]*connArray
-		}{
-			conns: make(map[string]*connArray),
-		},
 	}
+	c.mu.conns = make(map[string]*connArray)
+	c.mu.feeds = make(map[*regionFeedStates]struct{})
 
 	return
 }
 
+// RegionCount returns the number of the regions fed by the running EventFeeds
+func (c *CDCClient) RegionCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := 0
+	for states := range c.mu.feeds {
+		count += states.len()
+	}
+	return count
+}
+
 // Close CDCClient
 func (c *CDCClient) Close() error {
 	c.mu.Lock()
@@ -238,7 +391,7 @@ func (c *CDCClient) getConn(ctx context.Context, addr string) (*grpc.ClientConn,
 	if conns, ok := c.mu.conns[addr]; ok {
 		return conns.Get(), nil
 	}
-	ca, err := newConnArray(ctx, grpcConnCount, addr)
+	ca, err := newConnArray(ctx, grpcConnCount, addr, c.credential)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -273,9 +426,18 @@ func (c *CDCClient) EventFeed(
 
 	regionCh := make(chan singleRegionInfo, 16)
 	errCh := make(chan regionErrorInfo, 16)
+	states := newRegionFeedStates()
+	c.mu.Lock()
+	c.mu.feeds[states] = struct{}{}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.mu.feeds, states)
+		c.mu.Unlock()
+	}()
 
 	g.Go(func() error {
-		return c.dispatchRequest(ctx, g, regionCh, errCh, eventCh)
+		return c.dispatchRequest(ctx, g, regionCh, errCh, eventCh, states)
 	})
 
 	g.Go(func() error {
@@ -288,7 +450,7 @@ func (c *CDCClient) EventFeed(
 			case <-ctx.Done():
 				return ctx.Err()
 			case errInfo := <-errCh:
-				err = c.handleError(ctx, errInfo, regionCh)
+				err = c.handleError(ctx, errInfo, regionCh, states)
 				if err != nil {
 					return errors.Trace(err)
 				}
@@ -306,18 +468,21 @@ func (c *CDCClient) EventFeed(
 // Regions from `regionCh` will be connected. If any error happens to a
 // region, the error will be send to `errCh` and the receiver of `errCh` is
 // responsible for handling the error.
+// If a region is requested while another feed to it exists, which happens after
+// some regions are merged, the existing feed is stopped and the region is
+// requested again with the merged span.
 func (c *CDCClient) dispatchRequest(
 	ctx context.Context,
 	g *errgroup.Group,
 	regionCh chan singleRegionInfo,
 	errCh chan<- regionErrorInfo,
 	eventCh chan<- *model.RegionFeedEvent,
+	states *regionFeedStates,
 ) error {
-	streams := make(map[string]cdcpb.ChangeData_EventFeedClient)
-	// Stores pending regions info for each stream. After sending a new request, the region info wil be put to the map,
-	// and it will be loaded by the receiver thread when it receives the first response from that region. We need this
-	// to pass the region info to the receiver since the region info cannot be inferred from the response from TiKV.
-	storePendingRegions := make(map[string]*syncRegionInfoMap)
+	// Each stream stores its pending regions info. After sending a new request, the region info wil be put to the
+	// map, and it will be loaded by the receiver thread when it receives the first response from that region. We need
+	// this to pass the region info to the receiver since the region info cannot be inferred from the response from TiKV.
+	streams := make(map[string]*eventFeedStream)
 
 MainLoop:
 	for {
@@ -330,6 +495,9 @@ MainLoop:
 
 		log.Debug("dispatching region", zap.Uint64("regionID", sri.verID.GetID()))
 
+		// needNewStream is set if the region may be still registered in the existing stream
+		needNewStream := false
+
 		// Loop for retrying in case the stream has disconnected.
 		// TODO: Should we break if retries and fails too many times?
 		for {
@@ -345,6 +513,10 @@ MainLoop:
 				// Workaround: spawn to a new goroutine, otherwise the function may blocks when sending to regionCh but
 				// regionCh can only be received from `dispatchRequest`.
 				// TODO: Find better solution after a refactoring
+				if sri.state != nil {
+					states.remove(sri.verID.GetID(), sri.state)
+					sri.state.cancel()
+				}
 				g.Go(func() error {
 					err := c.divideAndSendEventFeedToRegions(ctx, sri.span, sri.ts, regionCh)
 					return errors.Trace(err)
@@ -353,6 +525,37 @@ MainLoop:
 			}
 			sri.rpcCtx = rpcCtx
 
+			if sri.state == nil {
+				state := newRegionFeedState(ctx, sri.span, sri.ts)
+				if old, ok := states.add(sri.verID.GetID(), state); ok && !old.isStopped() {
+					// Some regions have been merged into this region, stop the existing feed and request
+					// the region again with the merged span from the smaller checkpoint ts.
+					old.cancel()
+					sri.span = mergeSpan(old.span, sri.span)
+					if ts := old.getCheckpointTs(); ts < sri.ts {
+						sri.ts = ts
+					}
+					state.span = sri.span
+					state.checkpointTs = sri.ts
+					// The stopped feed is still registered in TiKV, send the request by a new stream
+					// to avoid the duplicated request error.
+					needNewStream = true
+					log.Info("region merged, request the region with the merged span",
+						zap.Uint64("regionID", sri.verID.GetID()),
+						zap.Reflect("span", sri.span),
+						zap.Uint64("checkpoint", sri.ts))
+				}
+				sri.state = state
+			}
+			if needNewStream {
+				// The other regions on the existing stream are still alive, it's closed after they stop.
+				if stream, ok := streams[rpcCtx.Addr]; ok {
+					stream.retire()
+					delete(streams, rpcCtx.Addr)
+				}
+				needNewStream = false
+			}
+
 			req := &cdcpb.ChangeDataRequest{
 				Header: &cdcpb.Header{
 					ClusterId: c.clusterID,
@@ -368,48 +571,45 @@ MainLoop:
 			// receiver thread for region here so that it can know the span.
 			// TODO: Find a better way to handle this.
 			// TODO: Make sure there will not be goroutine leak.
-			// TODO: Here we use region id to index the regionInfo. However, in case that region merge is enabled, there
-			// may be multiple streams to the same regions. Maybe we need to add a requestID field to the protocol for it.
-
-			// Get region info collection of the addr
-			pendingRegions, ok := storePendingRegions[rpcCtx.Addr]
-			if !ok {
-				pendingRegions = newSyncRegionInfoMap()
-				storePendingRegions[rpcCtx.Addr] = pendingRegions
-			}
-
-			_, hasOld := pendingRegions.replace(sri.verID.GetID(), sri)
-			if hasOld {
-				log.Error("region is already pending for the first response while trying to send another request."+
-					"region merge may have happened which is not supported yet",
-					zap.Uint64("regionID", sri.verID.GetID()))
-			}
+			// Here we use region id to index the regionInfo, there is at most one feed to a region in a stream.
 
 			stream, ok := streams[rpcCtx.Addr]
 			// Establish the stream if it has not been connected yet.
 			if !ok {
-				stream, err = c.getStream(ctx, rpcCtx.Addr)
+				streamCtx, cancel := context.WithCancel(ctx)
+				client, err := c.getStream(streamCtx, rpcCtx.Addr)
 				if err != nil {
+					cancel()
 					return errors.Trace(err)
 				}
+				// Each stream has its own pending regions, the regions are registered in TiKV per stream.
+				stream = newEventFeedStream(streamCtx, cancel, client)
 				streams[rpcCtx.Addr] = stream
 
 				g.Go(func() error {
-					return c.receiveFromStream(ctx, g, rpcCtx.Addr, rpcCtx.GetStoreID(), stream, regionCh, eventCh, errCh, pendingRegions)
+					defer cancel()
+					return c.receiveFromStream(ctx, g, rpcCtx.Addr, rpcCtx.Peer.GetStoreId(), stream, regionCh, eventCh, errCh)
 				})
 			}
+			pendingRegions := stream.pendingRegions
+
+			_, hasOld := pendingRegions.replace(sri.verID.GetID(), sri)
+			if hasOld {
+				log.Error("region is already pending for the first response while trying to send another request",
+					zap.Uint64("regionID", sri.verID.GetID()))
+			}
 
 			log.Info("start new request", zap.Reflect("request", req), zap.String("addr", rpcCtx.Addr))
-			err = stream.Send(req)
+			err = stream.client.Send(req)
 
 			// If Send error, the receiver should have received error too or will receive error soon. So we doesn't need
 			// to do extra work here.
 			if err != nil {
 				log.Error("send request to stream failed",
 					zap.String("addr", rpcCtx.Addr),
-					zap.Uint64("storeID", rpcCtx.GetStoreID()),
+					zap.Uint64("storeID", rpcCtx.Peer.GetStoreId()),
 					zap.Error(err))
-				err1 := stream.CloseSend()
+				err1 := stream.client.CloseSend()
 				if err1 != nil {
 					log.Error("failed to close stream", zap.Error(err1))
 				}
@@ -442,7 +642,7 @@ MainLoop:
 }
 
 func needReloadRegion(failStoreIDs map[uint64]struct{}, rpcCtx *tikv.RPCContext) (need bool) {
-	failStoreIDs[rpcCtx.GetStoreID()] = struct{}{}
+	failStoreIDs[rpcCtx.Peer.GetStoreId()] = struct{}{}
 	need = len(failStoreIDs) == len(rpcCtx.Meta.GetPeers())
 	if need {
 		for k := range failStoreIDs {
@@ -488,7 +688,9 @@ func (c *CDCClient) partialRegionFeed(
 		return errors.New("partialRegionFeed exceeds rate limit")
 	}
 
-	maxTs, err := c.singleEventFeed(ctx, regionInfo.span, regionInfo.ts, receiver, eventCh)
+	// The feed is stopped by the state if it's merged into another feed
+	state := regionInfo.state
+	maxTs, err := c.singleEventFeed(state.ctx, regionInfo.span, &state.checkpointTs, receiver, eventCh)
 	log.Debug("singleEventFeed quit")
 
 	if err == nil || errors.Cause(err) == context.Canceled {
@@ -588,7 +790,19 @@ func (c *CDCClient) divideAndSendEventFeedToRegions(
 
 // handleError handles error returned by a region. If some new EventFeed connection should be established, the region
 // info will be sent to `regionCh`.
-func (c *CDCClient) handleError(ctx context.Context, errInfo regionErrorInfo, regionCh chan<- singleRegionInfo) error {
+func (c *CDCClient) handleError(
+	ctx context.Context, errInfo regionErrorInfo, regionCh chan<- singleRegionInfo, states *regionFeedStates,
+) error {
+	if errInfo.state != nil {
+		states.remove(errInfo.verID.GetID(), errInfo.state)
+		// The feed has been merged into another feed, no need to retry.
+		if errInfo.state.isStopped() {
+			return nil
+		}
+		errInfo.state.cancel()
+		errInfo.state = nil
+	}
+
 	err := errInfo.err
 	switch eerr := errors.Cause(err).(type) {
 	case *eventError:
@@ -605,10 +819,13 @@ func (c *CDCClient) handleError(ctx context.Context, errInfo regionErrorInfo, re
 			eventFeedErrorCounter.WithLabelValues("RegionNotFound").Inc()
 			return c.divideAndSendEventFeedToRegions(ctx, errInfo.span, errInfo.ts, regionCh)
 		} else if duplicatedRequest := innerErr.GetDuplicateRequest(); duplicatedRequest != nil {
+			// The region has been requested in the stream, which happens after regions are merged.
+			// Divide the span again, it will be merged into the existing feed of the region.
 			eventFeedErrorCounter.WithLabelValues("DuplicateRequest").Inc()
-			log.Error("tikv reported duplicated request to the same region. region merge should happened which is not supported",
-				zap.Uint64("regionID", duplicatedRequest.RegionId))
-			return nil
+			log.Info("tikv reported duplicated request to the same region, region merge may have happened",
+				zap.Uint64("regionID", duplicatedRequest.RegionId),
+				zap.Reflect("span", errInfo.span))
+			return c.divideAndSendEventFeedToRegions(ctx, errInfo.span, errInfo.ts, regionCh)
 		} else {
 			eventFeedErrorCounter.WithLabelValues("Unknown").Inc()
 			log.Warn("receive empty or unknown error msg", zap.Stringer("error", innerErr))
@@ -639,12 +856,12 @@ func (c *CDCClient) receiveFromStream(
 	g *errgroup.Group,
 	addr string,
 	storeID uint64,
-	stream cdcpb.ChangeData_EventFeedClient,
+	stream *eventFeedStream,
 	regionCh <-chan singleRegionInfo,
 	eventCh chan<- *model.RegionFeedEvent,
 	errCh chan<- regionErrorInfo,
-	pendingRegions *syncRegionInfoMap,
 ) error {
+	pendingRegions := stream.pendingRegions
 	// Cancel the pending regions if the stream failed. Otherwise it will remain unhandled in the pendingRegions list
 	// however not registered in the new reconnected stream.
 	defer func() {
@@ -674,7 +891,7 @@ func (c *CDCClient) receiveFromStream(
 	regionStopped := make(map[uint64]*int32)
 
 	for {
-		cevent, err := stream.Recv()
+		cevent, err := stream.client.Recv()
 
 		// TODO: Should we have better way to handle the errors?
 		if err == io.EOF {
@@ -683,6 +900,11 @@ func (c *CDCClient) receiveFromStream(
 			}
 			return nil
 		}
+		if err != nil && stream.ctx.Err() != nil {
+			// The stream is canceled after all the regions of it stopped, or the EventFeed is canceled.
+			log.Info("stream canceled", zap.String("addr", addr), zap.Uint64("storeID", storeID))
+			return nil
+		}
 		if err != nil {
 			log.Error(
 				"failed to receive from stream",
@@ -714,9 +936,12 @@ func (c *CDCClient) receiveFromStream(
 				// It's the first response for this region. If the region is newly connected, the region info should
 				// have been put in `pendingRegions`. So here we load the region info from `pendingRegions` and start
 				// a new goroutine to handle messages from this region.
-				// Firstly load the region info.
+				// Firstly load the region info. The region is counted as active before it's taken from the pending
+				// regions, so a replaced stream is not closed in between.
+				stream.regionStarted()
 				sri, ok := pendingRegions.take(event.RegionId)
 				if !ok {
+					stream.regionStopped()
 					log.Warn("drop event due to region stopped", zap.Uint64("regionID", event.RegionId))
 					continue
 				}
@@ -728,6 +953,7 @@ func (c *CDCClient) receiveFromStream(
 				isStopped := new(int32)
 				regionStopped[event.RegionId] = isStopped
 				g.Go(func() error {
+					defer stream.regionStopped()
 					return c.partialRegionFeed(ctx, sri, ch, errCh, eventCh, isStopped)
 				})
 			}
@@ -742,14 +968,14 @@ func (c *CDCClient) receiveFromStream(
 }
 
 // singleEventFeed handles events of a single EventFeed stream.
-// Results will be send to eventCh
+// Results will be send to eventCh, and checkpointTs is forwarded by the resolved events
 // EventFeed RPC will not return checkpoint event directly
 // Resolved event is generate while there's not non-match pre-write
 // Return the maximum checkpoint
 func (c *CDCClient) singleEventFeed(
 	ctx context.Context,
 	span util.Span,
-	checkpointTs uint64,
+	checkpointTs *uint64,
 	receiverCh <-chan *cdcpb.Event,
 	eventCh chan<- *model.RegionFeedEvent,
 ) (uint64, error) {
@@ -772,7 +998,7 @@ func (c *CDCClient) singleEventFeed(
 				ResolvedTs: item.commit,
 			},
 		}
-		updateCheckpointTS(&checkpointTs, item.commit)
+		updateCheckpointTS(checkpointTs, item.commit)
 		select {
 		case eventCh <- revent:
 			sendEventCounter.WithLabelValues("sorter resolved", captureID, changefeedID).Inc()
@@ -791,18 +1017,18 @@ func (c *CDCClient) singleEventFeed(
 		var ok bool
 		select {
 		case <-ctx.Done():
-			return atomic.LoadUint64(&checkpointTs), ctx.Err()
+			return atomic.LoadUint64(checkpointTs), ctx.Err()
 		case event, ok = <-receiverCh:
 		}
 
 		if !ok {
 			log.Debug("singleEventFeed receiver closed")
-			return atomic.LoadUint64(&checkpointTs), nil
+			return atomic.LoadUint64(checkpointTs), nil
 		}
 
 		if event == nil {
 			log.Debug("singleEventFeed closed by error")
-			return atomic.LoadUint64(&checkpointTs), errors.New("single event feed aborted")
+			return atomic.LoadUint64(checkpointTs), errors.New("single event feed aborted")
 		}
 
 		eventSize.WithLabelValues(captureID).Observe(float64(event.Event.Size()))
@@ -821,22 +1047,23 @@ func (c *CDCClient) singleEventFeed(
 					case cdcpb.Event_Row_PUT:
 						opType = model.OpTypePut
 					default:
-						return atomic.LoadUint64(&checkpointTs), errors.Errorf("unknown tp: %v", entry.GetOpType())
+						return atomic.LoadUint64(checkpointTs), errors.Errorf("unknown tp: %v", entry.GetOpType())
 					}
 
 					revent := &model.RegionFeedEvent{
 						Val: &model.RawKVEntry{
-							OpType: opType,
-							Key:    entry.Key,
-							Value:  entry.GetValue(),
-							Ts:     entry.CommitTs,
+							OpType:  opType,
+							Key:     entry.Key,
+							Value:   entry.GetValue(),
+							StartTs: entry.StartTs,
+							Ts:      entry.CommitTs,
 						},
 					}
 					select {
 					case eventCh <- revent:
 						sendEventCounter.WithLabelValues("committed", captureID, changefeedID).Inc()
 					case <-ctx.Done():
-						return atomic.LoadUint64(&checkpointTs), errors.Trace(ctx.Err())
+						return atomic.LoadUint64(checkpointTs), errors.Trace(ctx.Err())
 					}
 				case cdcpb.Event_PREWRITE:
 					matcher.putPrewriteRow(entry)
@@ -859,15 +1086,16 @@ func (c *CDCClient) singleEventFeed(
 					case cdcpb.Event_Row_PUT:
 						opType = model.OpTypePut
 					default:
-						return atomic.LoadUint64(&checkpointTs), errors.Errorf("unknow tp: %v", entry.GetOpType())
+						return atomic.LoadUint64(checkpointTs), errors.Errorf("unknow tp: %v", entry.GetOpType())
 					}
 
 					revent := &model.RegionFeedEvent{
 						Val: &model.RawKVEntry{
-							OpType: opType,
-							Key:    entry.Key,
-							Value:  value,
-							Ts:     entry.CommitTs,
+							OpType:  opType,
+							Key:     entry.Key,
+							Value:   value,
+							StartTs: entry.StartTs,
+							Ts:      entry.CommitTs,
 						},
 					}
 
@@ -875,7 +1103,7 @@ func (c *CDCClient) singleEventFeed(
 					case eventCh <- revent:
 						sendEventCounter.WithLabelValues("commit", captureID, changefeedID).Inc()
 					case <-ctx.Done():
-						return atomic.LoadUint64(&checkpointTs), errors.Trace(ctx.Err())
+						return atomic.LoadUint64(checkpointTs), errors.Trace(ctx.Err())
 					}
 					sorter.pushTsItem(sortItem{
 						start:  entry.GetStartTs(),
@@ -894,7 +1122,7 @@ func (c *CDCClient) singleEventFeed(
 		case *cdcpb.Event_Admin_:
 			log.Info("receive admin event", zap.Stringer("event", event))
 		case *cdcpb.Event_Error:
-			return atomic.LoadUint64(&checkpointTs), errors.Trace(&eventError{err: x.Error})
+			return atomic.LoadUint64(checkpointTs), errors.Trace(&eventError{err: x.Error})
 		case *cdcpb.Event_ResolvedTs:
 			if atomic.LoadUint32(&initialized) == 0 {
 				continue
@@ -907,12 +1135,12 @@ func (c *CDCClient) singleEventFeed(
 				},
 			}
 
-			updateCheckpointTS(&checkpointTs, x.ResolvedTs)
+			updateCheckpointTS(checkpointTs, x.ResolvedTs)
 			select {
 			case eventCh <- revent:
 				sendEventCounter.WithLabelValues("native resolved", captureID, changefeedID).Inc()
 			case <-ctx.Done():
-				return atomic.LoadUint64(&checkpointTs), errors.Trace(ctx.Err())
+				return atomic.LoadUint64(checkpointTs), errors.Trace(ctx.Err())
 			}
 		}
 
@@ -3,11 +3,20 @@ package kv
 import (
 	"context"
 	"math/rand"
+	"net"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/cdcpb"
+	"github.com/pingcap/kvproto/pkg/errorpb"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/security"
+	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/pingcap/tidb/store/mockstore/mocktikv"
+	"google.golang.org/grpc"
 )
 
 func Test(t *testing.T) { check.TestingT(t) }
@@ -21,7 +30,7 @@ func (s *clientSuite) TestNewClose(c *check.C) {
 	cluster := mocktikv.NewCluster()
 	pdCli := mocktikv.NewPDClient(cluster)
 
-	cli, err := NewCDCClient(pdCli)
+	cli, err := NewCDCClient(pdCli, &security.Credential{})
 	c.Assert(err, check.IsNil)
 
 	err = cli.Close()
@@ -67,7 +76,7 @@ func (s *clientSuite) TestUpdateCheckpointTS(c *check.C) {
 // ref: https://github.com/grpc/grpc-go/blob/master/grpclog/loggerv2.go#L67-L72
 func (s *etcdSuite) TestConnArray(c *check.C) {
 	addr := "127.0.0.1:2379"
-	ca, err := newConnArray(context.TODO(), 2, addr)
+	ca, err := newConnArray(context.TODO(), 2, addr, &security.Credential{})
 	c.Assert(err, check.IsNil)
 
 	conn1 := ca.Get()
@@ -79,3 +88,206 @@ func (s *etcdSuite) TestConnArray(c *check.C) {
 
 	ca.Close()
 }
+
+func (s *clientSuite) TestRegionFeedStates(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	states := newRegionFeedStates()
+	state1 := newRegionFeedState(ctx, util.Span{Start: []byte("b"), End: []byte("c")}, 10)
+	old, ok := states.add(1, state1)
+	c.Assert(ok, check.IsFalse)
+	c.Assert(old, check.IsNil)
+
+	state2 := newRegionFeedState(ctx, util.Span{Start: []byte("a"), End: []byte("b")}, 5)
+	old, ok = states.add(1, state2)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(old, check.Equals, state1)
+	c.Assert(mergeSpan(old.span, state2.span), check.DeepEquals, util.Span{Start: []byte("a"), End: []byte("c")})
+	c.Assert(mergeSpan(util.Span{Start: []byte("a")}, old.span), check.DeepEquals, util.Span{Start: []byte("a")})
+
+	// removing a replaced state takes no effect
+	states.remove(1, state1)
+	old, ok = states.add(1, state2)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(old, check.Equals, state2)
+	states.remove(1, state2)
+	_, ok = states.add(1, state1)
+	c.Assert(ok, check.IsFalse)
+
+	c.Assert(state1.isStopped(), check.IsFalse)
+	state1.cancel()
+	c.Assert(state1.isStopped(), check.IsTrue)
+	c.Assert(state2.isStopped(), check.IsFalse)
+}
+
+type mockRequest struct {
+	streamID int
+	req      *cdcpb.ChangeDataRequest
+}
+
+// mockChangeDataService is a TiKV serving the EventFeed requests, the events
+// are sent by the test.
+type mockChangeDataService struct {
+	requests chan mockRequest
+	closed   chan int
+
+	mu      sync.Mutex
+	nextID  int
+	streams map[int]cdcpb.ChangeData_EventFeedServer
+}
+
+func newMockChangeDataService() *mockChangeDataService {
+	return &mockChangeDataService{
+		requests: make(chan mockRequest, 16),
+		closed:   make(chan int, 16),
+		streams:  make(map[int]cdcpb.ChangeData_EventFeedServer),
+	}
+}
+
+func (s *mockChangeDataService) EventFeed(server cdcpb.ChangeData_EventFeedServer) error {
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	s.streams[id] = server
+	s.mu.Unlock()
+	for {
+		req, err := server.Recv()
+		if err != nil {
+			s.closed <- id
+			return nil
+		}
+		s.requests <- mockRequest{streamID: id, req: req}
+	}
+}
+
+func (s *mockChangeDataService) send(c *check.C, streamID int, events ...*cdcpb.Event) {
+	s.mu.Lock()
+	server := s.streams[streamID]
+	s.mu.Unlock()
+	c.Assert(server.Send(&cdcpb.ChangeDataEvent{Events: events}), check.IsNil)
+}
+
+// expectRequests returns the requests of the regions, indexed by the region IDs
+func (s *mockChangeDataService) expectRequests(c *check.C, n int) map[uint64]mockRequest {
+	requests := make(map[uint64]mockRequest, n)
+	for len(requests) < n {
+		select {
+		case req := <-s.requests:
+			requests[req.req.RegionId] = req
+		case <-time.After(10 * time.Second):
+			c.Fatalf("expect %d requests, got %v", n, requests)
+		}
+	}
+	return requests
+}
+
+func assertRequest(c *check.C, req mockRequest, streamID int, start, end string, ts uint64) {
+	c.Assert(req.streamID, check.Equals, streamID)
+	c.Assert(string(req.req.StartKey), check.Equals, start)
+	c.Assert(string(req.req.EndKey), check.Equals, end)
+	c.Assert(req.req.CheckpointTs, check.Equals, ts)
+}
+
+func initializedEvent(regionID uint64) *cdcpb.Event {
+	return &cdcpb.Event{
+		RegionId: regionID,
+		Event: &cdcpb.Event_Entries_{Entries: &cdcpb.Event_Entries{
+			Entries: []*cdcpb.Event_Row{{Type: cdcpb.Event_INITIALIZED}},
+		}},
+	}
+}
+
+func resolvedEvent(regionID, ts uint64) *cdcpb.Event {
+	return &cdcpb.Event{RegionId: regionID, Event: &cdcpb.Event_ResolvedTs{ResolvedTs: ts}}
+}
+
+func errorEvent(regionID uint64, err *cdcpb.Error) *cdcpb.Event {
+	return &cdcpb.Event{RegionId: regionID, Event: &cdcpb.Event_Error{Error: err}}
+}
+
+func expectResolved(c *check.C, eventCh <-chan *model.RegionFeedEvent, ts uint64, n int) {
+	for n > 0 {
+		select {
+		case e := <-eventCh:
+			if e.Resolved != nil && e.Resolved.ResolvedTs == ts {
+				n--
+			}
+		case <-time.After(10 * time.Second):
+			c.Fatalf("expect resolved ts %d", ts)
+		}
+	}
+}
+
+func (s *clientSuite) TestRegionMergeAndSplit(c *check.C) {
+	service := newMockChangeDataService()
+	server := grpc.NewServer()
+	cdcpb.RegisterChangeDataServer(server, service)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	defer server.Stop()
+
+	cluster := mocktikv.NewCluster()
+	cluster.AddStore(1, lis.Addr().String())
+	cluster.Bootstrap(3, []uint64{1}, []uint64{4}, 4)
+	cluster.SplitRaw(3, 5, []byte("b"), []uint64{6}, 6)
+
+	cli, err := NewCDCClient(mocktikv.NewPDClient(cluster), &security.Credential{})
+	c.Assert(err, check.IsNil)
+	defer func() {
+		c.Assert(cli.Close(), check.IsNil)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eventCh := make(chan *model.RegionFeedEvent, 16)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cli.EventFeed(ctx, util.Span{Start: []byte("a"), End: []byte("c")}, 100, eventCh)
+	}()
+
+	requests := service.expectRequests(c, 2)
+	assertRequest(c, requests[3], 1, "a", "b", 100)
+	assertRequest(c, requests[5], 1, "b", "c", 100)
+	service.send(c, 1, initializedEvent(3), initializedEvent(5), resolvedEvent(3, 110), resolvedEvent(5, 110))
+	expectResolved(c, eventCh, 110, 2)
+
+	// After the regions are merged, the feeds are merged into one feed with
+	// the merged span, which is requested by a new stream.
+	cluster.Merge(3, 5)
+	service.send(c, 1, errorEvent(5, &cdcpb.Error{EpochNotMatch: &errorpb.EpochNotMatch{}}))
+	requests = service.expectRequests(c, 1)
+	assertRequest(c, requests[3], 2, "a", "c", 110)
+	// the replaced stream is closed since all of its regions stopped
+	select {
+	case id := <-service.closed:
+		c.Assert(id, check.Equals, 1)
+	case <-time.After(10 * time.Second):
+		c.Fatal("the replaced stream is not closed")
+	}
+	service.send(c, 2, initializedEvent(3), resolvedEvent(3, 120))
+	expectResolved(c, eventCh, 120, 1)
+
+	// After the region is split, the span is divided to the regions again.
+	cluster.SplitRaw(3, 7, []byte("b"), []uint64{8}, 8)
+	service.send(c, 2, errorEvent(3, &cdcpb.Error{EpochNotMatch: &errorpb.EpochNotMatch{}}))
+	requests = service.expectRequests(c, 2)
+	assertRequest(c, requests[3], 2, "a", "b", 120)
+	assertRequest(c, requests[7], 2, "b", "c", 120)
+
+	// The span of a duplicated request is divided and requested again.
+	service.send(c, 2, errorEvent(7, &cdcpb.Error{DuplicateRequest: &cdcpb.Error_DuplicateRequest{RegionId: 7}}))
+	requests = service.expectRequests(c, 1)
+	assertRequest(c, requests[7], 2, "b", "c", 120)
+
+	cancel()
+	select {
+	case err := <-errCh:
+		c.Assert(errors.Cause(err), check.Equals, context.Canceled)
+	case <-time.After(10 * time.Second):
+		c.Fatal("EventFeed is not canceled")
+	}
+}
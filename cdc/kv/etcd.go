@@ -16,12 +16,14 @@ package kv
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"go.etcd.io/etcd/embed"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb/store/tikv"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/mvcc/mvccpb"
 )
@@ -134,6 +136,22 @@ func (c CDCEtcdClient) GetChangeFeedInfo(ctx context.Context, id string) (*model
 	return detail, errors.Trace(err)
 }
 
+// GetChangeFeedInfoWithRevision queries the config of a given changefeed and
+// the mod revision of its key in etcd
+func (c CDCEtcdClient) GetChangeFeedInfoWithRevision(ctx context.Context, id string) (*model.ChangeFeedInfo, int64, error) {
+	key := GetEtcdKeyChangeFeedInfo(id)
+	resp, err := c.Client.Get(ctx, key)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	if resp.Count == 0 {
+		return nil, 0, errors.Annotatef(model.ErrChangeFeedNotExists, "query detail id %s", id)
+	}
+	detail := &model.ChangeFeedInfo{}
+	err = detail.Unmarshal(resp.Kvs[0].Value)
+	return detail, resp.Kvs[0].ModRevision, errors.Trace(err)
+}
+
 // DeleteChangeFeedInfo deletes a changefeed config from etcd
 func (c CDCEtcdClient) DeleteChangeFeedInfo(ctx context.Context, id string) error {
 	key := GetEtcdKeyChangeFeedInfo(id)
@@ -219,6 +237,77 @@ func (c CDCEtcdClient) SaveChangeFeedInfo(ctx context.Context, info *model.Chang
 	return errors.Trace(err)
 }
 
+// CreateChangeFeedInfo saves the changefeed info only if the changefeed doesn't exist
+func (c CDCEtcdClient) CreateChangeFeedInfo(ctx context.Context, info *model.ChangeFeedInfo, changeFeedID string) error {
+	key := GetEtcdKeyChangeFeedInfo(changeFeedID)
+	value, err := info.Marshal()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	resp, err := c.Client.Txn(ctx).If(
+		clientv3.Compare(clientv3.CreateRevision(key), "=", 0),
+	).Then(
+		clientv3.OpPut(key, value),
+	).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.Annotatef(model.ErrChangeFeedExists, "changefeed %s", changeFeedID)
+	}
+	return nil
+}
+
+// UpdateChangeFeedInfo saves the changefeed info only if its key is not modified
+// since modRevision, it returns the new mod revision of the key
+func (c CDCEtcdClient) UpdateChangeFeedInfo(ctx context.Context, info *model.ChangeFeedInfo, changeFeedID string, modRevision int64) (int64, error) {
+	key := GetEtcdKeyChangeFeedInfo(changeFeedID)
+	value, err := info.Marshal()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	resp, err := c.Client.Txn(ctx).If(
+		clientv3.Compare(clientv3.ModRevision(key), "=", modRevision),
+	).Then(
+		clientv3.OpPut(key, value),
+	).Commit()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return 0, errors.Annotatef(model.ErrChangeFeedInfoConflict, "changefeed %s", changeFeedID)
+	}
+	return resp.Header.Revision, nil
+}
+
+// GetSavedGCSafePoint returns the gc safe point saved by TiDB, it returns 0 if not found
+func (c CDCEtcdClient) GetSavedGCSafePoint(ctx context.Context) (uint64, error) {
+	resp, err := c.Client.Get(ctx, tikv.GcSavedSafePoint)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if resp.Count == 0 {
+		return 0, nil
+	}
+	safePoint, err := strconv.ParseUint(string(resp.Kvs[0].Value), 10, 64)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return safePoint, nil
+}
+
+// VerifyStartTs checks the start ts of a changefeed is not less than the saved gc safe point
+func (c CDCEtcdClient) VerifyStartTs(ctx context.Context, startTs uint64) error {
+	safePoint, err := c.GetSavedGCSafePoint(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if startTs < safePoint {
+		return errors.Errorf("startTs %d less than gcSafePoint %d", startTs, safePoint)
+	}
+	return nil
+}
+
 // GetAllTaskPositions queries all task positions of a changefeed, and returns a map
 // mapping from captureID to TaskPositions
 func (c CDCEtcdClient) GetAllTaskPositions(ctx context.Context, changefeedID string) (map[string]*model.TaskPosition, error) {
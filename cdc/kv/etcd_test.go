@@ -204,6 +204,37 @@ func (s *etcdSuite) TestOpChangeFeedDetail(c *check.C) {
 	c.Assert(errors.Cause(err), check.Equals, model.ErrChangeFeedNotExists)
 }
 
+func (s *etcdSuite) TestCreateUpdateChangeFeedInfo(c *check.C) {
+	ctx := context.Background()
+	detail := &model.ChangeFeedInfo{
+		SinkURI: "root@tcp(127.0.0.1:3306)/mysql",
+	}
+	cfID := "test-cas-cf"
+
+	err := s.client.CreateChangeFeedInfo(ctx, detail, cfID)
+	c.Assert(err, check.IsNil)
+	err = s.client.CreateChangeFeedInfo(ctx, detail, cfID)
+	c.Assert(errors.Cause(err), check.Equals, model.ErrChangeFeedExists)
+
+	d, rev, err := s.client.GetChangeFeedInfoWithRevision(ctx, cfID)
+	c.Assert(err, check.IsNil)
+	c.Assert(d.SinkURI, check.Equals, detail.SinkURI)
+
+	d.SinkURI = "blackhole://"
+	newRev, err := s.client.UpdateChangeFeedInfo(ctx, d, cfID, rev)
+	c.Assert(err, check.IsNil)
+	c.Assert(newRev, check.Greater, rev)
+
+	// the info is modified since rev
+	_, err = s.client.UpdateChangeFeedInfo(ctx, d, cfID, rev)
+	c.Assert(errors.Cause(err), check.Equals, model.ErrChangeFeedInfoConflict)
+
+	d, rev, err = s.client.GetChangeFeedInfoWithRevision(ctx, cfID)
+	c.Assert(err, check.IsNil)
+	c.Assert(d.SinkURI, check.Equals, "blackhole://")
+	c.Assert(rev, check.Equals, newRev)
+}
+
 func (s *etcdSuite) TestPutAllChangeFeedStatus(c *check.C) {
 	var (
 		status1 = &model.ChangeFeedStatus{
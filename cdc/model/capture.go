@@ -22,6 +22,11 @@ import (
 // CaptureInfo store in etcd.
 type CaptureInfo struct {
 	ID string `json:"id"`
+	// AdvertiseAddr is the address of the status server of the capture
+	AdvertiseAddr string `json:"address"`
+	// Draining is set when the capture is going to exit, the owner moves
+	// all the tables out of it and doesn't dispatch new tables to it
+	Draining bool `json:"draining,omitempty"`
 }
 
 // Marshal using json.Marshal.
@@ -24,6 +24,17 @@ import (
 	"github.com/pingcap/tidb/store/tikv/oracle"
 )
 
+// FeedState represents the running state of a changefeed
+type FeedState string
+
+// All FeedStates
+const (
+	StateNormal FeedState = "normal"
+	// StateFailed means the changefeed can't be recovered, e.g. its checkpoint
+	// has fallen behind the GC safepoint, it can only be removed.
+	StateFailed FeedState = "failed"
+)
+
 // ChangeFeedInfo describes the detail of a ChangeFeed
 type ChangeFeedInfo struct {
 	SinkURI    string            `json:"sink-uri"`
@@ -35,6 +46,14 @@ type ChangeFeedInfo struct {
 	TargetTs uint64 `json:"target-ts"`
 	// used for admin job notification, trigger watch event in capture
 	AdminJobType AdminJobType `json:"admin-job-type"`
+	// SortDir is the dir for the sorter to spill the unsorted events,
+	// the events are sorted in memory if it's empty
+	SortDir string `json:"sort-dir"`
+	// State is the running state of the changefeed, it's empty for the
+	// changefeeds created before the state was introduced
+	State FeedState `json:"state"`
+	// ErrorMsg records the reason why the changefeed failed
+	ErrorMsg string `json:"error-msg"`
 
 	Config *util.ReplicaConfig `json:"config"`
 }
@@ -47,6 +66,31 @@ func (info *ChangeFeedInfo) GetConfig() *util.ReplicaConfig {
 	return info.Config
 }
 
+// VerifyAdminJob checks whether the admin job can be applied to the changefeed in current state
+func (info *ChangeFeedInfo) VerifyAdminJob(job AdminJobType) error {
+	if info.State == StateFailed && job != AdminRemove {
+		return errors.Errorf("changefeed is failed (%s), it can only be removed", info.ErrorMsg)
+	}
+	switch job {
+	case AdminStop:
+		if info.AdminJobType == AdminStop {
+			return errors.New("changefeed is already paused")
+		}
+	case AdminResume:
+		if info.AdminJobType != AdminStop {
+			return errors.New("changefeed is not paused")
+		}
+	case AdminRemove:
+	case AdminMoveTable:
+		if info.AdminJobType == AdminStop {
+			return errors.New("changefeed is paused")
+		}
+	default:
+		return errors.Errorf("invalid admin job type: %d", job)
+	}
+	return nil
+}
+
 // GetStartTs returns StartTs if it's  specified or using the CreateTime of changefeed.
 func (info *ChangeFeedInfo) GetStartTs() uint64 {
 	if info.StartTs > 0 {
@@ -0,0 +1,49 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"github.com/pingcap/check"
+)
+
+type changefeedSuite struct{}
+
+var _ = check.Suite(&changefeedSuite{})
+
+func (s *changefeedSuite) TestVerifyAdminJob(c *check.C) {
+	running := &ChangeFeedInfo{}
+	paused := &ChangeFeedInfo{AdminJobType: AdminStop}
+	resumed := &ChangeFeedInfo{AdminJobType: AdminResume}
+
+	c.Assert(running.VerifyAdminJob(AdminStop), check.IsNil)
+	c.Assert(resumed.VerifyAdminJob(AdminStop), check.IsNil)
+	c.Assert(paused.VerifyAdminJob(AdminStop), check.ErrorMatches, ".*already paused.*")
+
+	c.Assert(paused.VerifyAdminJob(AdminResume), check.IsNil)
+	c.Assert(running.VerifyAdminJob(AdminResume), check.ErrorMatches, ".*not paused.*")
+	c.Assert(resumed.VerifyAdminJob(AdminResume), check.ErrorMatches, ".*not paused.*")
+
+	c.Assert(running.VerifyAdminJob(AdminRemove), check.IsNil)
+	c.Assert(paused.VerifyAdminJob(AdminRemove), check.IsNil)
+	c.Assert(running.VerifyAdminJob(AdminNone), check.NotNil)
+
+	c.Assert(running.VerifyAdminJob(AdminMoveTable), check.IsNil)
+	c.Assert(resumed.VerifyAdminJob(AdminMoveTable), check.IsNil)
+	c.Assert(paused.VerifyAdminJob(AdminMoveTable), check.ErrorMatches, ".*paused.*")
+
+	failed := &ChangeFeedInfo{AdminJobType: AdminStop, State: StateFailed, ErrorMsg: "behind gc safepoint"}
+	c.Assert(failed.VerifyAdminJob(AdminResume), check.ErrorMatches, ".*behind gc safepoint.*only be removed.*")
+	c.Assert(failed.VerifyAdminJob(AdminStop), check.NotNil)
+	c.Assert(failed.VerifyAdminJob(AdminRemove), check.IsNil)
+}
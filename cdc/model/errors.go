@@ -22,6 +22,8 @@ import (
 var (
 	ErrWriteTsConflict        = errors.New("write ts conflict")
 	ErrChangeFeedNotExists    = errors.New("changefeed not exists")
+	ErrChangeFeedInfoConflict = errors.New("changefeed info modified concurrently")
+	ErrChangeFeedExists       = errors.New("changefeed already exists")
 	ErrTaskStatusNotExists    = errors.New("task status not exists")
 	ErrTaskPositionNotExists  = errors.New("task position not exists")
 	ErrWriteTaskStatusConlict = errors.New("write task status conflict")
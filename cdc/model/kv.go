@@ -48,9 +48,11 @@ type RawKVEntry struct {
 	Key    []byte
 	// Nil fro delete type
 	Value []byte
-	Ts    uint64
+	// StartTs is the start ts of the transaction which writes this kv
+	StartTs uint64
+	Ts      uint64
 }
 
 func (v *RawKVEntry) String() string {
-	return fmt.Sprintf("OpType: %v, Key: %s, Value: %s, ts: %d", v.OpType, string(v.Key), string(v.Value), v.Ts)
+	return fmt.Sprintf("OpType: %v, Key: %s, Value: %s, startTs: %d, ts: %d", v.OpType, string(v.Key), string(v.Value), v.StartTs, v.Ts)
 }
@@ -25,10 +25,11 @@ const (
 
 // MqMessageKey represents the message key
 type MqMessageKey struct {
-	Ts     uint64        `json:"ts"`
-	Schema string        `json:"schema,omitempty"`
-	Table  string        `json:"table,omitempty"`
-	Type   MqMessageType `json:"type"`
+	Ts      uint64        `json:"ts"`
+	StartTs uint64        `json:"start-ts,omitempty"`
+	Schema  string        `json:"schema,omitempty"`
+	Table   string        `json:"table,omitempty"`
+	Type    MqMessageType `json:"type"`
 }
 
 // Encode encodes the message to the json bytes
@@ -45,7 +46,9 @@ func (m *MqMessageKey) Decode(data []byte) error {
 // MqMessageRow represents the row message value
 type MqMessageRow struct {
 	Update map[string]*Column `json:"update,omitempty"`
-	Delete map[string]*Column `json:"delete,omitempty"`
+	// PreUpdate holds the old values of an updated row
+	PreUpdate map[string]*Column `json:"pre-update,omitempty"`
+	Delete    map[string]*Column `json:"delete,omitempty"`
 }
 
 // Encode encodes the message to the json bytes
@@ -64,6 +67,9 @@ func (m *MqMessageRow) Decode(data []byte) error {
 	for _, column := range m.Update {
 		column.formatVal()
 	}
+	for _, column := range m.PreUpdate {
+		column.formatVal()
+	}
 	for _, column := range m.Delete {
 		column.formatVal()
 	}
@@ -57,6 +57,23 @@ type AdminJobType int
 type AdminJob struct {
 	CfID string
 	Type AdminJobType
+	// TableID and TargetCaptureID are used by AdminMoveTable only
+	TableID         uint64
+	TargetCaptureID CaptureID
+	// Result receives the result of the job if it's not nil, it should be
+	// buffered because the owner never blocks on it
+	Result chan error
+}
+
+// Done sends the result of the job to the Result channel if any
+func (job AdminJob) Done(err error) {
+	if job.Result == nil {
+		return
+	}
+	select {
+	case job.Result <- err:
+	default:
+	}
 }
 
 // All AdminJob types
@@ -65,6 +82,8 @@ const (
 	AdminStop
 	AdminResume
 	AdminRemove
+	// AdminMoveTable moves a table to the target capture, it's handled by the owner only
+	AdminMoveTable
 )
 
 // String implements fmt.Stringer interface.
@@ -78,6 +97,8 @@ func (t AdminJobType) String() string {
 		return "resume changefeed"
 	case AdminRemove:
 		return "remove changefeed"
+	case AdminMoveTable:
+		return "move table"
 	}
 	return "unknown"
 }
@@ -88,8 +109,26 @@ type TaskPosition struct {
 	CheckPointTs uint64 `json:"checkpoint-ts"`
 	// The event that satisfies CommitTs <= ResolvedTs can be synchronized. This is updated by corresponding processor.
 	ResolvedTs uint64 `json:"resolved-ts"`
+	// Workload records the workloads of the tables replicated by the processor,
+	// it's used by the owner to schedule and rebalance the tables.
+	Workload TaskWorkload `json:"workload,omitempty"`
 }
 
+// WorkloadInfo records the workload of a table
+type WorkloadInfo struct {
+	// Workload is the estimated cost of replicating the table, a table
+	// without any event has the workload of 1, and each event per second
+	// adds 1 to the workload.
+	Workload uint64 `json:"workload"`
+	// RegionCount is the number of the regions the table is fed by
+	RegionCount uint64 `json:"region-count"`
+	// SorterBytes is the size of the entries buffered in the sorter of the table
+	SorterBytes uint64 `json:"sorter-bytes"`
+}
+
+// TaskWorkload maps from table IDs to the workloads of the tables
+type TaskWorkload map[uint64]WorkloadInfo
+
 // Marshal returns the json marshal format of a TaskStatus
 func (tp *TaskPosition) Marshal() (string, error) {
 	data, err := json.Marshal(tp)
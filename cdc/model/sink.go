@@ -12,32 +12,54 @@ import (
 
 // RowChangedEvent represents a row changed event
 type RowChangedEvent struct {
+	// StartTs is the start ts of the upstream transaction
+	StartTs  uint64
 	Ts       uint64
 	Resolved bool
 
 	Schema string
 	Table  string
 
-	Delete bool
+	// Type is the type of the row change, it's one of InsertDMLType,
+	// UpdateDMLType and DeleteDMLType
+	Type DMLType
 
 	// if the table of this row only has one unique index(includes primary key),
 	// IndieMarkCol will be set to the name of the unique index
 	IndieMarkCol string
-	Columns      map[string]*Column
+	// Columns holds the column values after the change, it is nil for deletes
+	Columns map[string]*Column
+	// PreColumns holds the column values before the change, it is set for deletes,
+	// and for updates only when the old value is available
+	PreColumns map[string]*Column
+
+	// TableInfo is the schema of the table at the ts of the row, it is shared
+	// by the rows of the same schema version and is not sent to the MQ
+	TableInfo *model.TableInfo
+}
+
+// IsDelete returns true if the row is deleted
+func (e *RowChangedEvent) IsDelete() bool {
+	return e.Type == DeleteDMLType
 }
 
 // ToMqMessage transforms to message key and value
 func (e *RowChangedEvent) ToMqMessage() (*MqMessageKey, *MqMessageRow) {
 	key := &MqMessageKey{
-		Ts:     e.Ts,
-		Schema: e.Schema,
-		Table:  e.Table,
-		Type:   MqMessageTypeRow,
+		Ts:      e.Ts,
+		StartTs: e.StartTs,
+		Schema:  e.Schema,
+		Table:   e.Table,
+		Type:    MqMessageTypeRow,
 	}
 	value := &MqMessageRow{}
-	if e.Delete {
-		value.Delete = e.Columns
-	} else {
+	switch e.Type {
+	case DeleteDMLType:
+		value.Delete = e.PreColumns
+	case UpdateDMLType:
+		value.Update = e.Columns
+		value.PreUpdate = e.PreColumns
+	default:
 		value.Update = e.Columns
 	}
 	return key, value
@@ -46,16 +68,24 @@ func (e *RowChangedEvent) ToMqMessage() (*MqMessageKey, *MqMessageRow) {
 // FromMqMessage fills the values of RowChangedEvent from message key and value
 func (e *RowChangedEvent) FromMqMessage(key *MqMessageKey, value *MqMessageRow) {
 	e.Ts = key.Ts
+	e.StartTs = key.StartTs
 	e.Resolved = false
 	e.Table = key.Table
 	e.Schema = key.Schema
 
-	if len(value.Delete) != 0 {
-		e.Delete = true
-		e.Columns = value.Delete
-	} else {
-		e.Delete = false
+	switch {
+	case len(value.Delete) != 0:
+		e.Type = DeleteDMLType
+		e.Columns = nil
+		e.PreColumns = value.Delete
+	case len(value.PreUpdate) != 0:
+		e.Type = UpdateDMLType
 		e.Columns = value.Update
+		e.PreColumns = value.PreUpdate
+	default:
+		e.Type = InsertDMLType
+		e.Columns = value.Update
+		e.PreColumns = nil
 	}
 }
 
@@ -97,6 +127,10 @@ type DDLEvent struct {
 	Table  string
 	Query  string
 	Type   model.ActionType
+
+	// TableInfo is the schema of the table after the DDL, it is nil if the
+	// DDL changes no table
+	TableInfo *model.TableInfo
 }
 
 // ToMqMessage transforms to message key and value
@@ -21,3 +21,19 @@ func (s *columnSuite) TestFormatCol(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(row2, check.DeepEquals, row)
 }
+
+func (s *columnSuite) TestRowChangedEventMqMessage(c *check.C) {
+	preCols := map[string]*Column{"id": {Type: mysql.TypeLong, WhereHandle: true, Value: int64(1)}}
+	cols := map[string]*Column{"id": {Type: mysql.TypeLong, WhereHandle: true, Value: int64(2)}}
+	testCases := []*RowChangedEvent{
+		{StartTs: 1, Ts: 2, Schema: "test", Table: "t", Type: InsertDMLType, Columns: cols},
+		{StartTs: 3, Ts: 4, Schema: "test", Table: "t", Type: UpdateDMLType, PreColumns: preCols, Columns: cols},
+		{StartTs: 5, Ts: 6, Schema: "test", Table: "t", Type: DeleteDMLType, PreColumns: preCols},
+	}
+	for _, tc := range testCases {
+		key, value := tc.ToMqMessage()
+		row := new(RowChangedEvent)
+		row.FromMqMessage(key, value)
+		c.Assert(row, check.DeepEquals, tc)
+	}
+}
@@ -24,13 +24,14 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	timodel "github.com/pingcap/parser/model"
-	pd "github.com/pingcap/pd/client"
+	pd "github.com/pingcap/pd/v4/client"
 	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/cdc/roles"
 	"github.com/pingcap/ticdc/cdc/roles/storage"
 	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/clientv3/concurrency"
@@ -38,10 +39,19 @@ import (
 	"go.etcd.io/etcd/mvcc/mvccpb"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
 )
 
 const (
 	captureInfoWatchRetryDelay = time.Millisecond * 500
+
+	// CDCServiceSafePointID is the ID of the service GC safepoint registered by CDC in PD
+	CDCServiceSafePointID = "ticdc"
+	// DefaultGCTTL is the default TTL of the service GC safepoint in seconds
+	DefaultGCTTL = 24 * 60 * 60
+
+	gcSafePointUpdateInterval = time.Minute
 )
 
 type tableIDMap = map[uint64]struct{}
@@ -99,6 +109,15 @@ type changeFeed struct {
 	orphanTables  map[uint64]model.ProcessTableInfo
 	toCleanTables map[uint64]struct{}
 	infoWriter    *storage.OwnerTaskStatusEtcdWriter
+
+	// movingTables holds the tables being moved by rebalance,
+	// mapping from table ID to the capture the table is removed from
+	movingTables      map[uint64]model.CaptureID
+	lastRebalanceTime time.Time
+	// tableTargets holds the captures the tables are pinned to by moving them
+	// manually, the pinned tables are never moved by rebalanceTables. The pin
+	// is released once the capture is gone or starts draining.
+	tableTargets map[uint64]model.CaptureID
 }
 
 // String implements fmt.Stringer interface.
@@ -171,46 +190,38 @@ func (c *changeFeed) removeTable(sid, tid uint64) {
 		delete(c.schemas[sid], tid)
 	}
 	delete(c.tables, tid)
+	delete(c.tableTargets, tid)
 
 	if _, ok := c.orphanTables[tid]; ok {
 		delete(c.orphanTables, tid)
+	} else if _, ok := c.movingTables[tid]; ok {
+		// the table has been removed from the source capture
+		delete(c.movingTables, tid)
 	} else {
 		c.toCleanTables[tid] = struct{}{}
 	}
 }
 
-func (c *changeFeed) selectCapture(captures map[string]*model.CaptureInfo) string {
-	return c.minimumTablesCapture(captures)
-}
-
-func (c *changeFeed) minimumTablesCapture(captures map[string]*model.CaptureInfo) string {
-	if len(captures) == 0 {
-		return ""
+// updateSinkTables tells the sink the tables of the changefeed if it needs them
+func (c *changeFeed) updateSinkTables() {
+	s, ok := c.sink.(sink.TableAwareSink)
+	if !ok {
+		return
 	}
-
-	for id := range captures {
-		// We have not dispatch any table to this capture yet.
-		if _, ok := c.taskStatus[id]; !ok {
-			return id
-		}
+	tables := make([]entry.TableName, 0, len(c.tables))
+	for _, table := range c.tables {
+		tables = append(tables, table)
 	}
-
-	var minCount int = math.MaxInt64
-	var minID string
-
-	for id, pinfo := range c.taskStatus {
-		if len(pinfo.TableInfos) < minCount {
-			minID = id
-			minCount = len(pinfo.TableInfos)
-		}
-	}
-
-	return minID
+	s.UpdateTables(tables)
 }
 
 func (c *changeFeed) tryBalance(ctx context.Context, captures map[string]*model.CaptureInfo) {
 	c.cleanTables(ctx)
-	c.banlanceOrphanTables(ctx, captures)
+	c.handleMovingTables(captures)
+	c.drainCaptures(ctx, captures)
+	schedulable := schedulableCaptures(captures)
+	c.banlanceOrphanTables(ctx, schedulable)
+	c.rebalanceTables(ctx, schedulable)
 }
 
 func (c *changeFeed) restoreTableInfos(infoSnapshot *model.TaskStatus, captureID string) {
@@ -282,6 +293,13 @@ func (c *changeFeed) banlanceOrphanTables(ctx context.Context, captures map[stri
 		if len(captureID) == 0 {
 			return
 		}
+		if target, ok := c.tableTargets[tableID]; ok {
+			if _, alive := captures[target]; alive {
+				captureID = target
+			} else {
+				delete(c.tableTargets, tableID)
+			}
+		}
 
 		info := c.taskStatus[captureID]
 		if info == nil {
@@ -365,6 +383,7 @@ type ownerImpl struct {
 	l sync.RWMutex
 
 	pdEndpoints []string
+	credential  *security.Credential
 	pdClient    pd.Client
 	etcdClient  kv.CDCEtcdClient
 	manager     roles.Manager
@@ -375,10 +394,23 @@ type ownerImpl struct {
 
 	adminJobs     []model.AdminJob
 	adminJobsLock sync.Mutex
+
+	// stoppedFeeds holds the status of the paused changefeeds, which are not in
+	// changeFeeds but still need to be protected by the service GC safepoint
+	stoppedFeeds map[model.ChangeFeedID]*model.ChangeFeedStatus
+	// gcTTL is the TTL of the service GC safepoint in seconds,
+	// the safepoint is not maintained if it's not positive
+	gcTTL int64
+	// gcSafePoint is the last known GC safepoint of the cluster
+	gcSafePoint          uint64
+	lastUpdateGCTime     time.Time
+	serviceGCUnsupported bool
 }
 
 // NewOwner creates a new ownerImpl instance
-func NewOwner(pdEndpoints []string, cli kv.CDCEtcdClient, manager roles.Manager) (*ownerImpl, error) {
+func NewOwner(
+	pdEndpoints []string, credential *security.Credential, cli kv.CDCEtcdClient, manager roles.Manager, gcTTL int64,
+) (*ownerImpl, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	infos, watchC, err := newCaptureInfoWatch(ctx, cli)
 	if err != nil {
@@ -391,7 +423,7 @@ func NewOwner(pdEndpoints []string, cli kv.CDCEtcdClient, manager roles.Manager)
 		captures[info.ID] = info
 	}
 
-	pdClient, err := pd.NewClient(pdEndpoints, pd.SecurityOption{})
+	pdClient, err := pd.NewClient(pdEndpoints, credential.PDSecurityOption())
 	if err != nil {
 		cancel()
 		return nil, errors.Trace(err)
@@ -399,6 +431,7 @@ func NewOwner(pdEndpoints []string, cli kv.CDCEtcdClient, manager roles.Manager)
 
 	owner := &ownerImpl{
 		pdEndpoints:        pdEndpoints,
+		credential:         credential,
 		pdClient:           pdClient,
 		changeFeeds:        make(map[model.ChangeFeedID]*changeFeed),
 		activeProcessors:   make(map[string]*model.ProcessorInfo),
@@ -408,6 +441,8 @@ func NewOwner(pdEndpoints []string, cli kv.CDCEtcdClient, manager roles.Manager)
 		captureWatchC:      watchC,
 		captures:           captures,
 		cancelWatchCapture: cancel,
+		stoppedFeeds:       make(map[model.ChangeFeedID]*model.ChangeFeedStatus),
+		gcTTL:              gcTTL,
 	}
 
 	return owner, nil
@@ -516,7 +551,7 @@ func (o *ownerImpl) newChangeFeed(
 	log.Info("Find new changefeed", zap.Reflect("info", info),
 		zap.String("id", id), zap.Uint64("checkpoint ts", checkpointTs))
 
-	jobs, err := getHistoryDDLJobs(o.pdEndpoints)
+	jobs, err := getHistoryDDLJobs(o.pdEndpoints, o.credential)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -533,7 +568,7 @@ func (o *ownerImpl) newChangeFeed(
 		}
 	}
 
-	ddlHandler := newDDLHandler(o.pdClient, checkpointTs)
+	ddlHandler := newDDLHandler(o.pdClient, o.credential, checkpointTs)
 
 	existingTables := make(map[uint64]uint64)
 	for captureID, taskStatus := range processorsInfos {
@@ -583,7 +618,7 @@ func (o *ownerImpl) newChangeFeed(
 		}
 	}
 
-	sink, err := sink.NewSink(info.SinkURI, filter, info.Opts)
+	sink, err := sink.NewSink(info.SinkURI, filter, info.GetConfig(), info.Opts)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -597,6 +632,8 @@ func (o *ownerImpl) newChangeFeed(
 		tables:        tables,
 		orphanTables:  orphanTables,
 		toCleanTables: make(map[uint64]struct{}),
+		movingTables:  make(map[uint64]model.CaptureID),
+		tableTargets:  make(map[uint64]model.CaptureID),
 		status: &model.ChangeFeedStatus{
 			ResolvedTs:   0,
 			CheckpointTs: checkpointTs,
@@ -610,7 +647,10 @@ func (o *ownerImpl) newChangeFeed(
 		infoWriter:    storage.NewOwnerTaskStatusEtcdWriter(o.etcdClient),
 		filter:        filter,
 		sink:          sink,
+
+		lastRebalanceTime: time.Now(),
 	}
+	cf.updateSinkTables()
 	return cf, nil
 }
 
@@ -619,6 +659,7 @@ func (o *ownerImpl) loadChangeFeeds(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	stoppedFeeds := make(map[model.ChangeFeedID]*model.ChangeFeedStatus)
 	for changeFeedID, cfInfoRawValue := range details {
 		taskStatus, err := o.cfRWriter.GetAllTaskStatus(ctx, changeFeedID)
 		if err != nil {
@@ -633,21 +674,35 @@ func (o *ownerImpl) loadChangeFeeds(ctx context.Context) error {
 			continue
 		}
 
+		cfInfo := &model.ChangeFeedInfo{}
+		err = cfInfo.Unmarshal(cfInfoRawValue.Value)
+		if err != nil {
+			return err
+		}
+		if cfInfo.State == model.StateFailed {
+			continue
+		}
+
 		// we find a new changefeed, init changefeed info here.
 		status, err := o.cfRWriter.GetChangeFeedStatus(ctx, changeFeedID)
 		if err != nil && errors.Cause(err) != model.ErrChangeFeedNotExists {
 			return err
 		}
-		if status != nil && (status.AdminJobType == model.AdminStop || status.AdminJobType == model.AdminRemove) {
+		if status != nil && status.AdminJobType == model.AdminStop {
+			stoppedFeeds[changeFeedID] = status
 			continue
 		}
-
-		cfInfo := &model.ChangeFeedInfo{}
-		err = cfInfo.Unmarshal(cfInfoRawValue.Value)
-		if err != nil {
-			return err
+		if status != nil && status.AdminJobType == model.AdminRemove {
+			continue
 		}
 		checkpointTs := cfInfo.GetCheckpointTs(status)
+		if checkpointTs < o.gcSafePoint {
+			err = o.failChangeFeed(ctx, changeFeedID, cfInfo, checkpointTs)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
 
 		newCf, err := o.newChangeFeed(changeFeedID, taskStatus, taskPositions, cfInfo, checkpointTs)
 		if err != nil {
@@ -655,6 +710,7 @@ func (o *ownerImpl) loadChangeFeeds(ctx context.Context) error {
 		}
 		o.changeFeeds[changeFeedID] = newCf
 	}
+	o.stoppedFeeds = stoppedFeeds
 
 	for _, changefeed := range o.changeFeeds {
 		changefeed.tryBalance(ctx, o.captures)
@@ -671,6 +727,109 @@ func (o *ownerImpl) flushChangeFeedInfos(ctx context.Context) error {
 	return errors.Trace(o.cfRWriter.PutAllChangeFeedStatus(ctx, snapshot))
 }
 
+// updateGCSafePoint registers the minimum checkpoint ts of all the running and
+// paused changefeeds as the service GC safepoint of CDC in PD, and marks the
+// changefeeds whose checkpoint ts falls behind the GC safepoint as failed.
+func (o *ownerImpl) updateGCSafePoint(ctx context.Context) error {
+	if o.gcTTL <= 0 || time.Since(o.lastUpdateGCTime) < gcSafePointUpdateInterval {
+		return nil
+	}
+
+	minCheckpointTs := uint64(math.MaxUint64)
+	for _, cf := range o.changeFeeds {
+		if cf.status.CheckpointTs < minCheckpointTs {
+			minCheckpointTs = cf.status.CheckpointTs
+		}
+	}
+	for _, status := range o.stoppedFeeds {
+		if status.CheckpointTs < minCheckpointTs {
+			minCheckpointTs = status.CheckpointTs
+		}
+	}
+
+	if minCheckpointTs != math.MaxUint64 && !o.serviceGCUnsupported {
+		_, err := o.pdClient.UpdateServiceGCSafePoint(ctx, CDCServiceSafePointID, o.gcTTL, minCheckpointTs)
+		if grpcstatus.Code(errors.Cause(err)) == codes.Unimplemented {
+			// PD supports the service GC safepoint since 4.0
+			o.serviceGCUnsupported = true
+			log.Warn("service gc safepoint is not supported by pd, "+
+				"the changefeeds are not protected from gc", zap.Error(err))
+		} else if err != nil {
+			return errors.Annotate(err, "update service gc safepoint")
+		}
+	}
+
+	safePoint, err := o.getGCSafePoint(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	o.gcSafePoint = safePoint
+	o.lastUpdateGCTime = time.Now()
+
+	for id, cf := range o.changeFeeds {
+		if cf.status.CheckpointTs < safePoint {
+			err := o.failChangeFeed(ctx, id, cf.info, cf.status.CheckpointTs)
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	for id, status := range o.stoppedFeeds {
+		if status.CheckpointTs < safePoint {
+			info, err := o.etcdClient.GetChangeFeedInfo(ctx, id)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			err = o.failChangeFeed(ctx, id, info, status.CheckpointTs)
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// getGCSafePoint returns the GC safepoint of the cluster, which is the larger one
+// of the safepoint in PD and the one saved by TiDB.
+func (o *ownerImpl) getGCSafePoint(ctx context.Context) (uint64, error) {
+	// updating with 0 never moves the safepoint forward, but returns the current one
+	pdSafePoint, err := o.pdClient.UpdateGCSafePoint(ctx, 0)
+	if err != nil {
+		return 0, errors.Annotate(err, "get gc safepoint from pd")
+	}
+	savedSafePoint, err := o.etcdClient.GetSavedGCSafePoint(ctx)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if savedSafePoint > pdSafePoint {
+		return savedSafePoint, nil
+	}
+	return pdSafePoint, nil
+}
+
+// failChangeFeed marks the changefeed whose checkpoint ts falls behind the GC
+// safepoint as failed and stops it, the data it needs may have been collected.
+func (o *ownerImpl) failChangeFeed(
+	ctx context.Context, id model.ChangeFeedID, info *model.ChangeFeedInfo, checkpointTs uint64,
+) error {
+	log.Error("changefeed falls behind the gc safepoint, mark it as failed",
+		zap.String("changefeed", id),
+		zap.Uint64("checkpoint ts", checkpointTs),
+		zap.Uint64("gc safepoint", o.gcSafePoint))
+	info.State = model.StateFailed
+	info.ErrorMsg = fmt.Sprintf("checkpoint ts %d is behind the gc safepoint %d", checkpointTs, o.gcSafePoint)
+	info.AdminJobType = model.AdminStop
+	err := o.etcdClient.SaveChangeFeedInfo(ctx, info, id)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	delete(o.stoppedFeeds, id)
+	if _, ok := o.changeFeeds[id]; ok {
+		return errors.Trace(o.dispatchJob(ctx, model.AdminJob{CfID: id, Type: model.AdminStop}))
+	}
+	return nil
+}
+
 func (c *changeFeed) pullDDLJob() error {
 	ddlResolvedTs, ddlJobs, err := c.ddlHandler.PullDDL()
 	if err != nil {
@@ -688,7 +847,7 @@ func (c *changeFeed) calcResolvedTs(ctx context.Context) error {
 	}
 
 	// ProcessorInfos don't contains the whole set table id now.
-	if len(c.orphanTables) > 0 {
+	if len(c.orphanTables) > 0 || len(c.movingTables) > 0 {
 		return nil
 	}
 
@@ -845,11 +1004,12 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 		schemaName = todoDDLJob.BinlogInfo.DBInfo.Name.O
 	}
 	ddlEvent := &model.DDLEvent{
-		Ts:     todoDDLJob.BinlogInfo.FinishedTS,
-		Query:  todoDDLJob.Query,
-		Schema: schemaName,
-		Table:  tableName,
-		Type:   todoDDLJob.Type,
+		Ts:        todoDDLJob.BinlogInfo.FinishedTS,
+		Query:     todoDDLJob.Query,
+		Schema:    schemaName,
+		Table:     tableName,
+		Type:      todoDDLJob.Type,
+		TableInfo: todoDDLJob.BinlogInfo.TableInfo,
 	}
 
 	err := c.applyJob(todoDDLJob)
@@ -857,7 +1017,7 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 		return errors.Trace(err)
 	}
 
-	c.banlanceOrphanTables(ctx, captures)
+	c.banlanceOrphanTables(ctx, schedulableCaptures(captures))
 
 	err = c.sink.EmitDDLEvent(ctx, ddlEvent)
 	// If DDL executing failed, pause the changefeed and print log, rather
@@ -873,6 +1033,9 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 	log.Info("Execute DDL succeeded",
 		zap.String("ChangeFeedID", c.id),
 		zap.Reflect("ddlJob", todoDDLJob))
+	// the sink sends the DDL of a schema to the topics of the tables in it, so the
+	// tables are updated after the DDL is sent, since dropping a schema removes them.
+	c.updateSinkTables()
 
 	if c.ddlState != model.ChangeFeedExecDDL {
 		log.Fatal("changeFeedState must be ChangeFeedExecDDL when DDL is executed",
@@ -940,13 +1103,16 @@ func (o *ownerImpl) handleAdminJob(ctx context.Context) error {
 				return errors.Trace(err)
 			}
 		case model.AdminRemove:
-			err := o.dispatchJob(ctx, job)
-			if err != nil {
-				return errors.Trace(err)
+			// the stopped changefeed is not in owner cache, its processors have been stopped
+			if _, ok := o.changeFeeds[job.CfID]; ok {
+				err := o.dispatchJob(ctx, job)
+				if err != nil {
+					return errors.Trace(err)
+				}
 			}
 
 			// remove changefeed info
-			err = o.etcdClient.DeleteChangeFeedInfo(ctx, job.CfID)
+			err := o.etcdClient.DeleteChangeFeedInfo(ctx, job.CfID)
 			if err != nil {
 				return errors.Trace(err)
 			}
@@ -973,6 +1139,22 @@ func (o *ownerImpl) handleAdminJob(ctx context.Context) error {
 			if err != nil {
 				return errors.Trace(err)
 			}
+		case model.AdminMoveTable:
+			// the failure of moving table is responded to the requester by the job result
+			cf, ok := o.changeFeeds[job.CfID]
+			if !ok {
+				job.Done(errors.Errorf("changefeed %s not found in owner cache", job.CfID))
+				break
+			}
+			err := cf.moveTable(ctx, job.TableID, job.TargetCaptureID, o.captures)
+			if err != nil {
+				log.Warn("move table failed",
+					zap.String("changefeed", job.CfID),
+					zap.Uint64("table id", job.TableID),
+					zap.String("target capture", job.TargetCaptureID),
+					zap.Error(err))
+			}
+			job.Done(err)
 		}
 		removeIdx = i + 1
 	}
@@ -1092,6 +1274,11 @@ func (o *ownerImpl) run(ctx context.Context) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+
+	err = o.updateGCSafePoint(cctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	return nil
 }
 
@@ -1104,8 +1291,8 @@ func (o *ownerImpl) EnqueueJob(job model.AdminJob) error {
 		return errors.Trace(concurrency.ErrElectionNotLeader)
 	}
 	switch job.Type {
-	case model.AdminResume:
-	case model.AdminStop, model.AdminRemove:
+	case model.AdminResume, model.AdminRemove:
+	case model.AdminStop, model.AdminMoveTable:
 		_, ok := o.changeFeeds[job.CfID]
 		if !ok {
 			return errors.Errorf("changefeed [%s] not found", job.CfID)
@@ -0,0 +1,330 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultTableWorkload is the workload of the tables not reported by the processors yet
+	defaultTableWorkload = 1
+	rebalanceInterval    = time.Minute
+	// the tables are not rebalanced if the workload difference between the
+	// busiest and the idlest captures is within 1/rebalanceTolerance of the busiest one
+	rebalanceTolerance = 10
+	// regionWorkloadWeight is the workload of each region of a table, TiKV sends
+	// the resolved ts of every region about once a second
+	regionWorkloadWeight = 1
+	// sorterBytesPerWorkload is the size of the entries buffered in the sorter
+	// which weighs as much as one event per second
+	sorterBytesPerWorkload = 64 * 1024
+)
+
+// tableWorkload returns the workload of the table reported by the processor of the capture,
+// the event rate, the regions and the entries buffered in the sorter are weighted together.
+func (c *changeFeed) tableWorkload(captureID model.CaptureID, tableID uint64) uint64 {
+	if pos, ok := c.taskPositions[captureID]; ok {
+		if workload, ok := pos.Workload[tableID]; ok {
+			return weightWorkload(workload)
+		}
+	}
+	return defaultTableWorkload
+}
+
+func weightWorkload(workload model.WorkloadInfo) uint64 {
+	return workload.Workload +
+		workload.RegionCount*regionWorkloadWeight +
+		workload.SorterBytes/sorterBytesPerWorkload
+}
+
+// captureWorkloads sums up the workloads of the tables dispatched to each alive capture
+func (c *changeFeed) captureWorkloads(captures map[string]*model.CaptureInfo) map[model.CaptureID]uint64 {
+	workloads := make(map[model.CaptureID]uint64, len(captures))
+	for id := range captures {
+		workloads[id] = 0
+		status, ok := c.taskStatus[id]
+		if !ok {
+			continue
+		}
+		for _, table := range status.TableInfos {
+			workloads[id] += c.tableWorkload(id, table.ID)
+		}
+	}
+	return workloads
+}
+
+// schedulableCaptures returns the captures that are not draining, all the
+// captures are returned if every capture is draining to keep replicating.
+func schedulableCaptures(captures map[string]*model.CaptureInfo) map[string]*model.CaptureInfo {
+	schedulable := make(map[string]*model.CaptureInfo, len(captures))
+	for id, info := range captures {
+		if !info.Draining {
+			schedulable[id] = info
+		}
+	}
+	if len(schedulable) == 0 {
+		return captures
+	}
+	return schedulable
+}
+
+func (c *changeFeed) selectCapture(captures map[string]*model.CaptureInfo) string {
+	return c.minimumWorkloadCapture(captures)
+}
+
+func (c *changeFeed) minimumWorkloadCapture(captures map[string]*model.CaptureInfo) string {
+	var minID string
+	minWorkload := uint64(math.MaxUint64)
+	for id, workload := range c.captureWorkloads(captures) {
+		if workload < minWorkload || (workload == minWorkload && id < minID) {
+			minID = id
+			minWorkload = workload
+		}
+	}
+	return minID
+}
+
+// selectMovingTable selects the table to move from the capture, the workload
+// difference between the source and the target capture is reduced most by
+// moving it. It returns false if no table can reduce the difference.
+func (c *changeFeed) selectMovingTable(captureID model.CaptureID, diff uint64) (uint64, bool) {
+	status, ok := c.taskStatus[captureID]
+	if !ok {
+		return 0, false
+	}
+	var (
+		tableID uint64
+		found   bool
+		// the workload difference after moving the selected table
+		minDiff = diff
+	)
+	for _, table := range status.TableInfos {
+		if target, ok := c.tableTargets[table.ID]; ok && target == captureID {
+			continue
+		}
+		workload := c.tableWorkload(captureID, table.ID)
+		if workload >= diff {
+			continue
+		}
+		newDiff := diff - 2*workload
+		if 2*workload > diff {
+			newDiff = 2*workload - diff
+		}
+		if newDiff < minDiff || (newDiff == minDiff && found && table.ID < tableID) {
+			tableID = table.ID
+			minDiff = newDiff
+			found = true
+		}
+	}
+	return tableID, found
+}
+
+// rebalanceTables moves a table from the busiest capture if the workloads of
+// the captures are unbalanced. The table is removed from the busiest capture
+// with a P-lock, and will be dispatched again from the checkpoint ts recorded
+// in the C-lock after the processor commits the lock, see handleMovingTables.
+func (c *changeFeed) rebalanceTables(ctx context.Context, captures map[string]*model.CaptureInfo) {
+	if len(captures) < 2 || c.ddlState != model.ChangeFeedSyncDML ||
+		len(c.orphanTables) > 0 || len(c.toCleanTables) > 0 || len(c.movingTables) > 0 ||
+		time.Since(c.lastRebalanceTime) < rebalanceInterval {
+		return
+	}
+	c.lastRebalanceTime = time.Now()
+
+	var (
+		maxID, minID string
+		maxWorkload  uint64
+		minWorkload  = uint64(math.MaxUint64)
+	)
+	for id, workload := range c.captureWorkloads(captures) {
+		if workload > maxWorkload || (workload == maxWorkload && id < maxID) {
+			maxID = id
+			maxWorkload = workload
+		}
+		if workload < minWorkload || (workload == minWorkload && id < minID) {
+			minID = id
+			minWorkload = workload
+		}
+	}
+	diff := maxWorkload - minWorkload
+	if maxID == minID || diff*rebalanceTolerance <= maxWorkload {
+		return
+	}
+
+	tableID, ok := c.selectMovingTable(maxID, diff)
+	if !ok {
+		return
+	}
+
+	err := c.startMovingTable(ctx, maxID, tableID)
+	switch errors.Cause(err) {
+	case model.ErrFindPLockNotCommit:
+		log.Info("write table info delay, wait plock resolve",
+			zap.String("changefeed", c.id),
+			zap.String("capture", maxID))
+	case nil:
+		log.Info("move table for rebalance",
+			zap.String("changefeed", c.id),
+			zap.Uint64("table id", tableID),
+			zap.String("from capture", maxID),
+			zap.Uint64("from workload", maxWorkload),
+			zap.Uint64("to workload", minWorkload))
+	default:
+		log.Error("fail to put sub changefeed info", zap.Error(err))
+	}
+}
+
+// moveTable moves the table to the target capture manually. If the table is
+// not dispatched yet, it's dispatched to the target capture directly. The table
+// is pinned to the target capture so that it's not moved back by rebalance.
+func (c *changeFeed) moveTable(
+	ctx context.Context, tableID uint64, target model.CaptureID, captures map[string]*model.CaptureInfo,
+) error {
+	info, ok := captures[target]
+	if !ok {
+		return errors.Errorf("target capture %s not found", target)
+	}
+	if info.Draining {
+		return errors.Errorf("target capture %s is draining", target)
+	}
+	if _, ok := c.movingTables[tableID]; ok {
+		return errors.Errorf("table %d is being moved", tableID)
+	}
+	if _, ok := c.orphanTables[tableID]; ok {
+		c.tableTargets[tableID] = target
+		return nil
+	}
+	captureID, _, ok := findTaskStatusWithTable(c.taskStatus, tableID)
+	if !ok {
+		return errors.Errorf("table %d not found", tableID)
+	}
+	if captureID == target {
+		c.tableTargets[tableID] = target
+		log.Info("table is already on the target capture",
+			zap.String("changefeed", c.id),
+			zap.Uint64("table id", tableID),
+			zap.String("capture", target))
+		return nil
+	}
+	err := c.startMovingTable(ctx, captureID, tableID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.tableTargets[tableID] = target
+	log.Info("move table manually",
+		zap.String("changefeed", c.id),
+		zap.Uint64("table id", tableID),
+		zap.String("from capture", captureID),
+		zap.String("to capture", target))
+	return nil
+}
+
+// drainCaptures moves all the tables out of the draining captures, as long as
+// there is any other capture to take them over.
+func (c *changeFeed) drainCaptures(ctx context.Context, captures map[string]*model.CaptureInfo) {
+	var draining []model.CaptureID
+	for id, info := range captures {
+		if info.Draining {
+			draining = append(draining, id)
+		}
+	}
+	if len(draining) == 0 || len(draining) == len(captures) {
+		return
+	}
+	for _, captureID := range draining {
+		status, ok := c.taskStatus[captureID]
+		if !ok || len(status.TableInfos) == 0 {
+			continue
+		}
+		tableIDs := make([]uint64, 0, len(status.TableInfos))
+		for _, table := range status.TableInfos {
+			tableIDs = append(tableIDs, table.ID)
+		}
+		err := c.startMovingTable(ctx, captureID, tableIDs...)
+		switch errors.Cause(err) {
+		case model.ErrFindPLockNotCommit:
+			log.Info("write table info delay, wait plock resolve",
+				zap.String("changefeed", c.id),
+				zap.String("capture", captureID))
+		case nil:
+			log.Info("move tables out of the draining capture",
+				zap.String("changefeed", c.id),
+				zap.String("capture", captureID),
+				zap.Uint64s("table ids", tableIDs))
+		default:
+			log.Error("fail to put sub changefeed info", zap.Error(err))
+		}
+	}
+}
+
+// startMovingTable removes the tables from the capture with a P-lock, the tables
+// are dispatched again after the processor commits the lock.
+func (c *changeFeed) startMovingTable(ctx context.Context, captureID model.CaptureID, tableIDs ...uint64) error {
+	status := c.taskStatus[captureID]
+	infoClone := status.Clone()
+	for _, tableID := range tableIDs {
+		status.RemoveTable(tableID)
+	}
+	newInfo, err := c.infoWriter.Write(ctx, c.id, captureID, status, true)
+	if err != nil {
+		c.restoreTableInfos(infoClone, captureID)
+		return errors.Trace(err)
+	}
+	c.taskStatus[captureID] = newInfo
+	for _, tableID := range tableIDs {
+		c.movingTables[tableID] = captureID
+	}
+	return nil
+}
+
+// handleMovingTables re-adds the moving tables as orphan tables after the processors
+// they are removed from have committed the P-lock, then they will be dispatched
+// from the checkpoint ts recorded in the C-lock.
+func (c *changeFeed) handleMovingTables(captures map[string]*model.CaptureInfo) {
+	for tableID, captureID := range c.movingTables {
+		startTs := c.status.CheckpointTs
+		status, ok := c.taskStatus[captureID]
+		_, alive := captures[captureID]
+		switch {
+		case !ok || !alive || status.TablePLock == nil:
+			// The source capture is gone or the lock has been cleaned before the
+			// owner sees the C-lock, the table is replicated again from the checkpoint
+			// ts of the changefeed, which is safe but some events may be sent twice.
+			log.Warn("the checkpoint of the moving table is lost",
+				zap.String("changefeed", c.id),
+				zap.Uint64("table id", tableID),
+				zap.String("capture", captureID))
+		case status.TableCLock == nil:
+			continue
+		default:
+			if status.TableCLock.CheckpointTs > startTs {
+				startTs = status.TableCLock.CheckpointTs
+			}
+		}
+		log.Info("re-add moving table",
+			zap.String("changefeed", c.id),
+			zap.Uint64("table id", tableID),
+			zap.Uint64("start ts", startTs))
+		c.reAddTable(tableID, startTs)
+		delete(c.movingTables, tableID)
+	}
+}
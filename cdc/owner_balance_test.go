@@ -0,0 +1,185 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+type balanceSuite struct{}
+
+var _ = check.Suite(&balanceSuite{})
+
+func newChangeFeedForBalance() *changeFeed {
+	return &changeFeed{
+		status: &model.ChangeFeedStatus{CheckpointTs: 100},
+		taskStatus: model.ProcessorsInfos{
+			"c1": {TableInfos: []*model.ProcessTableInfo{{ID: 1}, {ID: 2}, {ID: 3}}},
+			"c2": {TableInfos: []*model.ProcessTableInfo{{ID: 4}}},
+		},
+		taskPositions: map[string]*model.TaskPosition{
+			"c1": {Workload: model.TaskWorkload{1: {Workload: 50}, 2: {Workload: 30}, 3: {Workload: 10}}},
+			"c2": {Workload: model.TaskWorkload{4: {Workload: 20}}},
+		},
+		orphanTables:  make(map[uint64]model.ProcessTableInfo),
+		toCleanTables: make(map[uint64]struct{}),
+		movingTables:  make(map[uint64]model.CaptureID),
+		tableTargets:  make(map[uint64]model.CaptureID),
+	}
+}
+
+func (s *balanceSuite) TestMinimumWorkloadCapture(c *check.C) {
+	cf := newChangeFeedForBalance()
+	captures := map[string]*model.CaptureInfo{"c1": {}, "c2": {}}
+	c.Assert(cf.captureWorkloads(captures), check.DeepEquals, map[model.CaptureID]uint64{"c1": 90, "c2": 20})
+	c.Assert(cf.selectCapture(captures), check.Equals, "c2")
+
+	// the new capture has no workload
+	captures["c3"] = &model.CaptureInfo{}
+	c.Assert(cf.selectCapture(captures), check.Equals, "c3")
+
+	// the tables not reported yet have the default workload
+	cf.taskStatus["c3"] = &model.TaskStatus{TableInfos: []*model.ProcessTableInfo{{ID: 5}}}
+	c.Assert(cf.captureWorkloads(captures)["c3"], check.Equals, uint64(defaultTableWorkload))
+	c.Assert(cf.selectCapture(captures), check.Equals, "c3")
+
+	// the dead capture is never selected
+	delete(captures, "c3")
+	delete(captures, "c2")
+	c.Assert(cf.selectCapture(captures), check.Equals, "c1")
+	c.Assert(cf.selectCapture(map[string]*model.CaptureInfo{}), check.Equals, "")
+}
+
+func (s *balanceSuite) TestWeightWorkload(c *check.C) {
+	cf := newChangeFeedForBalance()
+	captures := map[string]*model.CaptureInfo{"c1": {}, "c2": {}}
+	// table 4 has few events but many regions and a lot of buffered entries
+	cf.taskPositions["c2"].Workload[4] = model.WorkloadInfo{
+		Workload:    20,
+		RegionCount: 40,
+		SorterBytes: 50 * sorterBytesPerWorkload,
+	}
+	c.Assert(cf.tableWorkload("c2", 4), check.Equals, uint64(110))
+	c.Assert(cf.captureWorkloads(captures), check.DeepEquals, map[model.CaptureID]uint64{"c1": 90, "c2": 110})
+	c.Assert(cf.selectCapture(captures), check.Equals, "c1")
+}
+
+func (s *balanceSuite) TestSelectMovingTable(c *check.C) {
+	cf := newChangeFeedForBalance()
+	// c1: 90, c2: 20, moving table 2 makes it 60 and 50
+	tableID, ok := cf.selectMovingTable("c1", 70)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(tableID, check.Equals, uint64(2))
+
+	// no table is small enough to reduce the difference
+	_, ok = cf.selectMovingTable("c2", 20)
+	c.Assert(ok, check.IsFalse)
+	_, ok = cf.selectMovingTable("c3", 20)
+	c.Assert(ok, check.IsFalse)
+}
+
+func (s *balanceSuite) TestHandleMovingTables(c *check.C) {
+	cf := newChangeFeedForBalance()
+	captures := map[string]*model.CaptureInfo{"c1": {}, "c2": {}}
+	cf.movingTables[2] = "c1"
+	cf.taskStatus["c1"].TablePLock = &model.TableLock{Ts: 1}
+
+	// the p-lock is not committed yet
+	cf.handleMovingTables(captures)
+	c.Assert(cf.movingTables, check.HasLen, 1)
+	c.Assert(cf.orphanTables, check.HasLen, 0)
+
+	cf.taskStatus["c1"].TableCLock = &model.TableLock{Ts: 1, CheckpointTs: 120}
+	cf.handleMovingTables(captures)
+	c.Assert(cf.movingTables, check.HasLen, 0)
+	c.Assert(cf.orphanTables, check.DeepEquals, map[uint64]model.ProcessTableInfo{2: {ID: 2, StartTs: 120}})
+
+	// the source capture is gone, restart from the checkpoint of the changefeed
+	cf.movingTables[4] = "c2"
+	delete(captures, "c2")
+	cf.handleMovingTables(captures)
+	c.Assert(cf.movingTables, check.HasLen, 0)
+	c.Assert(cf.orphanTables[4], check.DeepEquals, model.ProcessTableInfo{ID: 4, StartTs: 100})
+}
+
+func (s *balanceSuite) TestMoveTable(c *check.C) {
+	ctx := context.Background()
+	cf := newChangeFeedForBalance()
+	captures := map[string]*model.CaptureInfo{"c1": {}, "c2": {}}
+
+	c.Assert(cf.moveTable(ctx, 1, "c3", captures), check.ErrorMatches, ".*target capture c3 not found.*")
+	c.Assert(cf.moveTable(ctx, 10, "c2", captures), check.ErrorMatches, ".*table 10 not found.*")
+	c.Assert(cf.moveTable(ctx, 4, "c2", captures), check.IsNil)
+	c.Assert(cf.movingTables, check.HasLen, 0)
+
+	cf.movingTables[1] = "c1"
+	c.Assert(cf.moveTable(ctx, 1, "c2", captures), check.ErrorMatches, ".*table 1 is being moved.*")
+
+	// the orphan table is dispatched to the target capture directly
+	cf.orphanTables[5] = model.ProcessTableInfo{ID: 5, StartTs: 100}
+	c.Assert(cf.moveTable(ctx, 5, "c1", captures), check.IsNil)
+	c.Assert(cf.tableTargets, check.DeepEquals, map[uint64]model.CaptureID{4: "c2", 5: "c1"})
+}
+
+func (s *balanceSuite) TestPinMovedTable(c *check.C) {
+	ctx := context.Background()
+	cf := newChangeFeedForBalance()
+	captures := map[string]*model.CaptureInfo{"c1": {}, "c2": {}}
+
+	// the pinned tables are never selected by rebalance
+	c.Assert(cf.moveTable(ctx, 4, "c2", captures), check.IsNil)
+	_, ok := cf.selectMovingTable("c2", 70)
+	c.Assert(ok, check.IsFalse)
+	cf.tableTargets[2] = "c1"
+	tableID, ok := cf.selectMovingTable("c1", 70)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(tableID, check.Equals, uint64(1))
+
+	// the pin is released when the table is removed
+	cf.removeTable(0, 2)
+	c.Assert(cf.tableTargets, check.DeepEquals, map[uint64]model.CaptureID{4: "c2"})
+}
+
+func (s *balanceSuite) TestAdminJobDone(c *check.C) {
+	// the job without a result channel is fine
+	model.AdminJob{Type: model.AdminMoveTable}.Done(nil)
+
+	result := make(chan error, 1)
+	job := model.AdminJob{Type: model.AdminMoveTable, Result: result}
+	job.Done(errors.New("move table failed"))
+	// never blocks even if the result is not received
+	job.Done(nil)
+	c.Assert(<-result, check.ErrorMatches, "move table failed")
+}
+
+func (s *balanceSuite) TestSchedulableCaptures(c *check.C) {
+	captures := map[string]*model.CaptureInfo{
+		"c1": {ID: "c1", Draining: true},
+		"c2": {ID: "c2"},
+	}
+	schedulable := schedulableCaptures(captures)
+	c.Assert(schedulable, check.HasLen, 1)
+	c.Assert(schedulable["c2"], check.NotNil)
+
+	cf := newChangeFeedForBalance()
+	c.Assert(cf.moveTable(context.Background(), 4, "c1", captures), check.ErrorMatches, ".*target capture c1 is draining.*")
+
+	// all the captures are returned if every capture is draining
+	captures["c2"].Draining = true
+	c.Assert(schedulableCaptures(captures), check.HasLen, 2)
+}
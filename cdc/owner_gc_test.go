@@ -0,0 +1,138 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/pingcap/check"
+	pd "github.com/pingcap/pd/v4/client"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/etcd"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb/store/mockstore/mocktikv"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/embed"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+type gcSafePointSuite struct {
+	etcd      *embed.Etcd
+	clientURL *url.URL
+	client    kv.CDCEtcdClient
+	ctx       context.Context
+	cancel    context.CancelFunc
+	errg      *errgroup.Group
+}
+
+var _ = check.Suite(&gcSafePointSuite{})
+
+func (s *gcSafePointSuite) SetUpTest(c *check.C) {
+	dir := c.MkDir()
+	var err error
+	s.clientURL, s.etcd, err = etcd.SetupEmbedEtcd(dir)
+	c.Assert(err, check.IsNil)
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{s.clientURL.String()},
+		DialTimeout: 3 * time.Second,
+	})
+	c.Assert(err, check.IsNil)
+	s.client = kv.NewCDCEtcdClient(client)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.errg = util.HandleErrWithErrGroup(s.ctx, s.etcd.Err(), func(e error) { c.Log(e) })
+}
+
+func (s *gcSafePointSuite) TearDownTest(c *check.C) {
+	s.etcd.Close()
+	s.cancel()
+	err := s.errg.Wait()
+	if err != nil {
+		c.Errorf("Error group error: %s", err)
+	}
+}
+
+// mockSafePointPDClient records the service GC safepoint updated by the owner
+type mockSafePointPDClient struct {
+	pd.Client
+	serviceID string
+	ttl       int64
+	safePoint uint64
+	calls     int
+	err       error
+}
+
+func (m *mockSafePointPDClient) UpdateServiceGCSafePoint(
+	ctx context.Context, serviceID string, ttl int64, safePoint uint64,
+) (uint64, error) {
+	m.calls++
+	if m.err != nil {
+		return 0, m.err
+	}
+	m.serviceID = serviceID
+	m.ttl = ttl
+	m.safePoint = safePoint
+	return safePoint, nil
+}
+
+func (s *gcSafePointSuite) TestUpdateServiceSafePoint(c *check.C) {
+	pdClient := &mockSafePointPDClient{Client: mocktikv.NewPDClient(mocktikv.NewCluster())}
+	owner := &ownerImpl{
+		pdClient:   pdClient,
+		etcdClient: s.client,
+		gcTTL:      600,
+		changeFeeds: map[model.ChangeFeedID]*changeFeed{
+			"cf1": {status: &model.ChangeFeedStatus{CheckpointTs: 300}},
+			"cf2": {status: &model.ChangeFeedStatus{CheckpointTs: 200}},
+		},
+		stoppedFeeds: map[model.ChangeFeedID]*model.ChangeFeedStatus{
+			"cf3": {CheckpointTs: 250},
+		},
+	}
+
+	// the minimum checkpoint ts of the changefeeds is sent to pd
+	c.Assert(owner.updateGCSafePoint(s.ctx), check.IsNil)
+	c.Assert(pdClient.calls, check.Equals, 1)
+	c.Assert(pdClient.serviceID, check.Equals, CDCServiceSafePointID)
+	c.Assert(pdClient.ttl, check.Equals, int64(600))
+	c.Assert(pdClient.safePoint, check.Equals, uint64(200))
+
+	// the safepoint is updated at most once in the interval
+	owner.changeFeeds["cf2"].status.CheckpointTs = 400
+	c.Assert(owner.updateGCSafePoint(s.ctx), check.IsNil)
+	c.Assert(pdClient.calls, check.Equals, 1)
+	owner.lastUpdateGCTime = time.Time{}
+	c.Assert(owner.updateGCSafePoint(s.ctx), check.IsNil)
+	c.Assert(pdClient.calls, check.Equals, 2)
+	c.Assert(pdClient.safePoint, check.Equals, uint64(250))
+
+	// the errors of pd are returned
+	pdClient.err = grpcstatus.Error(codes.Unavailable, "pd is unavailable")
+	owner.lastUpdateGCTime = time.Time{}
+	c.Assert(owner.updateGCSafePoint(s.ctx), check.ErrorMatches, ".*pd is unavailable.*")
+
+	// the service safepoint is not updated any more if pd doesn't support it
+	pdClient.err = grpcstatus.Error(codes.Unimplemented, "unknown method")
+	owner.lastUpdateGCTime = time.Time{}
+	c.Assert(owner.updateGCSafePoint(s.ctx), check.IsNil)
+	c.Assert(owner.serviceGCUnsupported, check.IsTrue)
+	calls := pdClient.calls
+	owner.lastUpdateGCTime = time.Time{}
+	c.Assert(owner.updateGCSafePoint(s.ctx), check.IsNil)
+	c.Assert(pdClient.calls, check.Equals, calls)
+}
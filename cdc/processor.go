@@ -29,7 +29,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	timodel "github.com/pingcap/parser/model"
-	pd "github.com/pingcap/pd/client"
+	pd "github.com/pingcap/pd/v4/client"
 	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
@@ -37,6 +37,7 @@ import (
 	"github.com/pingcap/ticdc/cdc/roles/storage"
 	"github.com/pingcap/ticdc/cdc/sink"
 	"github.com/pingcap/ticdc/pkg/retry"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/pingcap/tidb/store/helper"
 	"github.com/pingcap/tidb/store/tikv"
@@ -55,6 +56,7 @@ const (
 	resolveTsInterval           = time.Millisecond * 500
 	waitGlobalResolvedTsDelay   = time.Millisecond * 500
 	waitFallbackResolvedTsDelay = time.Millisecond * 500
+	updateWorkloadInterval      = time.Second * 10
 
 	defaultOutputChanSize = 128
 
@@ -76,14 +78,16 @@ type processor struct {
 	changefeed   model.ChangeFeedInfo
 	limitter     *puller.BlurResourceLimitter
 
-	pdCli   pd.Client
-	etcdCli kv.CDCEtcdClient
-	session *concurrency.Session
+	pdCli      pd.Client
+	credential *security.Credential
+	etcdCli    kv.CDCEtcdClient
+	session    *concurrency.Session
 
 	sink sink.Sink
 
-	ddlPuller     puller.Puller
-	schemaBuilder *entry.StorageBuilder
+	ddlPuller      puller.Puller
+	schemaBuilder  *entry.StorageBuilder
+	oldValueReader entry.OldValueReader
 
 	tsRWriter storage.ProcessorTsRWriter
 	output    chan *model.RowChangedEvent
@@ -91,6 +95,7 @@ type processor struct {
 	status             *model.TaskStatus
 	position           *model.TaskPosition
 	resolvedTsFallback int32
+	lastWorkloadTime   time.Time
 
 	tablesMu sync.Mutex
 	tables   map[int64]*tableInfo
@@ -103,7 +108,9 @@ type tableInfo struct {
 	id         int64
 	mounter    entry.Mounter
 	resolvedTS uint64
+	eventCount uint64
 	cancel     context.CancelFunc
+	puller     puller.Puller
 }
 
 func (t *tableInfo) loadResolvedTS() uint64 {
@@ -114,21 +121,36 @@ func (t *tableInfo) storeResolvedTS(ts uint64) {
 	atomic.StoreUint64(&t.resolvedTS, ts)
 }
 
+func (t *tableInfo) incEventCount() {
+	atomic.AddUint64(&t.eventCount, 1)
+}
+
+// resetEventCount returns the number of events since the last reset
+func (t *tableInfo) resetEventCount() uint64 {
+	return atomic.SwapUint64(&t.eventCount, 0)
+}
+
 // NewProcessor creates and returns a processor for the specified change feed
 func NewProcessor(
 	ctx context.Context,
 	pdEndpoints []string,
+	credential *security.Credential,
 	changefeed model.ChangeFeedInfo,
 	sink sink.Sink,
 	changefeedID, captureID string,
 	checkpointTs uint64) (*processor, error) {
-	pdCli, err := fNewPDCli(pdEndpoints, pd.SecurityOption{})
+	pdCli, err := fNewPDCli(pdEndpoints, credential.PDSecurityOption())
 	if err != nil {
 		return nil, errors.Annotatef(err, "create pd client failed, addr: %v", pdEndpoints)
 	}
 
+	tlsConfig, err := credential.ToTLSConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	etcdCli, err := clientv3.New(clientv3.Config{
 		Endpoints:   pdEndpoints,
+		TLS:         tlsConfig,
 		DialTimeout: 5 * time.Second,
 		DialOptions: []grpc.DialOption{
 			grpc.WithConnectParams(grpc.ConnectParams{
@@ -159,33 +181,53 @@ func NewProcessor(
 
 	limitter := puller.NewBlurResourceLimmter(defaultMemBufferCapacity)
 
+	if changefeed.SortDir != "" {
+		// the sorted run files of a crashed capture are never removed otherwise
+		if err := puller.CleanSortDir(changefeed.SortDir); err != nil {
+			log.Warn("clean sort dir failed", zap.String("dir", changefeed.SortDir), zap.Error(err))
+		}
+	}
+
 	// The key in DDL kv pair returned from TiKV is already memcompariable encoded,
 	// so we set `needEncode` to false.
-	ddlPuller := puller.NewPuller(pdCli, checkpointTs, []util.Span{util.GetDDLSpan()}, false, limitter)
+	ddlPuller := puller.NewPuller(pdCli, credential, checkpointTs, []util.Span{util.GetDDLSpan()}, false, limitter, "")
 	ddlEventCh := ddlPuller.SortedOutput(ctx)
-	schemaBuilder, err := createSchemaBuilder(pdEndpoints, ddlEventCh)
+	schemaBuilder, err := createSchemaBuilder(pdEndpoints, credential, ddlEventCh)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 
+	var oldValueReader entry.OldValueReader
+	if changefeed.GetConfig().EnableOldValue {
+		kvStore, err := createTiStore(strings.Join(pdEndpoints, ","), credential)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		oldValueReader = entry.NewOldValueReader(kvStore)
+	}
+
 	p := &processor{
-		id:            uuid.New().String(),
-		limitter:      limitter,
-		captureID:     captureID,
-		changefeedID:  changefeedID,
-		changefeed:    changefeed,
-		pdCli:         pdCli,
-		etcdCli:       cdcEtcdCli,
-		session:       sess,
-		sink:          sink,
-		ddlPuller:     ddlPuller,
-		schemaBuilder: schemaBuilder,
+		id:             uuid.New().String(),
+		limitter:       limitter,
+		captureID:      captureID,
+		changefeedID:   changefeedID,
+		changefeed:     changefeed,
+		pdCli:          pdCli,
+		credential:     credential,
+		etcdCli:        cdcEtcdCli,
+		session:        sess,
+		sink:           sink,
+		ddlPuller:      ddlPuller,
+		schemaBuilder:  schemaBuilder,
+		oldValueReader: oldValueReader,
 
 		tsRWriter: tsRWriter,
 		status:    tsRWriter.GetTaskStatus(),
 		position:  &model.TaskPosition{CheckPointTs: checkpointTs},
 		output:    make(chan *model.RowChangedEvent, defaultOutputChanSize),
 
+		lastWorkloadTime: time.Now(),
+
 		tables: make(map[int64]*tableInfo),
 	}
 
@@ -269,6 +311,7 @@ func (p *processor) positionWorker(ctx context.Context) error {
 	updateInfoTick := time.NewTicker(updateInfoInterval)
 	resolveTsTick := time.NewTicker(resolveTsInterval)
 	checkpointTsTick := time.NewTicker(resolveTsInterval)
+	workloadTick := time.NewTicker(updateWorkloadInterval)
 
 	updateInfo := func() error {
 		t0Update := time.Now()
@@ -290,6 +333,7 @@ func (p *processor) positionWorker(ctx context.Context) error {
 		updateInfoTick.Stop()
 		resolveTsTick.Stop()
 		checkpointTsTick.Stop()
+		workloadTick.Stop()
 
 		err := updateInfo()
 		if err != nil {
@@ -345,8 +389,35 @@ func (p *processor) positionWorker(ctx context.Context) error {
 			if err != nil {
 				return errors.Trace(err)
 			}
+		case <-workloadTick.C:
+			p.updateWorkload()
+		}
+	}
+}
+
+// updateWorkload estimates the workloads of the tables by the event throughput
+// since the last estimation, the number of the regions and the size of the entries
+// buffered in the sorter, the workloads are reported to the owner in the task position.
+func (p *processor) updateWorkload() {
+	now := time.Now()
+	seconds := now.Sub(p.lastWorkloadTime).Seconds()
+	p.lastWorkloadTime = now
+	if seconds <= 0 {
+		return
+	}
+
+	p.tablesMu.Lock()
+	defer p.tablesMu.Unlock()
+	workload := make(model.TaskWorkload, len(p.tables))
+	for id, table := range p.tables {
+		eventRate := float64(table.resetEventCount()) / seconds
+		workload[uint64(id)] = model.WorkloadInfo{
+			Workload:    1 + uint64(eventRate),
+			RegionCount: uint64(table.puller.RegionCount()),
+			SorterBytes: uint64(table.puller.SorterBufferedBytes()),
 		}
 	}
+	p.position.Workload = workload
 }
 
 func (p *processor) updateInfo(ctx context.Context) error {
@@ -552,8 +623,10 @@ func (p *processor) syncResolved(ctx context.Context) error {
 	}
 }
 
-func createSchemaBuilder(pdEndpoints []string, ddlEventCh <-chan *model.RawKVEntry) (*entry.StorageBuilder, error) {
-	jobs, err := getHistoryDDLJobs(pdEndpoints)
+func createSchemaBuilder(
+	pdEndpoints []string, credential *security.Credential, ddlEventCh <-chan *model.RawKVEntry,
+) (*entry.StorageBuilder, error) {
+	jobs, err := getHistoryDDLJobs(pdEndpoints, credential)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -561,9 +634,9 @@ func createSchemaBuilder(pdEndpoints []string, ddlEventCh <-chan *model.RawKVEnt
 	return builder, nil
 }
 
-func getHistoryDDLJobs(pdEndpoints []string) ([]*timodel.Job, error) {
+func getHistoryDDLJobs(pdEndpoints []string, credential *security.Credential) ([]*timodel.Job, error) {
 	// TODO here we create another pb client,we should reuse them
-	kvStore, err := createTiStore(strings.Join(pdEndpoints, ","))
+	kvStore, err := createTiStore(strings.Join(pdEndpoints, ","), credential)
 	if err != nil {
 		return nil, err
 	}
@@ -647,7 +720,8 @@ func (p *processor) addTable(ctx context.Context, tableID int64, startTs uint64)
 	// The key in DML kv pair returned from TiKV is not memcompariable encoded,
 	// so we set `needEncode` to true.
 	span := util.GetTableSpan(tableID, true)
-	puller := puller.NewPuller(p.pdCli, startTs, []util.Span{span}, true, p.limitter)
+	puller := puller.NewPuller(p.pdCli, p.credential, startTs, []util.Span{span}, true, p.limitter, p.changefeed.SortDir)
+	table.puller = puller
 	go func() {
 		err := puller.Run(ctx)
 		if errors.Cause(err) != context.Canceled {
@@ -659,7 +733,7 @@ func (p *processor) addTable(ctx context.Context, tableID int64, startTs uint64)
 		p.errCh <- errors.Trace(err)
 	}
 	// start mounter
-	mounter := entry.NewMounter(puller.SortedOutput(ctx), storage)
+	mounter := entry.NewMounter(puller.SortedOutput(ctx), storage, p.oldValueReader)
 	go func() {
 		err := mounter.Run(ctx)
 		if errors.Cause(err) != context.Canceled {
@@ -686,6 +760,7 @@ func (p *processor) addTable(ctx context.Context, tableID int64, startTs uint64)
 					}
 					return
 				case p.output <- row:
+					table.incEventCount()
 				}
 			}
 		}
@@ -9,6 +9,15 @@ import (
 	"github.com/pingcap/ticdc/cdc/model"
 )
 
+// Sorter accepts out-of-order raw kv entries and output sorted entries
+type Sorter interface {
+	Run(ctx context.Context)
+	AddEntry(entry *model.RawKVEntry)
+	Output() <-chan *model.RawKVEntry
+	// BufferedBytes returns the size of the entries added but not output yet
+	BufferedBytes() int64
+}
+
 // EntrySorter accepts out-of-order raw kv entries and output sorted entries
 type EntrySorter struct {
 	unsorted   []*model.RawKVEntry
@@ -16,6 +25,8 @@ type EntrySorter struct {
 	lock       sync.Mutex
 	resolvedTs uint64
 	closed     int32
+	// bufferedSize is the size of the entries added but not output yet
+	bufferedSize int64
 
 	output chan *model.RawKVEntry
 }
@@ -28,14 +39,15 @@ func NewEntrySorter() *EntrySorter {
 	}
 }
 
+func lessFunc(i *model.RawKVEntry, j *model.RawKVEntry) bool {
+	if i.Ts == j.Ts {
+		return i.OpType == model.OpTypeDelete
+	}
+	return i.Ts < j.Ts
+}
+
 // Run runs EntrySorter
 func (es *EntrySorter) Run(ctx context.Context) {
-	lessFunc := func(i *model.RawKVEntry, j *model.RawKVEntry) bool {
-		if i.Ts == j.Ts {
-			return i.OpType == model.OpTypeDelete
-		}
-		return i.Ts < j.Ts
-	}
 	mergeFunc := func(kvsA []*model.RawKVEntry, kvsB []*model.RawKVEntry, output func(*model.RawKVEntry)) {
 		var i, j int
 		for i < len(kvsA) && j < len(kvsB) {
@@ -78,6 +90,7 @@ func (es *EntrySorter) Run(ctx context.Context) {
 				mergeFunc(toSort, sorted, func(entry *model.RawKVEntry) {
 					if entry.Ts <= resolvedTs {
 						es.output <- entry
+						atomic.AddInt64(&es.bufferedSize, -entryMemSize(entry))
 					} else {
 						merged = append(merged, entry)
 					}
@@ -99,6 +112,7 @@ func (es *EntrySorter) AddEntry(entry *model.RawKVEntry) {
 		es.resolvedCh <- entry.Ts
 		return
 	}
+	atomic.AddInt64(&es.bufferedSize, entryMemSize(entry))
 	es.lock.Lock()
 	defer es.lock.Unlock()
 	es.unsorted = append(es.unsorted, entry)
@@ -109,3 +123,8 @@ func (es *EntrySorter) AddEntry(entry *model.RawKVEntry) {
 func (es *EntrySorter) Output() <-chan *model.RawKVEntry {
 	return es.output
 }
+
+// BufferedBytes returns the size of the entries kept in the EntrySorter
+func (es *EntrySorter) BufferedBytes() int64 {
+	return atomic.LoadInt64(&es.bufferedSize)
+}
@@ -0,0 +1,541 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSorterMemoryLimit = 64 * 1024 * 1024
+	// defaultMaxSortedRunFiles is the number of the sorted run files which
+	// triggers merging them into one file
+	defaultMaxSortedRunFiles = 16
+	sortedRunFilePattern     = "cdc-sort-"
+)
+
+// FileSorter accepts out-of-order raw kv entries and output sorted entries.
+// Once the size of the unsorted entries exceeds the memory limit, they are
+// sorted and spilled to a file under the sort dir, and all of the sorted runs
+// are merged when a resolved ts arrives. The sorted run files are merged into
+// one file once there are more than maxRunFiles of them.
+type FileSorter struct {
+	dir         string
+	memLimit    int64
+	maxRunFiles int
+
+	lock         sync.Mutex
+	unsorted     []*model.RawKVEntry
+	unsortedSize int64
+	spilled      []*sortedRun
+	// bufferedSize is the size of the entries added but not output yet
+	bufferedSize int64
+
+	resolvedCh chan uint64
+	resolvedTs uint64
+	// done is closed when the sorter stops, AddEntry never blocks after that
+	done chan struct{}
+	// errCh receives the error which stops the sorter
+	errCh chan error
+
+	output chan *model.RawKVEntry
+}
+
+// NewFileSorter creates a new FileSorter which spills the entries to the files under dir
+func NewFileSorter(dir string) *FileSorter {
+	return &FileSorter{
+		dir:         dir,
+		memLimit:    defaultSorterMemoryLimit,
+		maxRunFiles: defaultMaxSortedRunFiles,
+		resolvedCh:  make(chan uint64, 1024),
+		done:        make(chan struct{}),
+		errCh:       make(chan error, 1),
+		output:      make(chan *model.RawKVEntry, 128),
+	}
+}
+
+// Run runs FileSorter
+func (fs *FileSorter) Run(ctx context.Context) {
+	go func() {
+		// runs holds the sorted runs whose entries are greater than the last resolved ts
+		var runs []*sortedRun
+		defer func() {
+			// resolvedCh is not closed because AddEntry may be sending to it,
+			// AddEntry returns once done is closed.
+			close(fs.done)
+			close(fs.output)
+			fs.lock.Lock()
+			runs = append(runs, fs.spilled...)
+			fs.spilled = nil
+			fs.unsorted = nil
+			fs.lock.Unlock()
+			for _, run := range runs {
+				run.close()
+			}
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case resolvedTs := <-fs.resolvedCh:
+				fs.lock.Lock()
+				toSort := fs.unsorted
+				fs.unsorted = nil
+				fs.unsortedSize = 0
+				runs = append(runs, fs.spilled...)
+				fs.spilled = nil
+				fs.lock.Unlock()
+
+				sort.Slice(toSort, func(i, j int) bool {
+					return lessFunc(toSort[i], toSort[j])
+				})
+				runs = append(runs, newMemSortedRun(toSort))
+
+				var err error
+				runs, err = fs.mergeRuns(ctx, runs, resolvedTs)
+				if err != nil {
+					fs.errCh <- errors.Annotatef(err, "merge sorted runs in %s", fs.dir)
+					return
+				}
+				select {
+				case <-ctx.Done():
+				case fs.output <- &model.RawKVEntry{Ts: resolvedTs, OpType: model.OpTypeResolved}:
+				}
+			}
+		}
+	}()
+}
+
+// mergeRuns outputs the entries not greater than resolvedTs in order,
+// and returns the runs which still have entries.
+func (fs *FileSorter) mergeRuns(ctx context.Context, runs []*sortedRun, resolvedTs uint64) ([]*sortedRun, error) {
+	h := make(sortedRunHeap, 0, len(runs))
+	var remains []*sortedRun
+	for _, run := range runs {
+		if run.head == nil {
+			run.close()
+			continue
+		}
+		if run.head.Ts > resolvedTs {
+			remains = append(remains, run)
+			continue
+		}
+		h = append(h, run)
+	}
+	heap.Init(&h)
+
+	for h.Len() > 0 {
+		run := h[0]
+		select {
+		case <-ctx.Done():
+			return append(remains, h...), nil
+		case fs.output <- run.head:
+			atomic.AddInt64(&fs.bufferedSize, -entryMemSize(run.head))
+		}
+		if err := run.next(); err != nil {
+			return append(remains, h...), errors.Trace(err)
+		}
+		switch {
+		case run.head == nil:
+			run.close()
+			heap.Pop(&h)
+		case run.head.Ts > resolvedTs:
+			remains = append(remains, run)
+			heap.Pop(&h)
+		default:
+			heap.Fix(&h, 0)
+		}
+	}
+
+	// merge the remaining runs to reduce the number of runs, the runs in memory
+	// are merged into one and spilled if they are too large, and the runs in
+	// files are merged into one file if there are too many of them
+	var memRuns, fileRuns []*sortedRun
+	for _, run := range remains {
+		if run.file != nil {
+			fileRuns = append(fileRuns, run)
+		} else {
+			memRuns = append(memRuns, run)
+		}
+	}
+	if len(fileRuns) > fs.maxRunFiles {
+		run, err := mergeToFile(fs.dir, fileRuns)
+		if err != nil {
+			return append(memRuns, fileRuns...), errors.Trace(err)
+		}
+		fileRuns = []*sortedRun{run}
+	}
+	if len(memRuns) <= 1 {
+		return append(fileRuns, memRuns...), nil
+	}
+	var memEntries []*model.RawKVEntry
+	var memSize int64
+	// the runs in memory never fail to read
+	_ = mergeSortedRuns(memRuns, func(entry *model.RawKVEntry) error {
+		memEntries = append(memEntries, entry)
+		memSize += entryMemSize(entry)
+		return nil
+	})
+	if memSize >= fs.memLimit {
+		run, err := spillToFile(fs.dir, memEntries)
+		if err == nil {
+			return append(fileRuns, run), nil
+		}
+		log.Warn("spill sorted entries failed, keep them in memory", zap.String("dir", fs.dir), zap.Error(err))
+	}
+	return append(fileRuns, newMemSortedRun(memEntries)), nil
+}
+
+// mergeSortedRuns drains the runs and passes their entries to fn in order,
+// the drained runs are closed.
+func mergeSortedRuns(runs []*sortedRun, fn func(*model.RawKVEntry) error) error {
+	h := make(sortedRunHeap, 0, len(runs))
+	for _, run := range runs {
+		if run.head == nil {
+			run.close()
+			continue
+		}
+		h = append(h, run)
+	}
+	heap.Init(&h)
+	for h.Len() > 0 {
+		run := h[0]
+		if err := fn(run.head); err != nil {
+			return errors.Trace(err)
+		}
+		if err := run.next(); err != nil {
+			return errors.Trace(err)
+		}
+		if run.head == nil {
+			run.close()
+			heap.Pop(&h)
+		} else {
+			heap.Fix(&h, 0)
+		}
+	}
+	return nil
+}
+
+// AddEntry adds an RawKVEntry to the FileSorter
+func (fs *FileSorter) AddEntry(entry *model.RawKVEntry) {
+	if entry.OpType == model.OpTypeResolved {
+		atomic.StoreUint64(&fs.resolvedTs, entry.Ts)
+		select {
+		case <-fs.done:
+		case fs.resolvedCh <- entry.Ts:
+		}
+		return
+	}
+	select {
+	case <-fs.done:
+		return
+	default:
+	}
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	size := entryMemSize(entry)
+	atomic.AddInt64(&fs.bufferedSize, size)
+	fs.unsorted = append(fs.unsorted, entry)
+	fs.unsortedSize += size
+	if fs.unsortedSize < fs.memLimit {
+		return
+	}
+
+	toSpill := fs.unsorted
+	sort.Slice(toSpill, func(i, j int) bool {
+		return lessFunc(toSpill[i], toSpill[j])
+	})
+	run, err := spillToFile(fs.dir, toSpill)
+	// the entries are kept in memory if failed to spill,
+	// the sorter will try to spill again after another memLimit bytes of entries added.
+	fs.unsortedSize = 0
+	if err != nil {
+		log.Warn("spill sorted entries failed, keep them in memory", zap.String("dir", fs.dir), zap.Error(err))
+		return
+	}
+	fs.unsorted = nil
+	fs.spilled = append(fs.spilled, run)
+}
+
+// Output returns the sorted raw kv output channel
+func (fs *FileSorter) Output() <-chan *model.RawKVEntry {
+	return fs.output
+}
+
+// BufferedBytes returns the size of the entries kept in memory or spilled to files
+func (fs *FileSorter) BufferedBytes() int64 {
+	return atomic.LoadInt64(&fs.bufferedSize)
+}
+
+// Error returns the channel of the error which stops the sorter, the output
+// channel is closed after the error is sent.
+func (fs *FileSorter) Error() <-chan error {
+	return fs.errCh
+}
+
+// sortedRunFilePrefix returns the prefix of the sorted run files of this
+// process, the pid in the name tells whether the file is still in use.
+func sortedRunFilePrefix() string {
+	return sortedRunFilePattern + strconv.Itoa(os.Getpid()) + "-"
+}
+
+// CleanSortDir removes the sorted run files left in dir by the processes which
+// have exited, such as a crashed capture.
+func CleanSortDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, sortedRunFilePattern+"*"))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, file := range files {
+		fields := strings.SplitN(strings.TrimPrefix(filepath.Base(file), sortedRunFilePattern), "-", 2)
+		if len(fields) == 2 {
+			pid, err := strconv.Atoi(fields[0])
+			if err == nil && processExists(pid) {
+				continue
+			}
+		}
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return errors.Annotatef(err, "remove sorted run file %s", file)
+		}
+		log.Info("removed the stale sorted run file", zap.String("file", file))
+	}
+	return nil
+}
+
+func processExists(pid int) bool {
+	if pid == os.Getpid() {
+		return true
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
+
+func entryMemSize(entry *model.RawKVEntry) int64 {
+	return int64(sizeOfVal) + int64(len(entry.Key)+len(entry.Value))
+}
+
+// sortedRun is a sequence of sorted entries which is kept in memory or in a file
+type sortedRun struct {
+	// head is the next entry of the run, it's nil if the run is drained
+	head *model.RawKVEntry
+
+	entries []*model.RawKVEntry
+
+	file   *os.File
+	reader *bufio.Reader
+}
+
+func newMemSortedRun(entries []*model.RawKVEntry) *sortedRun {
+	run := &sortedRun{entries: entries}
+	// next never fails for the run in memory
+	_ = run.next()
+	return run
+}
+
+func spillToFile(dir string, entries []*model.RawKVEntry) (*sortedRun, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Trace(err)
+	}
+	file, err := ioutil.TempFile(dir, sortedRunFilePrefix())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	run := &sortedRun{file: file}
+	writer := bufio.NewWriter(file)
+	for _, entry := range entries {
+		if err := writeEntry(writer, entry); err != nil {
+			run.close()
+			return nil, errors.Trace(err)
+		}
+	}
+	if err := run.rewind(writer); err != nil {
+		run.close()
+		return nil, errors.Trace(err)
+	}
+	return run, nil
+}
+
+// mergeToFile merges the sorted runs into a new sorted run file
+func mergeToFile(dir string, runs []*sortedRun) (*sortedRun, error) {
+	file, err := ioutil.TempFile(dir, sortedRunFilePrefix())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	run := &sortedRun{file: file}
+	writer := bufio.NewWriter(file)
+	err = mergeSortedRuns(runs, func(entry *model.RawKVEntry) error {
+		return writeEntry(writer, entry)
+	})
+	if err != nil {
+		run.close()
+		return nil, errors.Trace(err)
+	}
+	if err := run.rewind(writer); err != nil {
+		run.close()
+		return nil, errors.Trace(err)
+	}
+	return run, nil
+}
+
+// rewind flushes the writer of the run file, and reads the run from the beginning
+func (r *sortedRun) rewind(writer *bufio.Writer) error {
+	if err := writer.Flush(); err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+		return errors.Trace(err)
+	}
+	r.reader = bufio.NewReader(r.file)
+	return errors.Trace(r.next())
+}
+
+func (r *sortedRun) next() error {
+	if r.file == nil {
+		if len(r.entries) == 0 {
+			r.head = nil
+			return nil
+		}
+		r.head = r.entries[0]
+		r.entries = r.entries[1:]
+		return nil
+	}
+	entry, err := readEntry(r.reader)
+	if err != nil {
+		if err == io.EOF {
+			r.head = nil
+			return nil
+		}
+		return errors.Trace(err)
+	}
+	r.head = entry
+	return nil
+}
+
+func (r *sortedRun) close() {
+	r.head = nil
+	r.entries = nil
+	if r.file == nil {
+		return
+	}
+	name := r.file.Name()
+	if err := r.file.Close(); err != nil {
+		log.Warn("close sorted run file failed", zap.String("file", name), zap.Error(err))
+	}
+	if err := os.Remove(name); err != nil {
+		log.Warn("remove sorted run file failed", zap.String("file", name), zap.Error(err))
+	}
+	r.file = nil
+	r.reader = nil
+}
+
+type sortedRunHeap []*sortedRun
+
+func (h sortedRunHeap) Len() int           { return len(h) }
+func (h sortedRunHeap) Less(i, j int) bool { return lessFunc(h[i].head, h[j].head) }
+func (h sortedRunHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *sortedRunHeap) Push(x interface{}) {
+	*h = append(*h, x.(*sortedRun))
+}
+
+func (h *sortedRunHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// writeEntry encodes the entry as:
+// opType(uint8) | startTs(uint64) | ts(uint64) | keyLen(uint32) | key | valueLen(uint32) | value
+func writeEntry(w io.Writer, entry *model.RawKVEntry) error {
+	var header [1 + 8 + 8 + 4]byte
+	header[0] = byte(entry.OpType)
+	binary.BigEndian.PutUint64(header[1:], entry.StartTs)
+	binary.BigEndian.PutUint64(header[9:], entry.Ts)
+	binary.BigEndian.PutUint32(header[17:], uint32(len(entry.Key)))
+	if _, err := w.Write(header[:]); err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := w.Write(entry.Key); err != nil {
+		return errors.Trace(err)
+	}
+	var valueLen [4]byte
+	binary.BigEndian.PutUint32(valueLen[:], uint32(len(entry.Value)))
+	if _, err := w.Write(valueLen[:]); err != nil {
+		return errors.Trace(err)
+	}
+	_, err := w.Write(entry.Value)
+	return errors.Trace(err)
+}
+
+func readEntry(r io.Reader) (*model.RawKVEntry, error) {
+	var header [1 + 8 + 8 + 4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		// io.EOF means there is no more entry
+		return nil, err
+	}
+	entry := &model.RawKVEntry{
+		OpType:  model.OpType(header[0]),
+		StartTs: binary.BigEndian.Uint64(header[1:]),
+		Ts:      binary.BigEndian.Uint64(header[9:]),
+	}
+	var err error
+	entry.Key, err = readBytes(r, binary.BigEndian.Uint32(header[17:]))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var valueLen [4]byte
+	if _, err := io.ReadFull(r, valueLen[:]); err != nil {
+		return nil, errors.Trace(err)
+	}
+	entry.Value, err = readBytes(r, binary.BigEndian.Uint32(valueLen[:]))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return entry, nil
+}
+
+func readBytes(r io.Reader, n uint32) ([]byte, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return b, nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+type fileSorterSuite struct{}
+
+var _ = check.Suite(&fileSorterSuite{})
+
+func (s *fileSorterSuite) TestEntryCodec(c *check.C) {
+	entries := []*model.RawKVEntry{
+		{OpType: model.OpTypePut, Key: []byte("k1"), Value: []byte("v1"), StartTs: 1, Ts: 2},
+		{OpType: model.OpTypeDelete, Key: []byte("k2"), StartTs: 3, Ts: 4},
+	}
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		c.Assert(writeEntry(&buf, entry), check.IsNil)
+	}
+	for _, entry := range entries {
+		decoded, err := readEntry(&buf)
+		c.Assert(err, check.IsNil)
+		c.Assert(decoded, check.DeepEquals, entry)
+	}
+	_, err := readEntry(&buf)
+	c.Assert(err, check.Equals, io.EOF)
+}
+
+func (s *fileSorterSuite) TestFileSorterRandomly(c *check.C) {
+	dir, err := ioutil.TempDir("", "file-sorter-test")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	fs := NewFileSorter(dir)
+	// spill the entries to files frequently
+	fs.memLimit = 1024
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fs.Run(ctx)
+
+	maxTs := uint64(100000)
+	go func() {
+		for resolvedTs := uint64(1); resolvedTs <= maxTs; resolvedTs += 400 {
+			var opType model.OpType
+			if rand.Intn(2) == 0 {
+				opType = model.OpTypePut
+			} else {
+				opType = model.OpTypeDelete
+			}
+			for i := 0; i < 1000; i++ {
+				entry := &model.RawKVEntry{
+					Ts:     uint64(int64(resolvedTs) + rand.Int63n(int64(maxTs-resolvedTs))),
+					OpType: opType,
+				}
+				fs.AddEntry(entry)
+			}
+			fs.AddEntry(&model.RawKVEntry{Ts: resolvedTs, OpType: model.OpTypeResolved})
+		}
+		fs.AddEntry(&model.RawKVEntry{Ts: maxTs, OpType: model.OpTypeResolved})
+	}()
+	var lastTs uint64
+	var resolvedTs uint64
+	lastOpType := model.OpTypePut
+	for entry := range fs.Output() {
+		c.Assert(entry.Ts, check.GreaterEqual, lastTs)
+		c.Assert(entry.Ts, check.Greater, resolvedTs)
+		if lastOpType == model.OpTypePut && entry.OpType == model.OpTypeDelete {
+			c.Assert(entry.Ts, check.Greater, lastTs)
+		}
+		lastTs = entry.Ts
+		lastOpType = entry.OpType
+		if entry.OpType == model.OpTypeResolved {
+			resolvedTs = entry.Ts
+		}
+		if resolvedTs == maxTs {
+			break
+		}
+	}
+}
+
+func (s *fileSorterSuite) TestBufferedBytes(c *check.C) {
+	fs := NewFileSorter(c.MkDir())
+	fs.memLimit = 1024
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fs.Run(ctx)
+
+	var size int64
+	for i := 0; i < 100; i++ {
+		entry := &model.RawKVEntry{Ts: uint64(100 + i), OpType: model.OpTypePut, Key: []byte("key"), Value: []byte("value")}
+		size += entryMemSize(entry)
+		fs.AddEntry(entry)
+	}
+	// the spilled entries are counted as well
+	c.Assert(fs.BufferedBytes(), check.Equals, size)
+
+	fs.AddEntry(&model.RawKVEntry{Ts: 50, OpType: model.OpTypeResolved})
+	entry := <-fs.Output()
+	c.Assert(entry.OpType, check.Equals, model.OpTypeResolved)
+	c.Assert(fs.BufferedBytes(), check.Equals, size)
+
+	fs.AddEntry(&model.RawKVEntry{Ts: 200, OpType: model.OpTypeResolved})
+	for entry := range fs.Output() {
+		if entry.OpType == model.OpTypeResolved {
+			break
+		}
+	}
+	c.Assert(fs.BufferedBytes(), check.Equals, int64(0))
+}
+
+func (s *fileSorterSuite) TestMergeRunFiles(c *check.C) {
+	dir := c.MkDir()
+	fs := NewFileSorter(dir)
+	fs.memLimit = 1024
+	fs.maxRunFiles = 2
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fs.Run(ctx)
+
+	for i := 99; i >= 0; i-- {
+		fs.AddEntry(&model.RawKVEntry{Ts: uint64(100 + i), OpType: model.OpTypePut, Key: []byte("key"), Value: []byte("value")})
+	}
+	files, err := filepath.Glob(filepath.Join(dir, sortedRunFilePrefix()+"*"))
+	c.Assert(err, check.IsNil)
+	c.Assert(len(files), check.Greater, fs.maxRunFiles)
+
+	fs.AddEntry(&model.RawKVEntry{Ts: 50, OpType: model.OpTypeResolved})
+	entry := <-fs.Output()
+	c.Assert(entry.OpType, check.Equals, model.OpTypeResolved)
+	files, err = filepath.Glob(filepath.Join(dir, sortedRunFilePrefix()+"*"))
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 1)
+
+	fs.AddEntry(&model.RawKVEntry{Ts: 200, OpType: model.OpTypeResolved})
+	var lastTs uint64
+	count := 0
+	for entry := range fs.Output() {
+		if entry.OpType == model.OpTypeResolved {
+			break
+		}
+		c.Assert(entry.Ts, check.Greater, lastTs)
+		lastTs = entry.Ts
+		count++
+	}
+	c.Assert(count, check.Equals, 100)
+}
+
+func (s *fileSorterSuite) TestAddEntryAfterStop(c *check.C) {
+	fs := NewFileSorter(c.MkDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	fs.Run(ctx)
+	cancel()
+	for range fs.Output() {
+	}
+	// neither blocks nor panics after the sorter stops
+	for i := 0; i < 2048; i++ {
+		fs.AddEntry(&model.RawKVEntry{Ts: uint64(i), OpType: model.OpTypeResolved})
+		fs.AddEntry(&model.RawKVEntry{Ts: uint64(i), OpType: model.OpTypePut})
+	}
+}
+
+func (s *fileSorterSuite) TestMergeError(c *check.C) {
+	dir := c.MkDir()
+	fs := NewFileSorter(dir)
+	fs.memLimit = 20000
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fs.Run(ctx)
+
+	// the entries are larger than the buffer of the reader, so the second one
+	// is read from the file when merging
+	for i := 1; i <= 3; i++ {
+		fs.AddEntry(&model.RawKVEntry{Ts: uint64(i), OpType: model.OpTypePut, Value: make([]byte, 8192)})
+	}
+	files, err := filepath.Glob(filepath.Join(dir, sortedRunFilePrefix()+"*"))
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 1)
+	c.Assert(os.Truncate(files[0], 10000), check.IsNil)
+	fs.AddEntry(&model.RawKVEntry{Ts: 3, OpType: model.OpTypeResolved})
+
+	select {
+	case err := <-fs.Error():
+		c.Assert(err, check.ErrorMatches, ".*merge sorted runs.*")
+	case <-time.After(10 * time.Second):
+		c.Fatal("the merge error is not reported")
+	}
+	for range fs.Output() {
+	}
+	// the sorted run file is removed after the sorter stops
+	files, err = filepath.Glob(filepath.Join(dir, sortedRunFilePrefix()+"*"))
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 0)
+}
+
+func (s *fileSorterSuite) TestCleanSortDir(c *check.C) {
+	dir := c.MkDir()
+	own := filepath.Join(dir, sortedRunFilePrefix()+"1")
+	// no process has such a large pid
+	stale := filepath.Join(dir, sortedRunFilePattern+"999999999-1")
+	legacy := filepath.Join(dir, sortedRunFilePattern+"123456")
+	other := filepath.Join(dir, "other-file")
+	for _, file := range []string{own, stale, legacy, other} {
+		c.Assert(ioutil.WriteFile(file, nil, 0644), check.IsNil)
+	}
+	c.Assert(CleanSortDir(dir), check.IsNil)
+	for _, file := range []string{own, other} {
+		_, err := os.Stat(file)
+		c.Assert(err, check.IsNil)
+	}
+	for _, file := range []string{stale, legacy} {
+		_, err := os.Stat(file)
+		c.Assert(os.IsNotExist(err), check.IsTrue)
+	}
+}
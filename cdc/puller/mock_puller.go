@@ -169,6 +169,14 @@ func (p *mockPuller) Output() ChanBuffer {
 	panic("unreachable")
 }
 
+func (p *mockPuller) RegionCount() int {
+	return 0
+}
+
+func (p *mockPuller) SorterBufferedBytes() int64 {
+	return 0
+}
+
 // NewMockPullerManager creates and sets up a mock puller manager
 func NewMockPullerManager(c *check.C, newRowFormat bool) *MockPullerManager {
 	m := &MockPullerManager{
@@ -291,18 +299,20 @@ func prewrite2RawKV(req *kvrpcpb.PrewriteRequest, commitTs uint64) []*model.RawK
 		switch mut.Op {
 		case kvrpcpb.Op_Put, kvrpcpb.Op_Insert:
 			rawKV := &model.RawKVEntry{
-				Ts:     commitTs,
-				Key:    mut.Key,
-				Value:  mut.Value,
-				OpType: model.OpTypePut,
+				StartTs: req.StartVersion,
+				Ts:      commitTs,
+				Key:     mut.Key,
+				Value:   mut.Value,
+				OpType:  model.OpTypePut,
 			}
 			putEntries = append(putEntries, rawKV)
 		case kvrpcpb.Op_Del:
 			rawKV := &model.RawKVEntry{
-				Ts:     commitTs,
-				Key:    mut.Key,
-				Value:  mut.Value,
-				OpType: model.OpTypeResolved,
+				StartTs: req.StartVersion,
+				Ts:      commitTs,
+				Key:     mut.Key,
+				Value:   mut.Value,
+				OpType:  model.OpTypeResolved,
 			}
 			deleteEntries = append(deleteEntries, rawKV)
 		default:
@@ -15,14 +15,16 @@ package puller
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
-	pd "github.com/pingcap/pd/client"
+	pd "github.com/pingcap/pd/v4/client"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -39,6 +41,10 @@ type Puller interface {
 	GetResolvedTs() uint64
 	Output() ChanBuffer
 	SortedOutput(ctx context.Context) <-chan *model.RawKVEntry
+	// RegionCount returns the number of the regions the puller is fed by
+	RegionCount() int
+	// SorterBufferedBytes returns the size of the entries buffered in the sorter
+	SorterBufferedBytes() int64
 }
 
 // resolveTsTracker checks resolved event of spans and moves the global resolved ts ahead
@@ -49,6 +55,7 @@ type resolveTsTracker interface {
 
 type pullerImpl struct {
 	pdCli        pd.Client
+	credential   *security.Credential
 	checkpointTs uint64
 	spans        []util.Span
 	buffer       *memBuffer
@@ -57,6 +64,17 @@ type pullerImpl struct {
 	resolvedTs   uint64
 	// needEncode represents whether we need to encode a key when checking it is in span
 	needEncode bool
+	// sortDir is the dir to spill the entries when sorting, the entries are
+	// sorted in memory if it is empty
+	sortDir string
+	// sorterErrCh receives the error which stops the sorter, it is returned by Run
+	sorterErrCh chan error
+
+	mu struct {
+		sync.Mutex
+		cli    *kv.CDCClient
+		sorter Sorter
+	}
 }
 
 // CancellablePuller is a puller that can be stopped with the Cancel function
@@ -70,19 +88,24 @@ type CancellablePuller struct {
 // and put into buf.
 func NewPuller(
 	pdCli pd.Client,
+	credential *security.Credential,
 	checkpointTs uint64,
 	spans []util.Span,
 	needEncode bool,
 	limitter *BlurResourceLimitter,
+	sortDir string,
 ) *pullerImpl {
 	p := &pullerImpl{
 		pdCli:        pdCli,
+		credential:   credential,
 		checkpointTs: checkpointTs,
 		spans:        spans,
 		buffer:       makeMemBuffer(limitter),
 		chanBuffer:   makeChanBuffer(),
 		tsTracker:    makeSpanFrontier(spans...),
 		needEncode:   needEncode,
+		sortDir:      sortDir,
+		sorterErrCh:  make(chan error, 1),
 	}
 
 	return p
@@ -95,7 +118,27 @@ func (p *pullerImpl) Output() ChanBuffer {
 func (p *pullerImpl) SortedOutput(ctx context.Context) <-chan *model.RawKVEntry {
 	captureID := util.CaptureIDFromCtx(ctx)
 	changefeedID := util.ChangefeedIDFromCtx(ctx)
-	sorter := NewEntrySorter()
+	var sorter Sorter
+	if p.sortDir != "" {
+		fileSorter := NewFileSorter(p.sortDir)
+		go func() {
+			select {
+			case <-ctx.Done():
+			case err := <-fileSorter.Error():
+				// only the first error is returned by Run
+				select {
+				case p.sorterErrCh <- err:
+				default:
+				}
+			}
+		}()
+		sorter = fileSorter
+	} else {
+		sorter = NewEntrySorter()
+	}
+	p.mu.Lock()
+	p.mu.sorter = sorter
+	p.mu.Unlock()
 	go func() {
 		sorter.Run(ctx)
 		for {
@@ -130,12 +173,15 @@ func (p *pullerImpl) SortedOutput(ctx context.Context) <-chan *model.RawKVEntry
 
 // Run the puller, continually fetch event from TiKV and add event into buffer
 func (p *pullerImpl) Run(ctx context.Context) error {
-	cli, err := kv.NewCDCClient(p.pdCli)
+	cli, err := kv.NewCDCClient(p.pdCli, p.credential)
 	if err != nil {
 		return errors.Annotate(err, "create cdc client failed")
 	}
 
 	defer cli.Close()
+	p.mu.Lock()
+	p.mu.cli = cli
+	p.mu.Unlock()
 
 	g, ctx := errgroup.WithContext(ctx)
 
@@ -197,6 +243,15 @@ func (p *pullerImpl) Run(ctx context.Context) error {
 		}
 	})
 
+	g.Go(func() error {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-p.sorterErrCh:
+			return errors.Trace(err)
+		}
+	})
+
 	g.Go(func() error {
 		for {
 			e, err := p.buffer.Get(ctx)
@@ -218,6 +273,24 @@ func (p *pullerImpl) GetResolvedTs() uint64 {
 	return p.tsTracker.Frontier()
 }
 
+func (p *pullerImpl) RegionCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.mu.cli == nil {
+		return 0
+	}
+	return p.mu.cli.RegionCount()
+}
+
+func (p *pullerImpl) SorterBufferedBytes() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.mu.sorter == nil {
+		return 0
+	}
+	return p.mu.sorter.BufferedBytes()
+}
+
 // TODO remove this function
 // collectRawTxns collects KV events from the inputFn,
 // groups them by transactions and sends them to the outputFn.
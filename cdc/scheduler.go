@@ -26,6 +26,7 @@ import (
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/mvcc"
@@ -48,15 +49,19 @@ type ChangeFeedWatcher struct {
 	lock        sync.RWMutex
 	captureID   string
 	pdEndpoints []string
+	credential  *security.Credential
 	etcdCli     kv.CDCEtcdClient
 	infos       map[string]model.ChangeFeedInfo
 }
 
 // NewChangeFeedWatcher creates a new changefeed watcher
-func NewChangeFeedWatcher(captureID string, pdEndpoints []string, cli kv.CDCEtcdClient) *ChangeFeedWatcher {
+func NewChangeFeedWatcher(
+	captureID string, pdEndpoints []string, credential *security.Credential, cli kv.CDCEtcdClient,
+) *ChangeFeedWatcher {
 	w := &ChangeFeedWatcher{
 		captureID:   captureID,
 		pdEndpoints: pdEndpoints,
+		credential:  credential,
 		etcdCli:     cli,
 		infos:       make(map[string]model.ChangeFeedInfo),
 	}
@@ -115,7 +120,7 @@ func (w *ChangeFeedWatcher) Watch(ctx context.Context, cb processorCallback) err
 			return errors.Trace(err)
 		}
 		if needRunWatcher {
-			_, err := runProcessorWatcher(ctx, changefeedID, w.captureID, w.pdEndpoints, w.etcdCli, info, errCh, cb)
+			_, err := runProcessorWatcher(ctx, changefeedID, w.captureID, w.pdEndpoints, w.credential, w.etcdCli, info, errCh, cb)
 			if err != nil {
 				return errors.Trace(err)
 			}
@@ -149,7 +154,7 @@ func (w *ChangeFeedWatcher) Watch(ctx context.Context, cb processorCallback) err
 						return errors.Trace(err)
 					}
 					if needRunWatcher {
-						_, err := runProcessorWatcher(ctx, changefeedID, w.captureID, w.pdEndpoints, w.etcdCli, info, errCh, cb)
+						_, err := runProcessorWatcher(ctx, changefeedID, w.captureID, w.pdEndpoints, w.credential, w.etcdCli, info, errCh, cb)
 						if err != nil {
 							return errors.Trace(err)
 						}
@@ -168,6 +173,7 @@ func (w *ChangeFeedWatcher) Watch(ctx context.Context, cb processorCallback) err
 // ProcessorWatcher is a processor watcher
 type ProcessorWatcher struct {
 	pdEndpoints  []string
+	credential   *security.Credential
 	changefeedID string
 	captureID    string
 	etcdCli      kv.CDCEtcdClient
@@ -182,6 +188,7 @@ func NewProcessorWatcher(
 	changefeedID string,
 	captureID string,
 	pdEndpoints []string,
+	credential *security.Credential,
 	cli kv.CDCEtcdClient,
 	info model.ChangeFeedInfo,
 	checkpointTs uint64,
@@ -190,6 +197,7 @@ func NewProcessorWatcher(
 		changefeedID: changefeedID,
 		captureID:    captureID,
 		pdEndpoints:  pdEndpoints,
+		credential:   credential,
 		etcdCli:      cli,
 		info:         info,
 		checkpointTs: checkpointTs,
@@ -265,7 +273,7 @@ func (w *ProcessorWatcher) Watch(ctx context.Context, errCh chan<- error, cb pro
 
 	cctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	err = runProcessor(cctx, w.pdEndpoints, w.info, w.changefeedID, w.captureID, w.checkpointTs, cb)
+	err = runProcessor(cctx, w.pdEndpoints, w.credential, w.info, w.changefeedID, w.captureID, w.checkpointTs, cb)
 	if err != nil {
 		errCh <- err
 		return
@@ -306,6 +314,7 @@ func realRunProcessorWatcher(
 	changefeedID string,
 	captureID string,
 	pdEndpoints []string,
+	credential *security.Credential,
 	etcdCli kv.CDCEtcdClient,
 	info model.ChangeFeedInfo,
 	errCh chan error,
@@ -316,7 +325,7 @@ func realRunProcessorWatcher(
 		return nil, errors.Trace(err)
 	}
 	checkpointTs := info.GetCheckpointTs(status)
-	sw := NewProcessorWatcher(changefeedID, captureID, pdEndpoints, etcdCli, info, checkpointTs)
+	sw := NewProcessorWatcher(changefeedID, captureID, pdEndpoints, credential, etcdCli, info, checkpointTs)
 	ctx = util.PutChangefeedIDInCtx(ctx, changefeedID)
 	sw.wg.Add(1)
 	go sw.Watch(ctx, errCh, cb)
@@ -327,6 +336,7 @@ func realRunProcessorWatcher(
 func realRunProcessor(
 	ctx context.Context,
 	pdEndpoints []string,
+	credential *security.Credential,
 	info model.ChangeFeedInfo,
 	changefeedID string,
 	captureID string,
@@ -343,7 +353,7 @@ func realRunProcessor(
 	if err != nil {
 		return errors.Trace(err)
 	}
-	sink, err := sink.NewSink(info.SinkURI, filter, opts)
+	sink, err := sink.NewSink(info.SinkURI, filter, info.GetConfig(), opts)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -354,7 +364,7 @@ func realRunProcessor(
 			errCh <- err
 		}
 	}()
-	processor, err := NewProcessor(ctx, pdEndpoints, info, sink, changefeedID, captureID, checkpointTs)
+	processor, err := NewProcessor(ctx, pdEndpoints, credential, info, sink, changefeedID, captureID, checkpointTs)
 	if err != nil {
 		cancel()
 		return err
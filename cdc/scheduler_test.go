@@ -26,6 +26,7 @@ import (
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/pkg/etcd"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/embed"
@@ -71,6 +72,7 @@ func (s *schedulerSuite) TearDownTest(c *check.C) {
 func mockRunProcessor(
 	ctx context.Context,
 	pdEndpoints []string,
+	credential *security.Credential,
 	detail model.ChangeFeedInfo,
 	changefeedID string,
 	captureID string,
@@ -84,6 +86,7 @@ func mockRunProcessor(
 func mockRunProcessorError(
 	ctx context.Context,
 	pdEndpoints []string,
+	credential *security.Credential,
 	detail model.ChangeFeedInfo,
 	changefeedID string,
 	captureID string,
@@ -98,6 +101,7 @@ func mockRunProcessorWatcher(
 	changefeedID string,
 	captureID string,
 	pdEndpoints []string,
+	credential *security.Credential,
 	etcdCli kv.CDCEtcdClient,
 	detail model.ChangeFeedInfo,
 	errCh chan error,
@@ -138,7 +142,7 @@ func (s *schedulerSuite) TestProcessorWatcher(c *check.C) {
 
 	// processor exists before watch starts
 	errCh := make(chan error, 1)
-	sw, err := runProcessorWatcher(context.Background(), changefeedID, captureID, pdEndpoints, cli, detail, errCh, nil)
+	sw, err := runProcessorWatcher(context.Background(), changefeedID, captureID, pdEndpoints, &security.Credential{}, cli, detail, errCh, nil)
 	c.Assert(err, check.IsNil)
 	c.Assert(util.WaitSomething(10, time.Millisecond*50, func() bool {
 		return atomic.LoadInt32(&runProcessorCount) == 1
@@ -164,7 +168,7 @@ func (s *schedulerSuite) TestProcessorWatcher(c *check.C) {
 
 	// check watcher can find new processor in watch loop
 	errCh2 := make(chan error, 1)
-	_, err = runProcessorWatcher(context.Background(), changefeedID, captureID, pdEndpoints, cli, detail, errCh2, nil)
+	_, err = runProcessorWatcher(context.Background(), changefeedID, captureID, pdEndpoints, &security.Credential{}, cli, detail, errCh2, nil)
 	c.Assert(err, check.IsNil)
 	_, err = cli.Client.Put(context.Background(), key, "{}")
 	c.Assert(err, check.IsNil)
@@ -202,7 +206,7 @@ func (s *schedulerSuite) TestProcessorWatcherError(c *check.C) {
 	c.Assert(err, check.IsNil)
 
 	errCh := make(chan error, 1)
-	sw, err := runProcessorWatcher(context.Background(), changefeedID, captureID, pdEndpoints, cli, detail, errCh, nil)
+	sw, err := runProcessorWatcher(context.Background(), changefeedID, captureID, pdEndpoints, &security.Credential{}, cli, detail, errCh, nil)
 	c.Assert(err, check.IsNil)
 	sw.wg.Add(1)
 	go sw.Watch(context.Background(), errCh, nil)
@@ -247,7 +251,7 @@ func (s *schedulerSuite) TestChangeFeedWatcher(c *check.C) {
 	cli := kv.NewCDCEtcdClient(etcdCli)
 
 	ctx, cancel := context.WithCancel(context.Background())
-	w := NewChangeFeedWatcher(captureID, pdEndpoints, cli)
+	w := NewChangeFeedWatcher(captureID, pdEndpoints, &security.Credential{}, cli)
 
 	var wg sync.WaitGroup
 	wg.Add(1)
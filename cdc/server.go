@@ -15,11 +15,14 @@ package cdc
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	"go.uber.org/zap"
 )
@@ -28,12 +31,20 @@ type options struct {
 	pdEndpoints string
 	statusHost  string
 	statusPort  int
+	credential  *security.Credential
+	// advertiseAddr is the status address for the clients to access,
+	// the bind status address is used if it's empty
+	advertiseAddr string
+	// gcTTL is the TTL of the service GC safepoint in seconds
+	gcTTL int64
 }
 
 var defaultServerOptions = options{
 	pdEndpoints: "http://127.0.0.1:2379",
 	statusHost:  "127.0.0.1",
 	statusPort:  defaultStatusPort,
+	credential:  &security.Credential{},
+	gcTTL:       DefaultGCTTL,
 }
 
 // PDEndpoints returns a ServerOption that sets the endpoints of PD for the server.
@@ -57,6 +68,27 @@ func StatusPort(p int) ServerOption {
 	}
 }
 
+// AdvertiseAddr returns a ServerOption that sets the advertise status address of the server
+func AdvertiseAddr(s string) ServerOption {
+	return func(o *options) {
+		o.advertiseAddr = s
+	}
+}
+
+// GCTTL returns a ServerOption that sets the TTL of the service GC safepoint in seconds
+func GCTTL(t int64) ServerOption {
+	return func(o *options) {
+		o.gcTTL = t
+	}
+}
+
+// Credential returns a ServerOption that sets the TLS credential of the server
+func Credential(c *security.Credential) ServerOption {
+	return func(o *options) {
+		o.credential = c
+	}
+}
+
 // A ServerOption sets options such as the addr of PD.
 type ServerOption func(*options)
 
@@ -76,9 +108,14 @@ func NewServer(opt ...ServerOption) (*Server, error) {
 	log.Info("creating CDC server",
 		zap.String("pd-addr", opts.pdEndpoints),
 		zap.String("status-host", opts.statusHost),
-		zap.Int("status-port", opts.statusPort))
+		zap.Int("status-port", opts.statusPort),
+		zap.Bool("tls-enabled", opts.credential.IsTLSEnabled()))
 
-	capture, err := NewCapture(strings.Split(opts.pdEndpoints, ","))
+	advertiseAddr := opts.advertiseAddr
+	if advertiseAddr == "" {
+		advertiseAddr = fmt.Sprintf("%s:%d", opts.statusHost, opts.statusPort)
+	}
+	capture, err := NewCapture(strings.Split(opts.pdEndpoints, ","), opts.credential, advertiseAddr, opts.gcTTL)
 	if err != nil {
 		return nil, err
 	}
@@ -92,11 +129,19 @@ func NewServer(opt ...ServerOption) (*Server, error) {
 
 // Run runs the server.
 func (s *Server) Run(ctx context.Context) error {
-	s.startStatusHTTP()
+	err := s.startStatusHTTP()
+	if err != nil {
+		return errors.Trace(err)
+	}
 	ctx = util.PutCaptureIDInCtx(ctx, s.capture.info.ID)
 	return s.capture.Start(ctx)
 }
 
+// Drain moves the tables of the capture to the other captures before the server exits
+func (s *Server) Drain(ctx context.Context) error {
+	return errors.Trace(s.capture.Drain(ctx))
+}
+
 // Close closes the server.
 func (s *Server) Close() {
 	if s.statusServer != nil {
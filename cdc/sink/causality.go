@@ -0,0 +1,98 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+)
+
+// causality provides a simple mechanism to detect the conflicts between rows,
+// two rows conflict with each other if they share a unique key value.
+type causality struct {
+	relations map[string]int
+}
+
+func newCausality() *causality {
+	return &causality{
+		relations: make(map[string]int),
+	}
+}
+
+// add records the keys are dispatched to the worker idx
+func (c *causality) add(keys []string, idx int) {
+	for _, key := range keys {
+		c.relations[key] = idx
+	}
+}
+
+func (c *causality) reset() {
+	c.relations = make(map[string]int)
+}
+
+// detectConflict returns whether the keys conflict with the keys added before.
+// If all of the conflicting keys are dispatched to one worker, the index of
+// the worker is returned, otherwise -1 is returned.
+func (c *causality) detectConflict(keys []string) (bool, int) {
+	firstIdx := -1
+	for _, key := range keys {
+		if idx, ok := c.relations[key]; ok {
+			if firstIdx == -1 {
+				firstIdx = idx
+			} else if firstIdx != idx {
+				return true, -1
+			}
+		}
+	}
+
+	return firstIdx != -1, firstIdx
+}
+
+// genKeys generates the keys of the unique key values of the row,
+// the keys of a row in a table without unique key is the table name
+func genKeys(row *model.RowChangedEvent) []string {
+	table := util.QuoteSchema(row.Schema, row.Table)
+	keys := genColumnKeys(table, row.PreColumns, nil)
+	keys = genColumnKeys(table, row.Columns, keys)
+	if len(keys) == 0 {
+		return []string{table}
+	}
+	return keys
+}
+
+func genColumnKeys(table string, cols map[string]*model.Column, keys []string) []string {
+	colNames := make([]string, 0, len(cols))
+	for colName, col := range cols {
+		if col.WhereHandle {
+			colNames = append(colNames, colName)
+		}
+	}
+	sort.Strings(colNames)
+	for _, colName := range colNames {
+		keys = append(keys, fmt.Sprintf("%s.%s=%v", table, util.QuoteName(colName), cols[colName].Value))
+	}
+	return keys
+}
+
+// genTxnKeys generates the keys of all the rows in the transaction
+func genTxnKeys(txn []*model.RowChangedEvent) []string {
+	var keys []string
+	for _, row := range txn {
+		keys = append(keys, genKeys(row)...)
+	}
+	return keys
+}
@@ -0,0 +1,195 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+type testCausalitySuite struct{}
+
+var _ = check.Suite(&testCausalitySuite{})
+
+func (s *testCausalitySuite) TestCausality(c *check.C) {
+	rows := [][]string{
+		{"a"},
+		{"b"},
+		{"c"},
+	}
+	ca := newCausality()
+	for i, row := range rows {
+		conflict, idx := ca.detectConflict(row)
+		c.Assert(conflict, check.IsFalse)
+		c.Assert(idx, check.Equals, -1)
+		ca.add(row, i)
+	}
+
+	testCases := []struct {
+		keys     []string
+		conflict bool
+		idx      int
+	}{
+		{keys: []string{"a", "d"}, conflict: true, idx: 0},
+		{keys: []string{"b", "c"}, conflict: true, idx: -1},
+		{keys: []string{"d", "e"}, conflict: false, idx: -1},
+		{keys: []string{"c"}, conflict: true, idx: 2},
+	}
+	for _, tc := range testCases {
+		conflict, idx := ca.detectConflict(tc.keys)
+		c.Assert(conflict, check.Equals, tc.conflict)
+		c.Assert(idx, check.Equals, tc.idx)
+	}
+
+	ca.reset()
+	conflict, _ := ca.detectConflict([]string{"a"})
+	c.Assert(conflict, check.IsFalse)
+}
+
+func (s *testCausalitySuite) TestGenKeys(c *check.C) {
+	row := &model.RowChangedEvent{
+		Schema: "test",
+		Table:  "t",
+		Type:   model.UpdateDMLType,
+		PreColumns: map[string]*model.Column{
+			"id":   {Type: mysql.TypeLong, WhereHandle: true, Value: 1},
+			"name": {Type: mysql.TypeVarchar, Value: "a"},
+		},
+		Columns: map[string]*model.Column{
+			"id":   {Type: mysql.TypeLong, WhereHandle: true, Value: 2},
+			"name": {Type: mysql.TypeVarchar, Value: "b"},
+		},
+	}
+	c.Assert(genKeys(row), check.DeepEquals, []string{"`test`.`t`.`id`=1", "`test`.`t`.`id`=2"})
+
+	row = &model.RowChangedEvent{
+		Schema: "test",
+		Table:  "t",
+		Type:   model.InsertDMLType,
+		Columns: map[string]*model.Column{
+			"name": {Type: mysql.TypeVarchar, Value: "b"},
+		},
+	}
+	c.Assert(genKeys(row), check.DeepEquals, []string{"`test`.`t`"})
+}
+
+// execRecorder records the order of the rows applied to the mock DB
+type execRecorder struct {
+	sync.Mutex
+	applied []string
+}
+
+// recordedArg matches the argument of a row, and records the row when it is applied
+type recordedArg struct {
+	value    int64
+	row      string
+	once     *sync.Once
+	recorder *execRecorder
+}
+
+func (a recordedArg) Match(v driver.Value) bool {
+	if v != a.value {
+		return false
+	}
+	// the argument is matched more than once for each exec
+	a.once.Do(func() {
+		a.recorder.Lock()
+		defer a.recorder.Unlock()
+		a.recorder.applied = append(a.recorder.applied, a.row)
+	})
+	return true
+}
+
+func (s *testCausalitySuite) TestCausalityExec(c *check.C) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	defer db.Close()
+	// the transactions dispatched to different workers are applied concurrently
+	mock.MatchExpectationsInOrder(false)
+
+	// the rows without unique key conflict with the rows of the same table
+	txnTables := []struct {
+		ts     uint64
+		tables []string
+	}{
+		{ts: 1, tables: []string{"t1"}},
+		{ts: 2, tables: []string{"t2"}},
+		// conflicts with the first transaction only
+		{ts: 3, tables: []string{"t1"}},
+		{ts: 4, tables: []string{"t3"}},
+		// conflicts with the transactions on both workers
+		{ts: 5, tables: []string{"t1", "t2"}},
+		{ts: 6, tables: []string{"t2"}},
+	}
+	recorder := &execRecorder{}
+	txns := make([][]*model.RowChangedEvent, 0, len(txnTables))
+	for _, txn := range txnTables {
+		rows := make([]*model.RowChangedEvent, 0, len(txn.tables))
+		mock.ExpectBegin()
+		for _, table := range txn.tables {
+			rows = append(rows, &model.RowChangedEvent{
+				StartTs: txn.ts - 1,
+				Ts:      txn.ts,
+				Schema:  "test",
+				Table:   table,
+				Type:    model.InsertDMLType,
+				Columns: map[string]*model.Column{
+					"ts": {Type: mysql.TypeLonglong, Value: int64(txn.ts)},
+				},
+			})
+			mock.ExpectExec(fmt.Sprintf("REPLACE INTO `test`.`%s`(`ts`) VALUES (?);", table)).
+				WithArgs(recordedArg{
+					value:    int64(txn.ts),
+					row:      fmt.Sprintf("%s@%d", table, txn.ts),
+					once:     new(sync.Once),
+					recorder: recorder,
+				}).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		mock.ExpectCommit()
+		txns = append(txns, rows)
+	}
+
+	sink := &mysqlSink{db: db, params: params{workerCount: 2}}
+	c.Assert(sink.concurrentExecTxns(context.Background(), txns), check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+	c.Assert(recorder.applied, check.HasLen, 7)
+
+	position := make(map[string]int, len(recorder.applied))
+	for i, row := range recorder.applied {
+		position[row] = i
+	}
+	// the conflicting rows are applied in the order of commit ts
+	for _, order := range [][]string{
+		{"t1@1", "t1@3", "t1@5"},
+		{"t2@2", "t2@5", "t2@6"},
+	} {
+		for i := 1; i < len(order); i++ {
+			c.Assert(position[order[i-1]], check.Less, position[order[i]], check.Commentf("applied: %v", recorder.applied))
+		}
+	}
+	// all of the dispatched transactions are applied before the one
+	// conflicting with more than one worker
+	for _, row := range []string{"t1@1", "t2@2", "t1@3", "t3@4"} {
+		c.Assert(position[row], check.Less, position["t1@5"], check.Commentf("applied: %v", recorder.applied))
+		c.Assert(position[row], check.Less, position["t2@5"], check.Commentf("applied: %v", recorder.applied))
+	}
+}
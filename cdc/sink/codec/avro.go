@@ -0,0 +1,366 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"regexp"
+	"strconv"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/parser/charset"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/model"
+	"go.uber.org/zap"
+)
+
+// avroMagicByte is the first byte of the messages in the Confluent wire format,
+// followed by the 4 bytes schema ID in big endian and the Avro binary.
+const avroMagicByte = 0
+
+// AvroEventEncoder encodes the rows to Avro in the Confluent wire format. The
+// key and value schemas of a table are the records `<schema>.<table>.Key` and
+// `<schema>.<table>.Value`, the invalid characters in the names are replaced
+// with `_`. They are registered in the schema registry under the subjects of
+// the record names, which follows the RecordNameStrategy of Confluent, so the
+// tables can be sent to the same topic. The consumers should be configured
+// with `key.subject.name.strategy` and `value.subject.name.strategy` set to
+// `io.confluent.kafka.serializers.subject.RecordNameStrategy`.
+// The key of a message is the first unique key of the table, and the value of
+// a delete is a tombstone, so the tables without any unique key are rejected.
+// There is no message for the resolved events and the DDL events, a DDL only
+// registers the new version of the schemas.
+type AvroEventEncoder struct {
+	registry *SchemaRegistry
+	// schemas caches the latest schemas of the tables by table ID
+	schemas map[int64]*avroTableSchema
+}
+
+// NewAvroEventEncoder creates a new AvroEventEncoder
+func NewAvroEventEncoder(registry *SchemaRegistry) EventEncoder {
+	return &AvroEventEncoder{
+		registry: registry,
+		schemas:  make(map[int64]*avroTableSchema),
+	}
+}
+
+type avroTableSchema struct {
+	// version is the UpdateTS of the table info
+	version uint64
+	key     *avroRecordSchema
+	value   *avroRecordSchema
+}
+
+type avroRecordSchema struct {
+	id     int32
+	codec  *goavro.Codec
+	fields []*avroField
+}
+
+type avroField struct {
+	name     string
+	column   string
+	tp       string
+	nullable bool
+}
+
+// avroSchema is the JSON of the Avro schema of a record
+type avroSchema struct {
+	Type      string        `json:"type"`
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace"`
+	Fields    []interface{} `json:"fields"`
+}
+
+var avroInvalidNameChars = regexp.MustCompile("[^A-Za-z0-9_]")
+
+// avroName converts the name of TiDB to a valid name of Avro
+func avroName(name string) string {
+	name = avroInvalidNameChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// getAvroType returns the primitive type of Avro of the column
+func getAvroType(col *timodel.ColumnInfo) (string, error) {
+	switch col.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeYear:
+		return "int", nil
+	case mysql.TypeLong:
+		if mysql.HasUnsignedFlag(col.Flag) {
+			return "long", nil
+		}
+		return "int", nil
+	case mysql.TypeLonglong:
+		// an unsigned bigint may overflow the long of Avro
+		if mysql.HasUnsignedFlag(col.Flag) {
+			return "string", nil
+		}
+		return "long", nil
+	case mysql.TypeBit, mysql.TypeEnum, mysql.TypeSet:
+		return "long", nil
+	case mysql.TypeFloat:
+		return "float", nil
+	case mysql.TypeDouble:
+		return "double", nil
+	case mysql.TypeDecimal, mysql.TypeNewDecimal, mysql.TypeDate, mysql.TypeNewDate,
+		mysql.TypeDatetime, mysql.TypeTimestamp, mysql.TypeDuration, mysql.TypeJSON:
+		return "string", nil
+	case mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeString,
+		mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
+		if col.Charset == charset.CharsetBin {
+			return "bytes", nil
+		}
+		return "string", nil
+	case mysql.TypeGeometry:
+		return "bytes", nil
+	default:
+		return "", errors.Errorf("unsupported type %d of column %s", col.Tp, col.Name.O)
+	}
+}
+
+// getAvroNativeValue converts the column value to the native value of goavro
+func getAvroNativeValue(tp string, value interface{}) (interface{}, error) {
+	switch tp {
+	case "int":
+		switch v := value.(type) {
+		case int64:
+			return int32(v), nil
+		case uint64:
+			return int32(v), nil
+		}
+	case "long":
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case uint64:
+			return int64(v), nil
+		}
+	case "float":
+		switch v := value.(type) {
+		case float32:
+			return v, nil
+		case float64:
+			return float32(v), nil
+		}
+	case "double":
+		switch v := value.(type) {
+		case float32:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		case uint64:
+			return strconv.FormatUint(v, 10), nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		}
+	case "bytes":
+		switch v := value.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		}
+	}
+	return nil, errors.Errorf("unexpected value %v(%T) of the avro type %s", value, value, tp)
+}
+
+func newAvroFields(cols []*timodel.ColumnInfo) ([]*avroField, []interface{}, error) {
+	fields := make([]*avroField, 0, len(cols))
+	schemaFields := make([]interface{}, 0, len(cols))
+	for _, col := range cols {
+		tp, err := getAvroType(col)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		field := &avroField{
+			name:     avroName(col.Name.O),
+			column:   col.Name.O,
+			tp:       tp,
+			nullable: !mysql.HasNotNullFlag(col.Flag),
+		}
+		fields = append(fields, field)
+		if field.nullable {
+			schemaFields = append(schemaFields, map[string]interface{}{
+				"name":    field.name,
+				"type":    []string{"null", tp},
+				"default": nil,
+			})
+		} else {
+			schemaFields = append(schemaFields, map[string]interface{}{
+				"name": field.name,
+				"type": tp,
+			})
+		}
+	}
+	return fields, schemaFields, nil
+}
+
+// registerRecordSchema builds the record schema of the columns and registers it
+// under the subject of the full name of the record.
+func (e *AvroEventEncoder) registerRecordSchema(name, namespace string, cols []*timodel.ColumnInfo) (*avroRecordSchema, error) {
+	subject := namespace + "." + name
+	fields, schemaFields, err := newAvroFields(cols)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	schema, err := json.Marshal(&avroSchema{
+		Type:      "record",
+		Name:      name,
+		Namespace: namespace,
+		Fields:    schemaFields,
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	codec, err := goavro.NewCodec(string(schema))
+	if err != nil {
+		return nil, errors.Annotatef(err, "create the avro codec of subject %s", subject)
+	}
+	id, err := e.registry.Register(context.Background(), subject, string(schema))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	log.Info("register avro schema", zap.String("subject", subject), zap.Int32("id", id))
+	return &avroRecordSchema{id: id, codec: codec, fields: fields}, nil
+}
+
+// getTableSchema returns the schemas of the table info, and registers them
+// if the table info is a new version.
+func (e *AvroEventEncoder) getTableSchema(schema, table string, info *timodel.TableInfo) (*avroTableSchema, error) {
+	if s, ok := e.schemas[info.ID]; ok && s.version == info.UpdateTS {
+		return s, nil
+	}
+	ti := entry.WrapTableInfo(info)
+	cols := make([]*timodel.ColumnInfo, 0, len(ti.Columns))
+	colsByName := make(map[string]*timodel.ColumnInfo, len(ti.Columns))
+	for _, col := range ti.Columns {
+		if ti.IsColWritable(col) {
+			cols = append(cols, col)
+			colsByName[col.Name.O] = col
+		}
+	}
+	namespace := avroName(schema) + "." + avroName(table)
+	uniqueKeys := ti.GetUniqueKeys()
+	if len(uniqueKeys) == 0 {
+		// the tombstone of a delete can't tell the deleted row without a key
+		return nil, errors.Errorf("table %s.%s has no primary key or unique key, which is required by the avro protocol",
+			schema, table)
+	}
+	keyCols := make([]*timodel.ColumnInfo, 0, len(uniqueKeys[0]))
+	for _, name := range uniqueKeys[0] {
+		col, ok := colsByName[name]
+		if !ok {
+			return nil, errors.NotFoundf("key column %s of table %s.%s", name, schema, table)
+		}
+		keyCols = append(keyCols, col)
+	}
+	s := &avroTableSchema{version: info.UpdateTS}
+	var err error
+	s.key, err = e.registerRecordSchema("Key", namespace, keyCols)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s.value, err = e.registerRecordSchema("Value", namespace, cols)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	e.schemas[info.ID] = s
+	return s, nil
+}
+
+// encode encodes the columns to the Confluent wire format
+func (s *avroRecordSchema) encode(cols map[string]*model.Column) ([]byte, error) {
+	native := make(map[string]interface{}, len(s.fields))
+	for _, field := range s.fields {
+		var value interface{}
+		if col, ok := cols[field.column]; ok && col.Value != nil {
+			v, err := getAvroNativeValue(field.tp, col.Value)
+			if err != nil {
+				return nil, errors.Annotatef(err, "encode column %s", field.column)
+			}
+			value = v
+		}
+		if field.nullable && value != nil {
+			value = goavro.Union(field.tp, value)
+		}
+		native[field.name] = value
+	}
+	buf := make([]byte, 5, 64)
+	buf[0] = avroMagicByte
+	binary.BigEndian.PutUint32(buf[1:], uint32(s.id))
+	data, err := s.codec.BinaryFromNative(buf, native)
+	return data, errors.Trace(err)
+}
+
+// EncodeResolvedEvent implements the EventEncoder interface
+func (e *AvroEventEncoder) EncodeResolvedEvent(ts uint64) (*MQMessage, error) {
+	return nil, nil
+}
+
+// EncodeRowChangedEvent implements the EventEncoder interface
+func (e *AvroEventEncoder) EncodeRowChangedEvent(row *model.RowChangedEvent) (*MQMessage, error) {
+	if row.TableInfo == nil {
+		return nil, errors.Errorf("the table info of %s.%s is unknown", row.Schema, row.Table)
+	}
+	s, err := e.getTableSchema(row.Schema, row.Table, row.TableInfo)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	cols := row.Columns
+	if row.IsDelete() {
+		cols = row.PreColumns
+	}
+	msg := new(MQMessage)
+	msg.Key, err = s.key.encode(cols)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if row.IsDelete() {
+		return msg, nil
+	}
+	msg.Value, err = s.value.encode(cols)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return msg, nil
+}
+
+// EncodeDDLEvent implements the EventEncoder interface
+func (e *AvroEventEncoder) EncodeDDLEvent(ddl *model.DDLEvent) (*MQMessage, error) {
+	switch ddl.Type {
+	case timodel.ActionDropTable, timodel.ActionDropView, timodel.ActionDropSchema:
+		return nil, nil
+	}
+	if ddl.TableInfo == nil || ddl.TableInfo.IsView() {
+		return nil, nil
+	}
+	_, err := e.getTableSchema(ddl.Schema, ddl.Table, ddl.TableInfo)
+	return nil, errors.Trace(err)
+}
@@ -0,0 +1,255 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/charset"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/parser/types"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+// fakeSchemaRegistry is an in-process fake of the Confluent schema registry
+type fakeSchemaRegistry struct {
+	mu       sync.Mutex
+	schemas  []string
+	subjects map[string][]int32
+}
+
+func newFakeSchemaRegistry() *fakeSchemaRegistry {
+	return &fakeSchemaRegistry{subjects: make(map[string][]int32)}
+}
+
+func (r *fakeSchemaRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	path := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if req.Method != http.MethodPost || len(path) != 3 || path[0] != "subjects" || path[2] != "versions" {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error_code":404,"message":"not found"}`))
+		return
+	}
+	request := new(schemaRegistryRequest)
+	if err := json.NewDecoder(req.Body).Decode(request); err != nil || request.Schema == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"error_code":42201,"message":"invalid schema"}`))
+		return
+	}
+	var id int32
+	for i, schema := range r.schemas {
+		if schema == request.Schema {
+			id = int32(i + 1)
+		}
+	}
+	if id == 0 {
+		r.schemas = append(r.schemas, request.Schema)
+		id = int32(len(r.schemas))
+	}
+	subject := path[1]
+	versions := r.subjects[subject]
+	if len(versions) == 0 || versions[len(versions)-1] != id {
+		r.subjects[subject] = append(versions, id)
+	}
+	_ = json.NewEncoder(w).Encode(&schemaRegistryResponse{ID: id})
+}
+
+func (r *fakeSchemaRegistry) versions(subject string) []int32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.subjects[subject]
+}
+
+// decode decodes a message in the Confluent wire format
+func (r *fakeSchemaRegistry) decode(c *check.C, data []byte) (int32, map[string]interface{}) {
+	c.Assert(len(data) > 5, check.IsTrue)
+	c.Assert(data[0], check.Equals, byte(avroMagicByte))
+	id := int32(binary.BigEndian.Uint32(data[1:5]))
+	r.mu.Lock()
+	schema := r.schemas[id-1]
+	r.mu.Unlock()
+	codec, err := goavro.NewCodec(schema)
+	c.Assert(err, check.IsNil)
+	native, remaining, err := codec.NativeFromBinary(data[5:])
+	c.Assert(err, check.IsNil)
+	c.Assert(remaining, check.HasLen, 0)
+	return id, native.(map[string]interface{})
+}
+
+type avroSuite struct {
+	registry *fakeSchemaRegistry
+	server   *httptest.Server
+}
+
+var _ = check.Suite(&avroSuite{})
+
+func (s *avroSuite) SetUpTest(c *check.C) {
+	s.registry = newFakeSchemaRegistry()
+	s.server = httptest.NewServer(s.registry)
+}
+
+func (s *avroSuite) TearDownTest(c *check.C) {
+	s.server.Close()
+}
+
+func newAvroTestColumn(id int64, name string, tp byte, flag uint, cs string) *timodel.ColumnInfo {
+	return &timodel.ColumnInfo{
+		ID:     id,
+		Name:   timodel.NewCIStr(name),
+		Offset: int(id - 1),
+		State:  timodel.StatePublic,
+		FieldType: types.FieldType{
+			Tp:      tp,
+			Flag:    flag,
+			Charset: cs,
+		},
+	}
+}
+
+func newAvroTestTableInfo(version uint64, withComment bool) *timodel.TableInfo {
+	info := &timodel.TableInfo{
+		ID:         47,
+		Name:       timodel.NewCIStr("t"),
+		PKIsHandle: true,
+		UpdateTS:   version,
+		State:      timodel.StatePublic,
+		Columns: []*timodel.ColumnInfo{
+			newAvroTestColumn(1, "id", mysql.TypeLonglong, mysql.PriKeyFlag|mysql.NotNullFlag, charset.CharsetBin),
+			newAvroTestColumn(2, "name", mysql.TypeVarchar, 0, charset.CharsetUTF8MB4),
+			newAvroTestColumn(3, "data", mysql.TypeBlob, mysql.BinaryFlag, charset.CharsetBin),
+			newAvroTestColumn(4, "rate", mysql.TypeDouble, mysql.NotNullFlag, charset.CharsetBin),
+		},
+	}
+	if withComment {
+		info.Columns = append(info.Columns, newAvroTestColumn(5, "comment", mysql.TypeVarchar, 0, charset.CharsetUTF8MB4))
+	}
+	return info
+}
+
+func (s *avroSuite) TestEncodeRows(c *check.C) {
+	encoder := NewAvroEventEncoder(NewSchemaRegistry(s.server.URL))
+	info := newAvroTestTableInfo(1, false)
+	cols := map[string]*model.Column{
+		"id":   {Type: mysql.TypeLonglong, WhereHandle: true, Value: int64(1)},
+		"name": {Type: mysql.TypeVarchar, Value: []byte("ticdc")},
+		"data": {Type: mysql.TypeBlob},
+		"rate": {Type: mysql.TypeDouble, Value: 2.5},
+	}
+	msg, err := encoder.EncodeRowChangedEvent(&model.RowChangedEvent{
+		Ts: 417318403368288260, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: cols, TableInfo: info,
+	})
+	c.Assert(err, check.IsNil)
+
+	keyID, key := s.registry.decode(c, msg.Key)
+	c.Assert(s.registry.versions("test.t.Key"), check.DeepEquals, []int32{keyID})
+	c.Assert(key, check.DeepEquals, map[string]interface{}{"id": int64(1)})
+	valueID, value := s.registry.decode(c, msg.Value)
+	c.Assert(s.registry.versions("test.t.Value"), check.DeepEquals, []int32{valueID})
+	c.Assert(value, check.DeepEquals, map[string]interface{}{
+		"id":   int64(1),
+		"name": map[string]interface{}{"string": "ticdc"},
+		"data": nil,
+		"rate": 2.5,
+	})
+
+	// the delete is a tombstone with the key
+	msg, err = encoder.EncodeRowChangedEvent(&model.RowChangedEvent{
+		Ts: 417318403368288261, Schema: "test", Table: "t", Type: model.DeleteDMLType, PreColumns: cols, TableInfo: info,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.Value, check.IsNil)
+	_, key = s.registry.decode(c, msg.Key)
+	c.Assert(key, check.DeepEquals, map[string]interface{}{"id": int64(1)})
+
+	// the schemas are registered once for a version
+	c.Assert(s.registry.versions("test.t.Value"), check.HasLen, 1)
+
+	_, err = encoder.EncodeRowChangedEvent(&model.RowChangedEvent{
+		Ts: 417318403368288262, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: cols,
+	})
+	c.Assert(err, check.ErrorMatches, ".*table info.*unknown.*")
+}
+
+func (s *avroSuite) TestDDLRegistersNewVersion(c *check.C) {
+	encoder := NewAvroEventEncoder(NewSchemaRegistry(s.server.URL))
+	msg, err := encoder.EncodeDDLEvent(&model.DDLEvent{
+		Ts: 417318403368288260, Schema: "test", Table: "t", Type: timodel.ActionCreateTable,
+		TableInfo: newAvroTestTableInfo(1, false),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.IsNil)
+	c.Assert(s.registry.versions("test.t.Value"), check.HasLen, 1)
+
+	info := newAvroTestTableInfo(2, true)
+	msg, err = encoder.EncodeDDLEvent(&model.DDLEvent{
+		Ts: 417318403368288261, Schema: "test", Table: "t", Type: timodel.ActionAddColumn, TableInfo: info,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.IsNil)
+	versions := s.registry.versions("test.t.Value")
+	c.Assert(versions, check.HasLen, 2)
+	// the key is not changed, so it has only one version
+	c.Assert(s.registry.versions("test.t.Key"), check.HasLen, 1)
+
+	msg, err = encoder.EncodeRowChangedEvent(&model.RowChangedEvent{
+		Ts: 417318403368288262, Schema: "test", Table: "t", Type: model.InsertDMLType, TableInfo: info,
+		Columns: map[string]*model.Column{
+			"id":      {Type: mysql.TypeLonglong, WhereHandle: true, Value: int64(2)},
+			"name":    {Type: mysql.TypeVarchar},
+			"data":    {Type: mysql.TypeBlob, Value: []byte{0x00, 0xff}},
+			"rate":    {Type: mysql.TypeDouble, Value: 0.5},
+			"comment": {Type: mysql.TypeVarchar, Value: []byte("new")},
+		},
+	})
+	c.Assert(err, check.IsNil)
+	valueID, value := s.registry.decode(c, msg.Value)
+	c.Assert(valueID, check.Equals, versions[1])
+	c.Assert(value["data"], check.DeepEquals, map[string]interface{}{"bytes": []byte{0x00, 0xff}})
+	c.Assert(value["comment"], check.DeepEquals, map[string]interface{}{"string": "new"})
+}
+
+func (s *avroSuite) TestTableWithoutUniqueKey(c *check.C) {
+	encoder := NewAvroEventEncoder(NewSchemaRegistry(s.server.URL))
+	info := newAvroTestTableInfo(1, false)
+	info.PKIsHandle = false
+	info.Columns[0].Flag = mysql.NotNullFlag
+	_, err := encoder.EncodeDDLEvent(&model.DDLEvent{
+		Ts: 417318403368288260, Schema: "test", Table: "t", Type: timodel.ActionCreateTable, TableInfo: info,
+	})
+	c.Assert(err, check.ErrorMatches, ".*test.t has no primary key or unique key.*")
+	_, err = encoder.EncodeRowChangedEvent(&model.RowChangedEvent{
+		Ts: 417318403368288261, Schema: "test", Table: "t", Type: model.DeleteDMLType, TableInfo: info,
+		PreColumns: map[string]*model.Column{"id": {Type: mysql.TypeLonglong, Value: int64(1)}},
+	})
+	c.Assert(err, check.ErrorMatches, ".*test.t has no primary key or unique key.*")
+	c.Assert(s.registry.versions("test.t.Value"), check.HasLen, 0)
+}
+
+func (s *avroSuite) TestRegistryError(c *check.C) {
+	registry := NewSchemaRegistry(s.server.URL + "/unknown")
+	_, err := registry.Register(context.Background(), "test.t.Value", `"string"`)
+	c.Assert(err, check.ErrorMatches, ".*404.*not found.*")
+	_, err = NewEventEncoder(ProtocolAvro, nil, "")
+	c.Assert(err, check.ErrorMatches, ".*registry.*required.*")
+}
@@ -0,0 +1,614 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/proto/canal"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+)
+
+const (
+	// canalPacketMagicNumber and canalProtocolVersion are the defaults in CanalProtocol.proto
+	canalPacketMagicNumber = 17
+	canalProtocolVersion   = 1
+	// canalCommitTsKey is the key of the header property carrying the commit ts,
+	// since the execute time of canal is in milliseconds.
+	canalCommitTsKey = "commit-ts"
+)
+
+// The values of java.sql.Types used as the sql types of the columns
+const (
+	javaSQLTypeBIT       = -7
+	javaSQLTypeTINYINT   = -6
+	javaSQLTypeSMALLINT  = 5
+	javaSQLTypeINTEGER   = 4
+	javaSQLTypeBIGINT    = -5
+	javaSQLTypeREAL      = 7
+	javaSQLTypeDOUBLE    = 8
+	javaSQLTypeDECIMAL   = 3
+	javaSQLTypeCHAR      = 1
+	javaSQLTypeVARCHAR   = 12
+	javaSQLTypeDATE      = 91
+	javaSQLTypeTIME      = 92
+	javaSQLTypeTIMESTAMP = 93
+	javaSQLTypeBINARY    = -2
+	javaSQLTypeBLOB      = 2004
+	javaSQLTypeNULL      = 0
+	javaSQLTypeOTHER     = 1111
+)
+
+// getJavaSQLType returns the java.sql.Types of the column type, following the
+// conversions of canal.
+func getJavaSQLType(tp byte) int32 {
+	switch tp {
+	case mysql.TypeBit, mysql.TypeSet:
+		return javaSQLTypeBIT
+	case mysql.TypeTiny:
+		return javaSQLTypeTINYINT
+	case mysql.TypeShort:
+		return javaSQLTypeSMALLINT
+	case mysql.TypeInt24, mysql.TypeLong, mysql.TypeEnum:
+		return javaSQLTypeINTEGER
+	case mysql.TypeLonglong:
+		return javaSQLTypeBIGINT
+	case mysql.TypeFloat:
+		return javaSQLTypeREAL
+	case mysql.TypeDouble:
+		return javaSQLTypeDOUBLE
+	case mysql.TypeDecimal, mysql.TypeNewDecimal:
+		return javaSQLTypeDECIMAL
+	case mysql.TypeDate, mysql.TypeNewDate:
+		return javaSQLTypeDATE
+	case mysql.TypeDuration:
+		return javaSQLTypeTIME
+	case mysql.TypeDatetime, mysql.TypeTimestamp:
+		return javaSQLTypeTIMESTAMP
+	case mysql.TypeYear, mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeJSON:
+		return javaSQLTypeVARCHAR
+	case mysql.TypeString:
+		return javaSQLTypeCHAR
+	case mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
+		return javaSQLTypeBLOB
+	case mysql.TypeGeometry:
+		return javaSQLTypeBINARY
+	case mysql.TypeNull:
+		return javaSQLTypeNULL
+	default:
+		return javaSQLTypeOTHER
+	}
+}
+
+// mysqlTypeNames is the names of the column types in the `mysqlType` of canal
+var mysqlTypeNames = map[byte]string{
+	mysql.TypeBit:        "bit",
+	mysql.TypeTiny:       "tinyint",
+	mysql.TypeShort:      "smallint",
+	mysql.TypeInt24:      "mediumint",
+	mysql.TypeLong:       "int",
+	mysql.TypeLonglong:   "bigint",
+	mysql.TypeFloat:      "float",
+	mysql.TypeDouble:     "double",
+	mysql.TypeNewDecimal: "decimal",
+	mysql.TypeDate:       "date",
+	mysql.TypeDuration:   "time",
+	mysql.TypeDatetime:   "datetime",
+	mysql.TypeTimestamp:  "timestamp",
+	mysql.TypeYear:       "year",
+	mysql.TypeEnum:       "enum",
+	mysql.TypeSet:        "set",
+	mysql.TypeJSON:       "json",
+	mysql.TypeVarchar:    "varchar",
+	mysql.TypeString:     "char",
+	mysql.TypeTinyBlob:   "tinyblob",
+	mysql.TypeBlob:       "blob",
+	mysql.TypeMediumBlob: "mediumblob",
+	mysql.TypeLongBlob:   "longblob",
+	mysql.TypeGeometry:   "geometry",
+	mysql.TypeNull:       "null",
+}
+
+var mysqlTypesByName = func() map[string]byte {
+	types := make(map[string]byte, len(mysqlTypeNames))
+	for tp, name := range mysqlTypeNames {
+		types[name] = tp
+	}
+	return types
+}()
+
+// mysqlTypeAliases holds the type names used by the full mysql types of the
+// table info which are not in mysqlTypeNames.
+var mysqlTypeAliases = map[string]byte{
+	"unspecified": mysql.TypeNewDecimal,
+	"var_string":  mysql.TypeVarchar,
+	"binary":      mysql.TypeString,
+	"varbinary":   mysql.TypeVarchar,
+	"tinytext":    mysql.TypeTinyBlob,
+	"text":        mysql.TypeBlob,
+	"mediumtext":  mysql.TypeMediumBlob,
+	"longtext":    mysql.TypeLongBlob,
+}
+
+// parseMySQLType returns the type of a mysql type name, which is either a bare
+// name such as `varchar` or a full type such as `int(10) unsigned`.
+func parseMySQLType(name string) (byte, bool) {
+	if i := strings.IndexAny(name, "( "); i >= 0 {
+		name = name[:i]
+	}
+	if tp, ok := mysqlTypesByName[name]; ok {
+		return tp, true
+	}
+	tp, ok := mysqlTypeAliases[name]
+	return tp, ok
+}
+
+func getMySQLTypeName(tp byte) string {
+	switch tp {
+	case mysql.TypeDecimal:
+		tp = mysql.TypeNewDecimal
+	case mysql.TypeNewDate:
+		tp = mysql.TypeDate
+	case mysql.TypeVarString:
+		tp = mysql.TypeVarchar
+	}
+	if name, ok := mysqlTypeNames[tp]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func isBlobType(tp byte) bool {
+	switch tp {
+	case mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
+		return true
+	}
+	return false
+}
+
+// formatColumnValue formats the column value to the string in canal, it returns
+// false if the value is NULL. The binary values are encoded in ISO-8859-1 like canal.
+func formatColumnValue(col *model.Column) (string, bool) {
+	switch v := col.Value.(type) {
+	case nil:
+		return "", false
+	case []byte:
+		if isBlobType(col.Type) {
+			runes := make([]rune, len(v))
+			for i, b := range v {
+				runes[i] = rune(b)
+			}
+			return string(runes), true
+		}
+		return string(v), true
+	case string:
+		return v, true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return fmt.Sprintf("%v", v), true
+	}
+}
+
+// parseColumnValue parses the string in canal to the column value as the mounter outputs
+func parseColumnValue(tp byte, value string) (interface{}, error) {
+	switch tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong, mysql.TypeYear:
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v, nil
+		}
+		v, err := strconv.ParseUint(value, 10, 64)
+		return v, errors.Trace(err)
+	case mysql.TypeBit, mysql.TypeEnum, mysql.TypeSet:
+		v, err := strconv.ParseUint(value, 10, 64)
+		return v, errors.Trace(err)
+	case mysql.TypeFloat:
+		v, err := strconv.ParseFloat(value, 32)
+		return float32(v), errors.Trace(err)
+	case mysql.TypeDouble:
+		v, err := strconv.ParseFloat(value, 64)
+		return v, errors.Trace(err)
+	case mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
+		runes := []rune(value)
+		v := make([]byte, len(runes))
+		for i, r := range runes {
+			v[i] = byte(r)
+		}
+		return v, nil
+	case mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeString:
+		return []byte(value), nil
+	default:
+		return value, nil
+	}
+}
+
+func sortedColumnNames(cols map[string]*model.Column) []string {
+	names := make([]string, 0, len(cols))
+	for name := range cols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isColumnUpdated returns whether the column is changed by the update
+func isColumnUpdated(col *model.Column, preCols map[string]*model.Column, name string) bool {
+	preCol, ok := preCols[name]
+	if !ok {
+		return true
+	}
+	value, notNull := formatColumnValue(col)
+	preValue, preNotNull := formatColumnValue(preCol)
+	return value != preValue || notNull != preNotNull
+}
+
+func getDMLEventType(tp model.DMLType) canal.EventType {
+	switch tp {
+	case model.InsertDMLType:
+		return canal.EventType_INSERT
+	case model.UpdateDMLType:
+		return canal.EventType_UPDATE
+	case model.DeleteDMLType:
+		return canal.EventType_DELETE
+	default:
+		return canal.EventType_EVENTTYPECOMPATIBLEPROTO2
+	}
+}
+
+func getDMLType(tp canal.EventType) model.DMLType {
+	switch tp {
+	case canal.EventType_INSERT:
+		return model.InsertDMLType
+	case canal.EventType_UPDATE:
+		return model.UpdateDMLType
+	case canal.EventType_DELETE:
+		return model.DeleteDMLType
+	default:
+		return model.UnknownDMLType
+	}
+}
+
+func getDDLEventType(tp timodel.ActionType) canal.EventType {
+	switch tp {
+	case timodel.ActionCreateSchema, timodel.ActionCreateTable, timodel.ActionCreateView:
+		return canal.EventType_CREATE
+	case timodel.ActionDropSchema, timodel.ActionDropTable, timodel.ActionDropView:
+		return canal.EventType_ERASE
+	case timodel.ActionTruncateTable:
+		return canal.EventType_TRUNCATE
+	case timodel.ActionRenameTable:
+		return canal.EventType_RENAME
+	case timodel.ActionAddIndex:
+		return canal.EventType_CINDEX
+	case timodel.ActionDropIndex:
+		return canal.EventType_DINDEX
+	case timodel.ActionAddColumn, timodel.ActionDropColumn, timodel.ActionModifyColumn:
+		return canal.EventType_ALTER
+	default:
+		return canal.EventType_QUERY
+	}
+}
+
+// getDDLActionType recovers the action type of the DDL roughly, only the types
+// used by the sinks are recovered exactly.
+func getDDLActionType(tp canal.EventType, table string) timodel.ActionType {
+	switch tp {
+	case canal.EventType_CREATE:
+		if table == "" {
+			return timodel.ActionCreateSchema
+		}
+		return timodel.ActionCreateTable
+	case canal.EventType_ERASE:
+		if table == "" {
+			return timodel.ActionDropSchema
+		}
+		return timodel.ActionDropTable
+	case canal.EventType_TRUNCATE:
+		return timodel.ActionTruncateTable
+	case canal.EventType_RENAME:
+		return timodel.ActionRenameTable
+	case canal.EventType_CINDEX:
+		return timodel.ActionAddIndex
+	case canal.EventType_DINDEX:
+		return timodel.ActionDropIndex
+	default:
+		return timodel.ActionNone
+	}
+}
+
+// CanalEventEncoder encodes the events to the protobuf packets of canal, each
+// packet contains one entry. There is no resolved event in canal.
+type CanalEventEncoder struct{}
+
+// NewCanalEventEncoder creates a new CanalEventEncoder
+func NewCanalEventEncoder() EventEncoder {
+	return &CanalEventEncoder{}
+}
+
+func newCanalHeader(ts uint64, schema, table string, eventType canal.EventType) *canal.Header {
+	return &canal.Header{
+		Version:      canalProtocolVersion,
+		ServerenCode: "UTF-8",
+		ExecuteTime:  oracle.ExtractPhysical(ts),
+		SourceType:   canal.Type_MYSQL,
+		SchemaName:   schema,
+		TableName:    table,
+		EventType:    eventType,
+		Props:        []*canal.Pair{{Key: canalCommitTsKey, Value: strconv.FormatUint(ts, 10)}},
+	}
+}
+
+func newCanalColumn(index int, name, mysqlType string, col *model.Column, updated bool) *canal.Column {
+	value, notNull := formatColumnValue(col)
+	return &canal.Column{
+		Index:     int32(index),
+		SqlType:   getJavaSQLType(col.Type),
+		Name:      name,
+		IsKey:     col.WhereHandle,
+		Updated:   updated,
+		IsNull:    !notNull,
+		Value:     value,
+		MysqlType: mysqlType,
+	}
+}
+
+// newCanalColumns builds the canal columns in the order of the table, the index
+// and the mysql type of a column are taken from the table info, they fall back
+// to the position in the sorted column names and the bare type name without it.
+func newCanalColumns(tableInfo *timodel.TableInfo, cols map[string]*model.Column, preCols map[string]*model.Column, isUpdate bool) []*canal.Column {
+	names := sortedColumnNames(cols)
+	columns := make([]*canal.Column, 0, len(names))
+	for i, name := range names {
+		updated := preCols == nil
+		if isUpdate {
+			updated = isColumnUpdated(cols[name], preCols, name)
+		}
+		index, mysqlType := i, getMySQLTypeName(cols[name].Type)
+		if tableInfo != nil {
+			if colInfo := timodel.FindColumnInfo(tableInfo.Columns, name); colInfo != nil {
+				index, mysqlType = colInfo.Offset, colInfo.FieldType.InfoSchemaStr()
+			}
+		}
+		columns = append(columns, newCanalColumn(index, name, mysqlType, cols[name], updated))
+	}
+	sort.SliceStable(columns, func(i, j int) bool { return columns[i].Index < columns[j].Index })
+	return columns
+}
+
+func marshalCanalEntry(header *canal.Header, rowChange *canal.RowChange) ([]byte, error) {
+	storeValue, err := proto.Marshal(rowChange)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	entry, err := proto.Marshal(&canal.Entry{
+		Header:     header,
+		EntryType:  canal.EntryType_ROWDATA,
+		StoreValue: storeValue,
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	messages, err := proto.Marshal(&canal.Messages{Messages: [][]byte{entry}})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	packet, err := proto.Marshal(&canal.Packet{
+		MagicNumber: canalPacketMagicNumber,
+		Version:     canalProtocolVersion,
+		Type:        canal.PacketType_MESSAGES,
+		Compression: canal.Compression_NONE,
+		Body:        messages,
+	})
+	return packet, errors.Trace(err)
+}
+
+// EncodeResolvedEvent implements the EventEncoder interface
+func (e *CanalEventEncoder) EncodeResolvedEvent(ts uint64) (*MQMessage, error) {
+	return nil, nil
+}
+
+// EncodeRowChangedEvent implements the EventEncoder interface
+func (e *CanalEventEncoder) EncodeRowChangedEvent(row *model.RowChangedEvent) (*MQMessage, error) {
+	eventType := getDMLEventType(row.Type)
+	rowData := new(canal.RowData)
+	switch row.Type {
+	case model.DeleteDMLType:
+		rowData.BeforeColumns = newCanalColumns(row.TableInfo, row.PreColumns, nil, false)
+	case model.UpdateDMLType:
+		rowData.BeforeColumns = newCanalColumns(row.TableInfo, row.PreColumns, nil, false)
+		rowData.AfterColumns = newCanalColumns(row.TableInfo, row.Columns, row.PreColumns, true)
+	default:
+		rowData.AfterColumns = newCanalColumns(row.TableInfo, row.Columns, nil, false)
+	}
+	value, err := marshalCanalEntry(newCanalHeader(row.Ts, row.Schema, row.Table, eventType), &canal.RowChange{
+		EventType: eventType,
+		RowDatas:  []*canal.RowData{rowData},
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &MQMessage{Value: value}, nil
+}
+
+// EncodeDDLEvent implements the EventEncoder interface
+func (e *CanalEventEncoder) EncodeDDLEvent(ddl *model.DDLEvent) (*MQMessage, error) {
+	eventType := getDDLEventType(ddl.Type)
+	value, err := marshalCanalEntry(newCanalHeader(ddl.Ts, ddl.Schema, ddl.Table, eventType), &canal.RowChange{
+		EventType:     eventType,
+		IsDdl:         true,
+		Sql:           ddl.Query,
+		DdlSchemaName: ddl.Schema,
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &MQMessage{Value: value}, nil
+}
+
+// CanalEventDecoder decodes the events from a protobuf packet of canal
+type CanalEventDecoder struct {
+	entries [][]byte
+
+	header    *canal.Header
+	rowChange *canal.RowChange
+}
+
+// NewCanalEventDecoder creates a new CanalEventDecoder
+func NewCanalEventDecoder(value []byte) (EventDecoder, error) {
+	packet := new(canal.Packet)
+	if err := proto.Unmarshal(value, packet); err != nil {
+		return nil, errors.Annotate(err, "decode canal packet")
+	}
+	if packet.Type != canal.PacketType_MESSAGES {
+		return nil, errors.Errorf("unexpected canal packet type %s", packet.Type)
+	}
+	messages := new(canal.Messages)
+	if err := proto.Unmarshal(packet.Body, messages); err != nil {
+		return nil, errors.Annotate(err, "decode canal messages")
+	}
+	return &CanalEventDecoder{entries: messages.Messages}, nil
+}
+
+// HasNext implements the EventDecoder interface
+func (d *CanalEventDecoder) HasNext() (model.MqMessageType, bool, error) {
+	for d.rowChange == nil {
+		if len(d.entries) == 0 {
+			return model.MqMessageTypeUnknow, false, nil
+		}
+		entry := new(canal.Entry)
+		if err := proto.Unmarshal(d.entries[0], entry); err != nil {
+			return model.MqMessageTypeUnknow, false, errors.Annotate(err, "decode canal entry")
+		}
+		d.entries = d.entries[1:]
+		// the transaction begin and end entries are skipped
+		if entry.EntryType != canal.EntryType_ROWDATA {
+			continue
+		}
+		rowChange := new(canal.RowChange)
+		if err := proto.Unmarshal(entry.StoreValue, rowChange); err != nil {
+			return model.MqMessageTypeUnknow, false, errors.Annotate(err, "decode canal row change")
+		}
+		d.header = entry.Header
+		d.rowChange = rowChange
+	}
+	if d.rowChange.IsDdl {
+		return model.MqMessageTypeDDL, true, nil
+	}
+	return model.MqMessageTypeRow, true, nil
+}
+
+// NextResolvedEvent implements the EventDecoder interface
+func (d *CanalEventDecoder) NextResolvedEvent() (uint64, error) {
+	return 0, errors.New("there is no resolved event in canal")
+}
+
+// commitTs returns the commit ts in the header property, or composes
+// it from the execute time if the entry is not sent by TiCDC.
+func (d *CanalEventDecoder) commitTs() (uint64, error) {
+	for _, prop := range d.header.Props {
+		if prop.Key == canalCommitTsKey {
+			ts, err := strconv.ParseUint(prop.Value, 10, 64)
+			return ts, errors.Trace(err)
+		}
+	}
+	return oracle.ComposeTS(d.header.ExecuteTime, 0), nil
+}
+
+func decodeCanalColumns(columns []*canal.Column) (map[string]*model.Column, error) {
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	cols := make(map[string]*model.Column, len(columns))
+	for _, column := range columns {
+		tp, ok := parseMySQLType(column.MysqlType)
+		if !ok {
+			return nil, errors.Errorf("unknown mysql type %s of column %s", column.MysqlType, column.Name)
+		}
+		col := &model.Column{Type: tp, WhereHandle: column.IsKey}
+		if !column.IsNull {
+			value, err := parseColumnValue(tp, column.Value)
+			if err != nil {
+				return nil, errors.Annotatef(err, "parse the value of column %s", column.Name)
+			}
+			col.Value = value
+		}
+		cols[column.Name] = col
+	}
+	return cols, nil
+}
+
+// NextRowChangedEvent implements the EventDecoder interface
+func (d *CanalEventDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	if d.rowChange == nil || d.rowChange.IsDdl {
+		return nil, errors.New("the next event is not a row changed event")
+	}
+	if len(d.rowChange.RowDatas) == 0 {
+		return nil, errors.New("there is no row data in the row change")
+	}
+	ts, err := d.commitTs()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	row := &model.RowChangedEvent{
+		Ts:     ts,
+		Schema: d.header.SchemaName,
+		Table:  d.header.TableName,
+		Type:   getDMLType(d.rowChange.EventType),
+	}
+	rowData := d.rowChange.RowDatas[0]
+	row.PreColumns, err = decodeCanalColumns(rowData.BeforeColumns)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	row.Columns, err = decodeCanalColumns(rowData.AfterColumns)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// a row change may contain more than one row
+	d.rowChange.RowDatas = d.rowChange.RowDatas[1:]
+	if len(d.rowChange.RowDatas) == 0 {
+		d.rowChange = nil
+	}
+	return row, nil
+}
+
+// NextDDLEvent implements the EventDecoder interface
+func (d *CanalEventDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	if d.rowChange == nil || !d.rowChange.IsDdl {
+		return nil, errors.New("the next event is not a DDL event")
+	}
+	ts, err := d.commitTs()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ddl := &model.DDLEvent{
+		Ts:     ts,
+		Schema: d.header.SchemaName,
+		Table:  d.header.TableName,
+		Query:  d.rowChange.Sql,
+		Type:   getDDLActionType(d.rowChange.EventType, d.header.TableName),
+	}
+	d.rowChange = nil
+	return ddl, nil
+}
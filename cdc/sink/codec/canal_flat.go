@@ -0,0 +1,273 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/proto/canal"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+)
+
+// canalFlatMessage is the flat message of canal, which is sent to the MQ by canal
+// when `flatMessage` is on. The values in Data and Old are strings, or nil for NULL.
+type canalFlatMessage struct {
+	ID        int64                `json:"id"`
+	Schema    string               `json:"database"`
+	Table     string               `json:"table"`
+	PKNames   []string             `json:"pkNames"`
+	IsDDL     bool                 `json:"isDdl"`
+	EventType string               `json:"type"`
+	ExecuteTs int64                `json:"es"`
+	BuildTs   int64                `json:"ts"`
+	Query     string               `json:"sql"`
+	SQLType   map[string]int32     `json:"sqlType"`
+	MySQLType map[string]string    `json:"mysqlType"`
+	Data      []map[string]*string `json:"data"`
+	Old       []map[string]*string `json:"old"`
+	// TiDBExtension is not in canal, it carries the commit ts of TiDB
+	TiDBExtension *canalFlatTiDBExtension `json:"_tidb,omitempty"`
+}
+
+type canalFlatTiDBExtension struct {
+	CommitTs uint64 `json:"commitTs"`
+}
+
+// CanalFlatEventEncoder encodes the events to the flat messages of canal in JSON
+type CanalFlatEventEncoder struct{}
+
+// NewCanalFlatEventEncoder creates a new CanalFlatEventEncoder
+func NewCanalFlatEventEncoder() EventEncoder {
+	return &CanalFlatEventEncoder{}
+}
+
+func newCanalFlatMessage(ts uint64, schema, table string, eventType canal.EventType) *canalFlatMessage {
+	return &canalFlatMessage{
+		Schema:        schema,
+		Table:         table,
+		EventType:     eventType.String(),
+		ExecuteTs:     oracle.ExtractPhysical(ts),
+		BuildTs:       time.Now().UnixNano() / int64(time.Millisecond),
+		TiDBExtension: &canalFlatTiDBExtension{CommitTs: ts},
+	}
+}
+
+func flatColumnValues(cols map[string]*model.Column) map[string]*string {
+	values := make(map[string]*string, len(cols))
+	for name, col := range cols {
+		if value, notNull := formatColumnValue(col); notNull {
+			values[name] = &value
+		} else {
+			values[name] = nil
+		}
+	}
+	return values
+}
+
+// EncodeResolvedEvent implements the EventEncoder interface
+func (e *CanalFlatEventEncoder) EncodeResolvedEvent(ts uint64) (*MQMessage, error) {
+	return nil, nil
+}
+
+// EncodeRowChangedEvent implements the EventEncoder interface
+func (e *CanalFlatEventEncoder) EncodeRowChangedEvent(row *model.RowChangedEvent) (*MQMessage, error) {
+	msg := newCanalFlatMessage(row.Ts, row.Schema, row.Table, getDMLEventType(row.Type))
+	cols := row.Columns
+	if row.Type == model.DeleteDMLType {
+		cols = row.PreColumns
+	}
+	msg.SQLType = make(map[string]int32, len(cols))
+	msg.MySQLType = make(map[string]string, len(cols))
+	msg.PKNames = make([]string, 0, 1)
+	for _, name := range sortedColumnNames(cols) {
+		col := cols[name]
+		msg.SQLType[name] = getJavaSQLType(col.Type)
+		msg.MySQLType[name] = getMySQLTypeName(col.Type)
+		if col.WhereHandle {
+			msg.PKNames = append(msg.PKNames, name)
+		}
+	}
+	msg.Data = []map[string]*string{flatColumnValues(cols)}
+	// like canal, only the changed columns are in the old values of an update
+	if row.Type == model.UpdateDMLType && row.PreColumns != nil {
+		old := make(map[string]*string)
+		for name, value := range flatColumnValues(row.PreColumns) {
+			if col, ok := row.Columns[name]; !ok || isColumnUpdated(col, row.PreColumns, name) {
+				old[name] = value
+			}
+		}
+		msg.Old = []map[string]*string{old}
+	}
+	value, err := json.Marshal(msg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &MQMessage{Value: value}, nil
+}
+
+// EncodeDDLEvent implements the EventEncoder interface
+func (e *CanalFlatEventEncoder) EncodeDDLEvent(ddl *model.DDLEvent) (*MQMessage, error) {
+	msg := newCanalFlatMessage(ddl.Ts, ddl.Schema, ddl.Table, getDDLEventType(ddl.Type))
+	msg.IsDDL = true
+	msg.Query = ddl.Query
+	value, err := json.Marshal(msg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &MQMessage{Value: value}, nil
+}
+
+// CanalFlatEventDecoder decodes the event from a flat message of canal
+type CanalFlatEventDecoder struct {
+	msg *canalFlatMessage
+}
+
+// NewCanalFlatEventDecoder creates a new CanalFlatEventDecoder
+func NewCanalFlatEventDecoder(value []byte) (EventDecoder, error) {
+	msg := new(canalFlatMessage)
+	if err := json.Unmarshal(value, msg); err != nil {
+		return nil, errors.Annotate(err, "decode canal flat message")
+	}
+	return &CanalFlatEventDecoder{msg: msg}, nil
+}
+
+// HasNext implements the EventDecoder interface
+func (d *CanalFlatEventDecoder) HasNext() (model.MqMessageType, bool, error) {
+	if d.msg == nil {
+		return model.MqMessageTypeUnknow, false, nil
+	}
+	if d.msg.IsDDL {
+		return model.MqMessageTypeDDL, true, nil
+	}
+	return model.MqMessageTypeRow, true, nil
+}
+
+// NextResolvedEvent implements the EventDecoder interface
+func (d *CanalFlatEventDecoder) NextResolvedEvent() (uint64, error) {
+	return 0, errors.New("there is no resolved event in canal")
+}
+
+func (d *CanalFlatEventDecoder) commitTs() uint64 {
+	if d.msg.TiDBExtension != nil {
+		return d.msg.TiDBExtension.CommitTs
+	}
+	return oracle.ComposeTS(d.msg.ExecuteTs, 0)
+}
+
+func parseEventType(name string) canal.EventType {
+	for tp := canal.EventType_EVENTTYPECOMPATIBLEPROTO2; tp <= canal.EventType_MHEARTBEAT; tp++ {
+		if tp.String() == name {
+			return tp
+		}
+	}
+	return canal.EventType_EVENTTYPECOMPATIBLEPROTO2
+}
+
+func (d *CanalFlatEventDecoder) decodeColumns(values map[string]*string) (map[string]*model.Column, error) {
+	if values == nil {
+		return nil, nil
+	}
+	pkNames := make(map[string]struct{}, len(d.msg.PKNames))
+	for _, name := range d.msg.PKNames {
+		pkNames[name] = struct{}{}
+	}
+	cols := make(map[string]*model.Column, len(values))
+	for name, value := range values {
+		tp, ok := mysqlTypesByName[d.msg.MySQLType[name]]
+		if !ok {
+			return nil, errors.Errorf("unknown mysql type %s of column %s", d.msg.MySQLType[name], name)
+		}
+		_, isKey := pkNames[name]
+		col := &model.Column{Type: tp, WhereHandle: isKey}
+		if value != nil {
+			v, err := parseColumnValue(tp, *value)
+			if err != nil {
+				return nil, errors.Annotatef(err, "parse the value of column %s", name)
+			}
+			col.Value = v
+		}
+		cols[name] = col
+	}
+	return cols, nil
+}
+
+// NextRowChangedEvent implements the EventDecoder interface
+func (d *CanalFlatEventDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	if d.msg == nil || d.msg.IsDDL {
+		return nil, errors.New("the next event is not a row changed event")
+	}
+	if len(d.msg.Data) == 0 {
+		return nil, errors.New("there is no data in the flat message")
+	}
+	row := &model.RowChangedEvent{
+		Ts:     d.commitTs(),
+		Schema: d.msg.Schema,
+		Table:  d.msg.Table,
+		Type:   getDMLType(parseEventType(d.msg.EventType)),
+	}
+	cols, err := d.decodeColumns(d.msg.Data[0])
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	switch row.Type {
+	case model.DeleteDMLType:
+		row.PreColumns = cols
+	case model.UpdateDMLType:
+		row.Columns = cols
+		if len(d.msg.Old) > 0 {
+			// the unchanged columns are omitted in the old values
+			old := make(map[string]*string, len(d.msg.Data[0]))
+			for name, value := range d.msg.Data[0] {
+				old[name] = value
+			}
+			for name, value := range d.msg.Old[0] {
+				old[name] = value
+			}
+			row.PreColumns, err = d.decodeColumns(old)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	default:
+		row.Columns = cols
+	}
+	// a flat message may contain more than one row
+	d.msg.Data = d.msg.Data[1:]
+	if len(d.msg.Old) > 0 {
+		d.msg.Old = d.msg.Old[1:]
+	}
+	if len(d.msg.Data) == 0 {
+		d.msg = nil
+	}
+	return row, nil
+}
+
+// NextDDLEvent implements the EventDecoder interface
+func (d *CanalFlatEventDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	if d.msg == nil || !d.msg.IsDDL {
+		return nil, errors.New("the next event is not a DDL event")
+	}
+	ddl := &model.DDLEvent{
+		Ts:     d.commitTs(),
+		Schema: d.msg.Schema,
+		Table:  d.msg.Table,
+		Query:  d.msg.Query,
+		Type:   getDDLActionType(parseEventType(d.msg.EventType), d.msg.Table),
+	}
+	d.msg = nil
+	return ddl, nil
+}
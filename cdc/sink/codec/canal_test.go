@@ -0,0 +1,191 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/parser/types"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+type canalSuite struct{}
+
+var _ = check.Suite(&canalSuite{})
+
+var (
+	canalTestPreCols = map[string]*model.Column{
+		"id":   {Type: mysql.TypeLong, WhereHandle: true, Value: int64(1)},
+		"name": {Type: mysql.TypeVarchar, Value: []byte("pingcap")},
+		"data": {Type: mysql.TypeBlob, Value: []byte{0x00, 0xff, 0x7f}},
+		"rate": {Type: mysql.TypeDouble, Value: 1.5},
+		"memo": {Type: mysql.TypeVarchar},
+	}
+	canalTestCols = map[string]*model.Column{
+		"id":   {Type: mysql.TypeLong, WhereHandle: true, Value: int64(1)},
+		"name": {Type: mysql.TypeVarchar, Value: []byte("ticdc")},
+		"data": {Type: mysql.TypeBlob, Value: []byte{0x00, 0xff, 0x7f}},
+		"rate": {Type: mysql.TypeDouble, Value: 2.5},
+		"memo": {Type: mysql.TypeVarchar, Value: []byte("")},
+	}
+	canalTestRows = []*model.RowChangedEvent{
+		{Ts: 417318403368288260, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: canalTestCols},
+		{Ts: 417318403368288261, Schema: "test", Table: "t", Type: model.UpdateDMLType, PreColumns: canalTestPreCols, Columns: canalTestCols},
+		{Ts: 417318403368288262, Schema: "test", Table: "t", Type: model.DeleteDMLType, PreColumns: canalTestPreCols},
+	}
+	canalTestDDLs = []*model.DDLEvent{
+		{Ts: 417318403368288263, Schema: "test", Table: "t", Query: "create table t(id int primary key)", Type: timodel.ActionCreateTable},
+		{Ts: 417318403368288264, Schema: "test", Table: "t", Query: "alter table t add index idx(name)", Type: timodel.ActionAddIndex},
+		{Ts: 417318403368288265, Schema: "test", Query: "drop database test", Type: timodel.ActionDropSchema},
+	}
+)
+
+func (s *canalSuite) testRoundTrip(c *check.C, encoder EventEncoder, newDecoder func(value []byte) (EventDecoder, error)) {
+	msg, err := encoder.EncodeResolvedEvent(417318403368288266)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.IsNil)
+
+	for _, row := range canalTestRows {
+		msg, err := encoder.EncodeRowChangedEvent(row)
+		c.Assert(err, check.IsNil)
+		c.Assert(msg.Key, check.IsNil)
+		decoder, err := newDecoder(msg.Value)
+		c.Assert(err, check.IsNil)
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+		decoded, err := decoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil)
+		c.Assert(decoded, check.DeepEquals, row)
+		_, hasNext, err = decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsFalse)
+	}
+
+	for _, ddl := range canalTestDDLs {
+		msg, err := encoder.EncodeDDLEvent(ddl)
+		c.Assert(err, check.IsNil)
+		decoder, err := newDecoder(msg.Value)
+		c.Assert(err, check.IsNil)
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeDDL)
+		_, err = decoder.NextRowChangedEvent()
+		c.Assert(err, check.NotNil)
+		decoded, err := decoder.NextDDLEvent()
+		c.Assert(err, check.IsNil)
+		c.Assert(decoded, check.DeepEquals, ddl)
+	}
+}
+
+func (s *canalSuite) TestCanalRoundTrip(c *check.C) {
+	encoder, err := NewEventEncoder(ProtocolCanal, nil, "")
+	c.Assert(err, check.IsNil)
+	s.testRoundTrip(c, encoder, NewCanalEventDecoder)
+}
+
+func (s *canalSuite) TestCanalFlatRoundTrip(c *check.C) {
+	encoder, err := NewEventEncoder(ProtocolCanalJSON, nil, "")
+	c.Assert(err, check.IsNil)
+	s.testRoundTrip(c, encoder, NewCanalFlatEventDecoder)
+}
+
+func (s *canalSuite) TestJSONRoundTrip(c *check.C) {
+	encoder, err := NewEventEncoder(ProtocolDefault, nil, "")
+	c.Assert(err, check.IsNil)
+	msg, err := encoder.EncodeResolvedEvent(417318403368288266)
+	c.Assert(err, check.IsNil)
+	decoder, err := NewJSONEventDecoder(msg.Key, msg.Value)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeResolved)
+	ts, err := decoder.NextResolvedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(ts, check.Equals, uint64(417318403368288266))
+}
+
+func (s *canalSuite) TestCanalColumns(c *check.C) {
+	msg, err := NewCanalFlatEventEncoder().EncodeRowChangedEvent(canalTestRows[1])
+	c.Assert(err, check.IsNil)
+	decoder, err := NewCanalFlatEventDecoder(msg.Value)
+	c.Assert(err, check.IsNil)
+	flat := decoder.(*CanalFlatEventDecoder).msg
+	c.Assert(flat.EventType, check.Equals, "UPDATE")
+	c.Assert(flat.PKNames, check.DeepEquals, []string{"id"})
+	c.Assert(flat.SQLType["id"], check.Equals, int32(javaSQLTypeINTEGER))
+	c.Assert(flat.SQLType["data"], check.Equals, int32(javaSQLTypeBLOB))
+	c.Assert(flat.MySQLType["name"], check.Equals, "varchar")
+	// only the changed columns are in the old values
+	c.Assert(flat.Old, check.HasLen, 1)
+	c.Assert(flat.Old[0], check.HasLen, 3)
+	c.Assert(*flat.Old[0]["name"], check.Equals, "pingcap")
+	c.Assert(flat.Old[0]["memo"], check.IsNil)
+	c.Assert(flat.TiDBExtension.CommitTs, check.Equals, uint64(417318403368288261))
+}
+
+func (s *canalSuite) TestCanalColumnsWithTableInfo(c *check.C) {
+	newColumnInfo := func(name string, offset int, tp byte, flen int, cs string) *timodel.ColumnInfo {
+		return &timodel.ColumnInfo{
+			Name:      timodel.NewCIStr(name),
+			Offset:    offset,
+			FieldType: types.FieldType{Tp: tp, Flen: flen, Charset: cs},
+		}
+	}
+	row := *canalTestRows[1]
+	row.TableInfo = &timodel.TableInfo{
+		Name: timodel.NewCIStr("t"),
+		Columns: []*timodel.ColumnInfo{
+			newColumnInfo("id", 0, mysql.TypeLong, 11, "binary"),
+			newColumnInfo("name", 1, mysql.TypeVarchar, 255, "utf8mb4"),
+			newColumnInfo("rate", 2, mysql.TypeDouble, -1, "binary"),
+			newColumnInfo("data", 3, mysql.TypeBlob, -1, "binary"),
+			newColumnInfo("memo", 4, mysql.TypeVarchar, 64, "utf8mb4"),
+		},
+	}
+	msg, err := NewCanalEventEncoder().EncodeRowChangedEvent(&row)
+	c.Assert(err, check.IsNil)
+	decoder, err := NewCanalEventDecoder(msg.Value)
+	c.Assert(err, check.IsNil)
+	_, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+
+	// the columns are in the order of the table with their full mysql types
+	columns := decoder.(*CanalEventDecoder).rowChange.RowDatas[0].AfterColumns
+	c.Assert(columns, check.HasLen, 5)
+	expected := []struct {
+		name      string
+		mysqlType string
+	}{
+		{"id", "int(11)"},
+		{"name", "varchar(255)"},
+		{"rate", "double"},
+		{"data", "blob"},
+		{"memo", "varchar(64)"},
+	}
+	for i, col := range columns {
+		c.Assert(col.Index, check.Equals, int32(i))
+		c.Assert(col.Name, check.Equals, expected[i].name)
+		c.Assert(col.MysqlType, check.Equals, expected[i].mysqlType)
+	}
+
+	decoded, err := decoder.NextRowChangedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded, check.DeepEquals, canalTestRows[1])
+}
@@ -0,0 +1,202 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+// Protocol is the protocol of the messages sent to the MQ
+type Protocol int
+
+// Protocols supported by the MQ sink
+const (
+	// ProtocolDefault is the open protocol of TiCDC
+	ProtocolDefault Protocol = iota
+	// ProtocolCanal is the protobuf protocol of canal
+	ProtocolCanal
+	// ProtocolCanalJSON is the flat message protocol of canal in JSON
+	ProtocolCanalJSON
+	// ProtocolAvro is the Avro encoding with the schemas in a Confluent schema registry
+	ProtocolAvro
+	// ProtocolDebezium is the change event envelope of Debezium in JSON
+	ProtocolDebezium
+	// ProtocolBatch is the open protocol of TiCDC, in which the events of a
+	// partition are packed in batches
+	ProtocolBatch
+)
+
+// FromString parses the protocol from the `protocol` parameter of sink-uri
+func (p *Protocol) FromString(protocol string) error {
+	switch strings.ToLower(protocol) {
+	case "", "default":
+		*p = ProtocolDefault
+	case "canal":
+		*p = ProtocolCanal
+	case "canal-json":
+		*p = ProtocolCanalJSON
+	case "avro":
+		*p = ProtocolAvro
+	case "debezium":
+		*p = ProtocolDebezium
+	case "batch":
+		*p = ProtocolBatch
+	default:
+		return errors.Errorf("the protocol (%s) is not supported", protocol)
+	}
+	return nil
+}
+
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolDefault:
+		return "default"
+	case ProtocolCanal:
+		return "canal"
+	case ProtocolCanalJSON:
+		return "canal-json"
+	case ProtocolAvro:
+		return "avro"
+	case ProtocolDebezium:
+		return "debezium"
+	case ProtocolBatch:
+		return "batch"
+	default:
+		return "unknown"
+	}
+}
+
+// MQMessage is the key and value of a message sent to the MQ
+type MQMessage struct {
+	Key   []byte
+	Value []byte
+}
+
+// EventEncoder encodes the events to the messages of a protocol
+type EventEncoder interface {
+	// EncodeResolvedEvent encodes the resolved ts, which means no event before
+	// the ts will be sent. It returns nil if the protocol has no such message.
+	EncodeResolvedEvent(ts uint64) (*MQMessage, error)
+	// EncodeRowChangedEvent encodes the row changed event
+	EncodeRowChangedEvent(e *model.RowChangedEvent) (*MQMessage, error)
+	// EncodeDDLEvent encodes the DDL event, it returns nil if the protocol
+	// sends no message for DDLs.
+	EncodeDDLEvent(e *model.DDLEvent) (*MQMessage, error)
+}
+
+// TombstoneEncoder is implemented by the encoders sending a tombstone, which is a
+// message with the key of the row and no value, after each delete.
+type TombstoneEncoder interface {
+	// EncodeTombstone encodes the tombstone of the row, it returns nil if no
+	// tombstone is needed.
+	EncodeTombstone(e *model.RowChangedEvent) (*MQMessage, error)
+}
+
+// BatchEventEncoder is implemented by the encoders packing the messages of the
+// events sent to a partition in batches, each batch is sent as one message.
+type BatchEventEncoder interface {
+	EventEncoder
+	// NewBatch creates an empty batch
+	NewBatch() MessageBatch
+}
+
+// MessageBatch is a batch of the messages sent to a partition
+type MessageBatch interface {
+	// Append appends the message to the batch, it returns false without
+	// appending if the batch is full. A message is always appended to an
+	// empty batch, even if it exceeds the limits.
+	Append(msg *MQMessage) bool
+	// Len returns the number of the messages in the batch
+	Len() int
+	// Build returns the message of the batch and empties the batch, it returns
+	// nil if the batch is empty.
+	Build() (*MQMessage, error)
+}
+
+func getIntParam(params url.Values, name string, defaultValue int) (int, error) {
+	s := params.Get(name)
+	if s == "" {
+		return defaultValue, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Annotatef(err, "invalid %s of sink-uri", name)
+	}
+	if v <= 0 {
+		return 0, errors.Errorf("invalid %s of sink-uri, it must be positive", name)
+	}
+	return v, nil
+}
+
+// NewEventEncoder creates the encoder of the protocol, the params are the
+// parameters of sink-uri used by the protocol, and the captureID is the
+// capture running the sink.
+func NewEventEncoder(p Protocol, params url.Values, captureID string) (EventEncoder, error) {
+	switch p {
+	case ProtocolCanal:
+		return NewCanalEventEncoder(), nil
+	case ProtocolCanalJSON:
+		return NewCanalFlatEventEncoder(), nil
+	case ProtocolAvro:
+		registry := params.Get("registry")
+		if registry == "" {
+			return nil, errors.New("the registry of sink-uri is required by the avro protocol")
+		}
+		return NewAvroEventEncoder(NewSchemaRegistry(registry)), nil
+	case ProtocolDebezium:
+		var tombstone bool
+		if s := params.Get("tombstone"); s != "" {
+			var err error
+			tombstone, err = strconv.ParseBool(s)
+			if err != nil {
+				return nil, errors.Annotate(err, "invalid tombstone of sink-uri")
+			}
+		}
+		return NewDebeziumEventEncoder(params.Get("cluster"), captureID, tombstone), nil
+	case ProtocolBatch:
+		maxMessageBytes, err := getIntParam(params, "max-message-bytes", defaultMaxBatchMessageBytes)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		maxBatchSize, err := getIntParam(params, "max-batch-size", defaultMaxBatchSize)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		var compression CompressionType
+		if err := compression.FromString(params.Get("batch-compression")); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return NewJSONEventBatchEncoder(maxMessageBytes, maxBatchSize, compression), nil
+	default:
+		return NewJSONEventEncoder(), nil
+	}
+}
+
+// EventDecoder decodes the events from a message, a message may contain
+// more than one event.
+type EventDecoder interface {
+	// HasNext returns the type of the next event, and whether there is any event left
+	HasNext() (model.MqMessageType, bool, error)
+	// NextResolvedEvent returns the next resolved event
+	NextResolvedEvent() (uint64, error)
+	// NextRowChangedEvent returns the next row changed event
+	NextRowChangedEvent() (*model.RowChangedEvent, error)
+	// NextDDLEvent returns the next DDL event
+	NextDDLEvent() (*model.DDLEvent, error)
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"testing"
+
+	"github.com/pingcap/check"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type protocolSuite struct{}
+
+var _ = check.Suite(&protocolSuite{})
+
+func (s *protocolSuite) TestFromString(c *check.C) {
+	testCases := []struct {
+		protocol string
+		expected Protocol
+	}{
+		{"", ProtocolDefault},
+		{"default", ProtocolDefault},
+		{"canal", ProtocolCanal},
+		{"Canal-JSON", ProtocolCanalJSON},
+		{"avro", ProtocolAvro},
+		{"debezium", ProtocolDebezium},
+		{"batch", ProtocolBatch},
+	}
+	for _, tc := range testCases {
+		var p Protocol
+		c.Assert(p.FromString(tc.protocol), check.IsNil)
+		c.Assert(p, check.Equals, tc.expected)
+	}
+	var p Protocol
+	c.Assert(p.FromString("protobuf"), check.ErrorMatches, ".*not supported.*")
+}
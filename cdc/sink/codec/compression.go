@@ -0,0 +1,146 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+	"github.com/pingcap/errors"
+)
+
+// CompressionType is the compression of the batch messages, the values are the
+// same as the compression codecs of Kafka and they are written in the messages.
+type CompressionType uint64
+
+// Compression types supported by the batch messages
+const (
+	CompressionNone CompressionType = iota
+	CompressionGzip
+	CompressionSnappy
+	CompressionLZ4
+	CompressionZstd
+)
+
+// FromString parses the compression type from the parameter of sink-uri
+func (c *CompressionType) FromString(compression string) error {
+	switch strings.ToLower(compression) {
+	case "", "none":
+		*c = CompressionNone
+	case "gzip":
+		*c = CompressionGzip
+	case "snappy":
+		*c = CompressionSnappy
+	case "lz4":
+		*c = CompressionLZ4
+	case "zstd":
+		*c = CompressionZstd
+	default:
+		return errors.Errorf("the compression (%s) is not supported", compression)
+	}
+	return nil
+}
+
+func (c CompressionType) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionSnappy:
+		return "snappy"
+	case CompressionLZ4:
+		return "lz4"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		return "unknown"
+	}
+}
+
+func compress(tp CompressionType, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	switch tp {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+	case CompressionLZ4:
+		w := lz4.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	case CompressionZstd:
+		w, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	default:
+		return nil, errors.Errorf("unknown compression type %d", tp)
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(tp CompressionType, data []byte) ([]byte, error) {
+	switch tp {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		defer r.Close()
+		result, err := ioutil.ReadAll(r)
+		return result, errors.Trace(err)
+	case CompressionSnappy:
+		result, err := snappy.Decode(nil, data)
+		return result, errors.Trace(err)
+	case CompressionLZ4:
+		result, err := ioutil.ReadAll(lz4.NewReader(bytes.NewReader(data)))
+		return result, errors.Trace(err)
+	case CompressionZstd:
+		r, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		defer r.Close()
+		result, err := ioutil.ReadAll(r)
+		return result, errors.Trace(err)
+	default:
+		return nil, errors.Errorf("unknown compression type %d", tp)
+	}
+}
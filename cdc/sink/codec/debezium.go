@@ -0,0 +1,204 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser/charset"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+)
+
+const (
+	debeziumVersion   = "1.1.0"
+	debeziumConnector = "tidb"
+	// defaultDebeziumCluster is the logical name of the upstream cluster in the source block
+	defaultDebeziumCluster = "tidb"
+)
+
+// The values of the `op` in the envelope of Debezium
+const (
+	debeziumOpCreate = "c"
+	debeziumOpUpdate = "u"
+	debeziumOpDelete = "d"
+)
+
+// debeziumSource is the `source` block of the envelope
+type debeziumSource struct {
+	Version   string `json:"version"`
+	Connector string `json:"connector"`
+	Name      string `json:"name"`
+	TsMs      int64  `json:"ts_ms"`
+	Snapshot  string `json:"snapshot"`
+	DB        string `json:"db"`
+	Table     string `json:"table"`
+	CommitTs  uint64 `json:"commit_ts"`
+	CaptureID string `json:"capture_id"`
+}
+
+// debeziumEnvelope is the value of a row change in Debezium, the schemas of
+// the envelope are not embedded like the JsonConverter with schemas disabled.
+type debeziumEnvelope struct {
+	Before map[string]interface{} `json:"before"`
+	After  map[string]interface{} `json:"after"`
+	Source *debeziumSource        `json:"source"`
+	Op     string                 `json:"op"`
+	TsMs   int64                  `json:"ts_ms"`
+}
+
+// DebeziumEventEncoder encodes the rows to the change event envelope of Debezium,
+// the key of a message is the handle columns of the row. There is no message for
+// the resolved events and the DDL events.
+type DebeziumEventEncoder struct {
+	cluster   string
+	captureID string
+	tombstone bool
+}
+
+// NewDebeziumEventEncoder creates a new DebeziumEventEncoder, the cluster is the
+// logical name of the upstream, and a tombstone is sent after each delete if
+// tombstone is true.
+func NewDebeziumEventEncoder(cluster, captureID string, tombstone bool) EventEncoder {
+	if cluster == "" {
+		cluster = defaultDebeziumCluster
+	}
+	return &DebeziumEventEncoder{
+		cluster:   cluster,
+		captureID: captureID,
+		tombstone: tombstone,
+	}
+}
+
+// debeziumBinaryColumns returns the names of the binary columns, whose values are
+// encoded in base64 like the bytes of Debezium.
+func debeziumBinaryColumns(info *timodel.TableInfo) map[string]struct{} {
+	if info == nil {
+		return nil
+	}
+	cols := make(map[string]struct{})
+	for _, col := range info.Columns {
+		if col.Charset == charset.CharsetBin {
+			cols[col.Name.O] = struct{}{}
+		}
+	}
+	return cols
+}
+
+func debeziumColumnValues(cols map[string]*model.Column, binaryCols map[string]struct{}, keyOnly bool) map[string]interface{} {
+	if cols == nil {
+		return nil
+	}
+	values := make(map[string]interface{}, len(cols))
+	for name, col := range cols {
+		if keyOnly && !col.WhereHandle {
+			continue
+		}
+		switch v := col.Value.(type) {
+		case []byte:
+			// without the table info, only the blob types are treated as binary
+			_, isBinary := binaryCols[name]
+			if isBinary || (binaryCols == nil && isBlobType(col.Type)) {
+				values[name] = base64.StdEncoding.EncodeToString(v)
+			} else {
+				values[name] = string(v)
+			}
+		default:
+			values[name] = v
+		}
+	}
+	return values
+}
+
+func (e *DebeziumEventEncoder) encodeKey(row *model.RowChangedEvent) ([]byte, error) {
+	cols := row.Columns
+	if row.IsDelete() {
+		cols = row.PreColumns
+	}
+	key := debeziumColumnValues(cols, debeziumBinaryColumns(row.TableInfo), true)
+	if len(key) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(key)
+	return data, errors.Trace(err)
+}
+
+// EncodeResolvedEvent implements the EventEncoder interface
+func (e *DebeziumEventEncoder) EncodeResolvedEvent(ts uint64) (*MQMessage, error) {
+	return nil, nil
+}
+
+// EncodeRowChangedEvent implements the EventEncoder interface
+func (e *DebeziumEventEncoder) EncodeRowChangedEvent(row *model.RowChangedEvent) (*MQMessage, error) {
+	binaryCols := debeziumBinaryColumns(row.TableInfo)
+	envelope := &debeziumEnvelope{
+		Before: debeziumColumnValues(row.PreColumns, binaryCols, false),
+		Source: &debeziumSource{
+			Version:   debeziumVersion,
+			Connector: debeziumConnector,
+			Name:      e.cluster,
+			TsMs:      oracle.ExtractPhysical(row.Ts),
+			Snapshot:  "false",
+			DB:        row.Schema,
+			Table:     row.Table,
+			CommitTs:  row.Ts,
+			CaptureID: e.captureID,
+		},
+		TsMs: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	switch row.Type {
+	case model.DeleteDMLType:
+		envelope.Op = debeziumOpDelete
+	case model.UpdateDMLType:
+		envelope.Op = debeziumOpUpdate
+		envelope.After = debeziumColumnValues(row.Columns, binaryCols, false)
+	default:
+		envelope.Op = debeziumOpCreate
+		envelope.After = debeziumColumnValues(row.Columns, binaryCols, false)
+	}
+	key, err := e.encodeKey(row)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	value, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &MQMessage{Key: key, Value: value}, nil
+}
+
+// EncodeTombstone implements the TombstoneEncoder interface
+func (e *DebeziumEventEncoder) EncodeTombstone(row *model.RowChangedEvent) (*MQMessage, error) {
+	if !e.tombstone || !row.IsDelete() {
+		return nil, nil
+	}
+	key, err := e.encodeKey(row)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// the compaction of Kafka can't remove the rows without key
+	if key == nil {
+		return nil, nil
+	}
+	return &MQMessage{Key: key}, nil
+}
+
+// EncodeDDLEvent implements the EventEncoder interface
+func (e *DebeziumEventEncoder) EncodeDDLEvent(ddl *model.DDLEvent) (*MQMessage, error) {
+	return nil, nil
+}
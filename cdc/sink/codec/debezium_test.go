@@ -0,0 +1,168 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/charset"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+type debeziumSuite struct{}
+
+var _ = check.Suite(&debeziumSuite{})
+
+func decodeDebeziumEnvelope(c *check.C, msg *MQMessage) (map[string]interface{}, *debeziumEnvelope) {
+	key := make(map[string]interface{})
+	c.Assert(json.Unmarshal(msg.Key, &key), check.IsNil)
+	envelope := new(debeziumEnvelope)
+	c.Assert(json.Unmarshal(msg.Value, envelope), check.IsNil)
+	return key, envelope
+}
+
+func (s *debeziumSuite) TestEnvelope(c *check.C) {
+	params := url.Values{}
+	params.Set("cluster", "tidb-test")
+	encoder, err := NewEventEncoder(ProtocolDebezium, params, "capture-1")
+	c.Assert(err, check.IsNil)
+
+	info := newAvroTestTableInfo(1, false)
+	preCols := map[string]*model.Column{
+		"id":   {Type: mysql.TypeLonglong, WhereHandle: true, Value: int64(1)},
+		"name": {Type: mysql.TypeVarchar, Value: []byte("pingcap")},
+		"data": {Type: mysql.TypeBlob, Value: []byte{0x00, 0xff}},
+		"rate": {Type: mysql.TypeDouble, Value: 1.5},
+	}
+	cols := map[string]*model.Column{
+		"id":   {Type: mysql.TypeLonglong, WhereHandle: true, Value: int64(1)},
+		"name": {Type: mysql.TypeVarchar, Value: []byte("ticdc")},
+		"data": {Type: mysql.TypeBlob},
+		"rate": {Type: mysql.TypeDouble, Value: 2.5},
+	}
+
+	msg, err := encoder.EncodeRowChangedEvent(&model.RowChangedEvent{
+		Ts: 417318403368288260, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: cols, TableInfo: info,
+	})
+	c.Assert(err, check.IsNil)
+	key, envelope := decodeDebeziumEnvelope(c, msg)
+	c.Assert(key, check.DeepEquals, map[string]interface{}{"id": float64(1)})
+	c.Assert(envelope.Op, check.Equals, debeziumOpCreate)
+	c.Assert(envelope.Before, check.IsNil)
+	c.Assert(envelope.After, check.DeepEquals, map[string]interface{}{
+		"id": float64(1), "name": "ticdc", "data": nil, "rate": 2.5,
+	})
+	c.Assert(envelope.Source, check.DeepEquals, &debeziumSource{
+		Version:   debeziumVersion,
+		Connector: debeziumConnector,
+		Name:      "tidb-test",
+		TsMs:      1591943372224,
+		Snapshot:  "false",
+		DB:        "test",
+		Table:     "t",
+		CommitTs:  417318403368288260,
+		CaptureID: "capture-1",
+	})
+
+	msg, err = encoder.EncodeRowChangedEvent(&model.RowChangedEvent{
+		Ts: 417318403368288261, Schema: "test", Table: "t", Type: model.UpdateDMLType, PreColumns: preCols, Columns: cols, TableInfo: info,
+	})
+	c.Assert(err, check.IsNil)
+	_, envelope = decodeDebeziumEnvelope(c, msg)
+	c.Assert(envelope.Op, check.Equals, debeziumOpUpdate)
+	// the binary values are in base64
+	c.Assert(envelope.Before["data"], check.Equals, "AP8=")
+	c.Assert(envelope.Before["name"], check.Equals, "pingcap")
+	c.Assert(envelope.After["name"], check.Equals, "ticdc")
+
+	deleteRow := &model.RowChangedEvent{
+		Ts: 417318403368288262, Schema: "test", Table: "t", Type: model.DeleteDMLType, PreColumns: preCols, TableInfo: info,
+	}
+	msg, err = encoder.EncodeRowChangedEvent(deleteRow)
+	c.Assert(err, check.IsNil)
+	key, envelope = decodeDebeziumEnvelope(c, msg)
+	c.Assert(key, check.DeepEquals, map[string]interface{}{"id": float64(1)})
+	c.Assert(envelope.Op, check.Equals, debeziumOpDelete)
+	c.Assert(envelope.After, check.IsNil)
+	c.Assert(envelope.Before["id"], check.Equals, float64(1))
+
+	// the tombstones are disabled by default
+	msg, err = encoder.(TombstoneEncoder).EncodeTombstone(deleteRow)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.IsNil)
+
+	msg, err = encoder.EncodeDDLEvent(&model.DDLEvent{Ts: 417318403368288263, Schema: "test", Table: "t", Type: timodel.ActionAddColumn})
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.IsNil)
+	msg, err = encoder.EncodeResolvedEvent(417318403368288263)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.IsNil)
+}
+
+func (s *debeziumSuite) TestTombstone(c *check.C) {
+	params := url.Values{}
+	params.Set("tombstone", "true")
+	encoder, err := NewEventEncoder(ProtocolDebezium, params, "capture-1")
+	c.Assert(err, check.IsNil)
+	tombstoneEncoder := encoder.(TombstoneEncoder)
+
+	preCols := map[string]*model.Column{
+		"id":   {Type: mysql.TypeLonglong, WhereHandle: true, Value: int64(1)},
+		"name": {Type: mysql.TypeVarchar, Value: []byte("pingcap")},
+	}
+	msg, err := tombstoneEncoder.EncodeTombstone(&model.RowChangedEvent{
+		Ts: 417318403368288262, Schema: "test", Table: "t", Type: model.DeleteDMLType, PreColumns: preCols,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(string(msg.Key), check.Equals, `{"id":1}`)
+	c.Assert(msg.Value, check.IsNil)
+
+	// no tombstone for the inserts and the rows without handle
+	msg, err = tombstoneEncoder.EncodeTombstone(&model.RowChangedEvent{
+		Ts: 417318403368288263, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: preCols,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.IsNil)
+	msg, err = tombstoneEncoder.EncodeTombstone(&model.RowChangedEvent{
+		Ts: 417318403368288264, Schema: "test", Table: "t", Type: model.DeleteDMLType,
+		PreColumns: map[string]*model.Column{"name": {Type: mysql.TypeVarchar, Value: []byte("pingcap")}},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.IsNil)
+
+	params.Set("tombstone", "yes")
+	_, err = NewEventEncoder(ProtocolDebezium, params, "")
+	c.Assert(err, check.ErrorMatches, ".*invalid tombstone.*")
+}
+
+func (s *debeziumSuite) TestBinaryColumns(c *check.C) {
+	cols := map[string]*model.Column{
+		"text": {Type: mysql.TypeBlob, Value: []byte("text")},
+		"blob": {Type: mysql.TypeBlob, Value: []byte{0xff}},
+	}
+	// without the table info, the blob types are binary
+	values := debeziumColumnValues(cols, nil, false)
+	c.Assert(values["text"], check.Equals, "dGV4dA==")
+	info := &timodel.TableInfo{Columns: []*timodel.ColumnInfo{
+		newAvroTestColumn(1, "text", mysql.TypeBlob, 0, charset.CharsetUTF8MB4),
+		newAvroTestColumn(2, "blob", mysql.TypeBlob, mysql.BinaryFlag, charset.CharsetBin),
+	}}
+	values = debeziumColumnValues(cols, debeziumBinaryColumns(info), false)
+	c.Assert(values["text"], check.Equals, "text")
+	c.Assert(values["blob"], check.Equals, "/w==")
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+// JSONEventEncoder encodes the events to the open protocol of TiCDC, the key and
+// value of a message are the JSON of model.MqMessageKey and the message value.
+type JSONEventEncoder struct{}
+
+// NewJSONEventEncoder creates a new JSONEventEncoder
+func NewJSONEventEncoder() EventEncoder {
+	return &JSONEventEncoder{}
+}
+
+// EncodeResolvedEvent implements the EventEncoder interface
+func (e *JSONEventEncoder) EncodeResolvedEvent(ts uint64) (*MQMessage, error) {
+	key, err := model.NewResolvedMessage(ts).Encode()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &MQMessage{Key: key}, nil
+}
+
+// EncodeRowChangedEvent implements the EventEncoder interface
+func (e *JSONEventEncoder) EncodeRowChangedEvent(row *model.RowChangedEvent) (*MQMessage, error) {
+	key, value := row.ToMqMessage()
+	keyByte, err := key.Encode()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	valueByte, err := value.Encode()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &MQMessage{Key: keyByte, Value: valueByte}, nil
+}
+
+// EncodeDDLEvent implements the EventEncoder interface
+func (e *JSONEventEncoder) EncodeDDLEvent(ddl *model.DDLEvent) (*MQMessage, error) {
+	key, value := ddl.ToMqMessage()
+	keyByte, err := key.Encode()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	valueByte, err := value.Encode()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &MQMessage{Key: keyByte, Value: valueByte}, nil
+}
+
+// JSONEventDecoder decodes the event from a message of the open protocol
+type JSONEventDecoder struct {
+	key   *model.MqMessageKey
+	value []byte
+}
+
+// NewJSONEventDecoder creates a new JSONEventDecoder
+func NewJSONEventDecoder(key []byte, value []byte) (EventDecoder, error) {
+	k := new(model.MqMessageKey)
+	if err := k.Decode(key); err != nil {
+		return nil, errors.Annotate(err, "decode message key")
+	}
+	return &JSONEventDecoder{key: k, value: value}, nil
+}
+
+// HasNext implements the EventDecoder interface
+func (d *JSONEventDecoder) HasNext() (model.MqMessageType, bool, error) {
+	if d.key == nil {
+		return model.MqMessageTypeUnknow, false, nil
+	}
+	return d.key.Type, true, nil
+}
+
+// NextResolvedEvent implements the EventDecoder interface
+func (d *JSONEventDecoder) NextResolvedEvent() (uint64, error) {
+	if d.key == nil || d.key.Type != model.MqMessageTypeResolved {
+		return 0, errors.New("the next event is not a resolved event")
+	}
+	ts := d.key.Ts
+	d.key = nil
+	return ts, nil
+}
+
+// NextRowChangedEvent implements the EventDecoder interface
+func (d *JSONEventDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	if d.key == nil || d.key.Type != model.MqMessageTypeRow {
+		return nil, errors.New("the next event is not a row changed event")
+	}
+	value := new(model.MqMessageRow)
+	if err := value.Decode(d.value); err != nil {
+		return nil, errors.Annotate(err, "decode message value")
+	}
+	row := new(model.RowChangedEvent)
+	row.FromMqMessage(d.key, value)
+	d.key = nil
+	return row, nil
+}
+
+// NextDDLEvent implements the EventDecoder interface
+func (d *JSONEventDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	if d.key == nil || d.key.Type != model.MqMessageTypeDDL {
+		return nil, errors.New("the next event is not a DDL event")
+	}
+	value := new(model.MqMessageDDL)
+	if err := value.Decode(d.value); err != nil {
+		return nil, errors.Annotate(err, "decode message value")
+	}
+	ddl := new(model.DDLEvent)
+	ddl.FromMqMessage(d.key, value)
+	d.key = nil
+	return ddl, nil
+}
@@ -0,0 +1,290 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+const (
+	// BatchVersion1 is the version of the batch format of the open protocol. The
+	// key of a batch message is the version followed by the keys of the events,
+	// and the value is the values of the events, each key and value is prefixed
+	// by its length. The version and the lengths are uint64 in big endian.
+	BatchVersion1 uint64 = 1
+	// BatchVersion2 is the version of the compressed batch format, the key is the
+	// version and the compression type followed by the compressed keys of the
+	// events, and the value is the compressed values of the events.
+	BatchVersion2 uint64 = 2
+
+	// defaultMaxBatchSize is the default number of the events in a batch
+	defaultMaxBatchSize = 4096
+	// defaultMaxBatchMessageBytes is the default size of a batch message, which
+	// is the default max message size of the Kafka broker.
+	defaultMaxBatchMessageBytes = 1 << 20
+
+	batchVersionLength     = 8
+	batchCompressionLength = 8
+	batchLengthLength      = 8
+)
+
+// JSONEventBatchEncoder encodes the events to the open protocol of TiCDC like
+// JSONEventEncoder, and packs the messages of a partition in batches.
+type JSONEventBatchEncoder struct {
+	JSONEventEncoder
+
+	maxMessageBytes int
+	maxBatchSize    int
+	compression     CompressionType
+}
+
+// NewJSONEventBatchEncoder creates a new JSONEventBatchEncoder, the batches are
+// bounded by the bytes of the message and the number of the events, and they
+// are compressed if the compression is not CompressionNone.
+func NewJSONEventBatchEncoder(maxMessageBytes, maxBatchSize int, compression CompressionType) *JSONEventBatchEncoder {
+	return &JSONEventBatchEncoder{
+		maxMessageBytes: maxMessageBytes,
+		maxBatchSize:    maxBatchSize,
+		compression:     compression,
+	}
+}
+
+// NewBatch implements the BatchEventEncoder interface
+func (e *JSONEventBatchEncoder) NewBatch() MessageBatch {
+	return &jsonMessageBatch{
+		maxMessageBytes: e.maxMessageBytes,
+		maxBatchSize:    e.maxBatchSize,
+		compression:     e.compression,
+	}
+}
+
+// jsonMessageBatch holds the keys and the values of the batch without the header
+type jsonMessageBatch struct {
+	keyBuf   bytes.Buffer
+	valueBuf bytes.Buffer
+	count    int
+
+	maxMessageBytes int
+	maxBatchSize    int
+	compression     CompressionType
+}
+
+func (b *jsonMessageBatch) reset() {
+	b.keyBuf.Reset()
+	b.valueBuf.Reset()
+	b.count = 0
+}
+
+func writeWithLength(buf *bytes.Buffer, data []byte) {
+	var length [batchLengthLength]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(data)))
+	buf.Write(length[:])
+	buf.Write(data)
+}
+
+// Append implements the MessageBatch interface
+func (b *jsonMessageBatch) Append(msg *MQMessage) bool {
+	if b.count > 0 {
+		size := batchVersionLength + b.keyBuf.Len() + b.valueBuf.Len() + 2*batchLengthLength + len(msg.Key) + len(msg.Value)
+		if b.count >= b.maxBatchSize || size > b.maxMessageBytes {
+			return false
+		}
+	}
+	writeWithLength(&b.keyBuf, msg.Key)
+	writeWithLength(&b.valueBuf, msg.Value)
+	b.count++
+	return true
+}
+
+// Len implements the MessageBatch interface
+func (b *jsonMessageBatch) Len() int {
+	return b.count
+}
+
+// Build implements the MessageBatch interface
+func (b *jsonMessageBatch) Build() (*MQMessage, error) {
+	if b.count == 0 {
+		return nil, nil
+	}
+	defer b.reset()
+	if b.compression == CompressionNone {
+		key := make([]byte, batchVersionLength, batchVersionLength+b.keyBuf.Len())
+		binary.BigEndian.PutUint64(key, BatchVersion1)
+		return &MQMessage{
+			Key:   append(key, b.keyBuf.Bytes()...),
+			Value: append([]byte(nil), b.valueBuf.Bytes()...),
+		}, nil
+	}
+	keys, err := compress(b.compression, b.keyBuf.Bytes())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	value, err := compress(b.compression, b.valueBuf.Bytes())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	key := make([]byte, batchVersionLength+batchCompressionLength, batchVersionLength+batchCompressionLength+len(keys))
+	binary.BigEndian.PutUint64(key, BatchVersion2)
+	binary.BigEndian.PutUint64(key[batchVersionLength:], uint64(b.compression))
+	return &MQMessage{Key: append(key, keys...), Value: value}, nil
+}
+
+// IsBatchMessage returns whether the message of the open protocol is a batch,
+// the key of a message which is not a batch is a JSON object.
+func IsBatchMessage(key []byte) bool {
+	return len(key) >= batchVersionLength && key[0] != '{'
+}
+
+// JSONEventBatchDecoder decodes the events from a batch message of the open protocol
+type JSONEventBatchDecoder struct {
+	keyBytes   []byte
+	valueBytes []byte
+
+	key *model.MqMessageKey
+}
+
+// NewJSONEventBatchDecoder creates a new JSONEventBatchDecoder
+func NewJSONEventBatchDecoder(key []byte, value []byte) (EventDecoder, error) {
+	if len(key) < batchVersionLength {
+		return nil, errors.New("the key of the batch message is too short")
+	}
+	version := binary.BigEndian.Uint64(key[:batchVersionLength])
+	key = key[batchVersionLength:]
+	switch version {
+	case BatchVersion1:
+		return &JSONEventBatchDecoder{keyBytes: key, valueBytes: value}, nil
+	case BatchVersion2:
+		if len(key) < batchCompressionLength {
+			return nil, errors.New("the key of the batch message is too short")
+		}
+		compression := CompressionType(binary.BigEndian.Uint64(key[:batchCompressionLength]))
+		keyBytes, err := decompress(compression, key[batchCompressionLength:])
+		if err != nil {
+			return nil, errors.Annotate(err, "decompress the keys of the batch")
+		}
+		valueBytes, err := decompress(compression, value)
+		if err != nil {
+			return nil, errors.Annotate(err, "decompress the values of the batch")
+		}
+		return &JSONEventBatchDecoder{keyBytes: keyBytes, valueBytes: valueBytes}, nil
+	default:
+		return nil, errors.Errorf("unsupported batch version %d", version)
+	}
+}
+
+// NewOpenProtocolEventDecoder creates the decoder of a message of the open
+// protocol, which is either a batch or a single event.
+func NewOpenProtocolEventDecoder(key []byte, value []byte) (EventDecoder, error) {
+	if IsBatchMessage(key) {
+		return NewJSONEventBatchDecoder(key, value)
+	}
+	return NewJSONEventDecoder(key, value)
+}
+
+func readWithLength(data []byte) ([]byte, []byte, error) {
+	if len(data) < batchLengthLength {
+		return nil, nil, errors.New("the batch message is truncated")
+	}
+	length := binary.BigEndian.Uint64(data[:batchLengthLength])
+	data = data[batchLengthLength:]
+	if uint64(len(data)) < length {
+		return nil, nil, errors.New("the batch message is truncated")
+	}
+	return data[:length], data[length:], nil
+}
+
+// HasNext implements the EventDecoder interface
+func (d *JSONEventBatchDecoder) HasNext() (model.MqMessageType, bool, error) {
+	if d.key == nil {
+		if len(d.keyBytes) == 0 {
+			return model.MqMessageTypeUnknow, false, nil
+		}
+		keyBytes, rest, err := readWithLength(d.keyBytes)
+		if err != nil {
+			return model.MqMessageTypeUnknow, false, errors.Trace(err)
+		}
+		key := new(model.MqMessageKey)
+		if err := key.Decode(keyBytes); err != nil {
+			return model.MqMessageTypeUnknow, false, errors.Annotate(err, "decode message key")
+		}
+		d.keyBytes = rest
+		d.key = key
+	}
+	return d.key.Type, true, nil
+}
+
+// nextValue returns the value of the next event, whose key is decoded by HasNext
+func (d *JSONEventBatchDecoder) nextValue(tp model.MqMessageType) (*model.MqMessageKey, []byte, error) {
+	_, hasNext, err := d.HasNext()
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	if !hasNext {
+		return nil, nil, errors.New("there is no event left in the batch")
+	}
+	if d.key.Type != tp {
+		return nil, nil, errors.Errorf("the type of the next event is %d, not %d", d.key.Type, tp)
+	}
+	value, rest, err := readWithLength(d.valueBytes)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	key := d.key
+	d.key = nil
+	d.valueBytes = rest
+	return key, value, nil
+}
+
+// NextResolvedEvent implements the EventDecoder interface
+func (d *JSONEventBatchDecoder) NextResolvedEvent() (uint64, error) {
+	key, _, err := d.nextValue(model.MqMessageTypeResolved)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return key.Ts, nil
+}
+
+// NextRowChangedEvent implements the EventDecoder interface
+func (d *JSONEventBatchDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	key, valueBytes, err := d.nextValue(model.MqMessageTypeRow)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	value := new(model.MqMessageRow)
+	if err := value.Decode(valueBytes); err != nil {
+		return nil, errors.Annotate(err, "decode message value")
+	}
+	row := new(model.RowChangedEvent)
+	row.FromMqMessage(key, value)
+	return row, nil
+}
+
+// NextDDLEvent implements the EventDecoder interface
+func (d *JSONEventBatchDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	key, valueBytes, err := d.nextValue(model.MqMessageTypeDDL)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	value := new(model.MqMessageDDL)
+	if err := value.Decode(valueBytes); err != nil {
+		return nil, errors.Annotate(err, "decode message value")
+	}
+	ddl := new(model.DDLEvent)
+	ddl.FromMqMessage(key, value)
+	return ddl, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/binary"
+	"net/url"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+type batchSuite struct{}
+
+var _ = check.Suite(&batchSuite{})
+
+// decodeNext decodes the next event of the decoder, which is the row changed
+// event, the DDL event or the resolved ts.
+func decodeNext(c *check.C, decoder EventDecoder) interface{} {
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	var event interface{}
+	switch tp {
+	case model.MqMessageTypeRow:
+		event, err = decoder.NextRowChangedEvent()
+	case model.MqMessageTypeDDL:
+		event, err = decoder.NextDDLEvent()
+	case model.MqMessageTypeResolved:
+		event, err = decoder.NextResolvedEvent()
+	default:
+		c.Fatalf("unexpected message type %d", tp)
+	}
+	c.Assert(err, check.IsNil)
+	return event
+}
+
+func (s *batchSuite) TestBatchRoundTrip(c *check.C) {
+	for _, compression := range []string{"", "gzip", "snappy", "lz4", "zstd"} {
+		s.testBatchRoundTrip(c, compression)
+	}
+}
+
+func (s *batchSuite) testBatchRoundTrip(c *check.C, compression string) {
+	params := url.Values{}
+	params.Set("batch-compression", compression)
+	encoder, err := NewEventEncoder(ProtocolBatch, params, "")
+	c.Assert(err, check.IsNil)
+	batchEncoder, ok := encoder.(BatchEventEncoder)
+	c.Assert(ok, check.IsTrue)
+
+	var messages []*MQMessage
+	for _, row := range canalTestRows {
+		msg, err := encoder.EncodeRowChangedEvent(row)
+		c.Assert(err, check.IsNil)
+		messages = append(messages, msg)
+	}
+	for _, ddl := range canalTestDDLs {
+		msg, err := encoder.EncodeDDLEvent(ddl)
+		c.Assert(err, check.IsNil)
+		messages = append(messages, msg)
+	}
+	msg, err := encoder.EncodeResolvedEvent(417318403368288266)
+	c.Assert(err, check.IsNil)
+	messages = append(messages, msg)
+
+	batch := batchEncoder.NewBatch()
+	batchMsg, err := batch.Build()
+	c.Assert(err, check.IsNil)
+	c.Assert(batchMsg, check.IsNil)
+	for _, msg := range messages {
+		c.Assert(batch.Append(msg), check.IsTrue)
+	}
+	c.Assert(batch.Len(), check.Equals, len(messages))
+	batchMsg, err = batch.Build()
+	c.Assert(err, check.IsNil)
+	c.Assert(batch.Len(), check.Equals, 0)
+	c.Assert(IsBatchMessage(batchMsg.Key), check.IsTrue)
+	version := BatchVersion1
+	if compression != "" {
+		version = BatchVersion2
+	}
+	c.Assert(binary.BigEndian.Uint64(batchMsg.Key), check.Equals, version)
+
+	// the events in the batch are the same as the events of the single messages
+	decoder, err := NewOpenProtocolEventDecoder(batchMsg.Key, batchMsg.Value)
+	c.Assert(err, check.IsNil)
+	for _, msg := range messages {
+		c.Assert(IsBatchMessage(msg.Key), check.IsFalse)
+		singleDecoder, err := NewOpenProtocolEventDecoder(msg.Key, msg.Value)
+		c.Assert(err, check.IsNil)
+		c.Assert(decodeNext(c, decoder), check.DeepEquals, decodeNext(c, singleDecoder))
+	}
+	_, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsFalse)
+}
+
+func (s *batchSuite) TestBatchLimits(c *check.C) {
+	params := url.Values{}
+	params.Set("max-batch-size", "2")
+	encoder, err := NewEventEncoder(ProtocolBatch, params, "")
+	c.Assert(err, check.IsNil)
+	batch := encoder.(BatchEventEncoder).NewBatch()
+	msg := &MQMessage{Key: []byte(`{"ts":1,"type":1}`), Value: []byte(`{}`)}
+	c.Assert(batch.Append(msg), check.IsTrue)
+	c.Assert(batch.Append(msg), check.IsTrue)
+	c.Assert(batch.Append(msg), check.IsFalse)
+	c.Assert(batch.Len(), check.Equals, 2)
+
+	// the size of a batch is limited, but a large message is always appended to an empty batch
+	params = url.Values{}
+	params.Set("max-message-bytes", "64")
+	encoder, err = NewEventEncoder(ProtocolBatch, params, "")
+	c.Assert(err, check.IsNil)
+	batch = encoder.(BatchEventEncoder).NewBatch()
+	c.Assert(batch.Append(msg), check.IsTrue)
+	c.Assert(batch.Append(msg), check.IsFalse)
+	_, err = batch.Build()
+	c.Assert(err, check.IsNil)
+	large := &MQMessage{Key: msg.Key, Value: make([]byte, 128)}
+	c.Assert(batch.Append(large), check.IsTrue)
+	c.Assert(batch.Append(msg), check.IsFalse)
+
+	params.Set("max-batch-size", "0")
+	_, err = NewEventEncoder(ProtocolBatch, params, "")
+	c.Assert(err, check.ErrorMatches, ".*invalid max-batch-size.*")
+	params = url.Values{}
+	params.Set("batch-compression", "brotli")
+	_, err = NewEventEncoder(ProtocolBatch, params, "")
+	c.Assert(err, check.ErrorMatches, ".*compression \\(brotli\\) is not supported.*")
+}
+
+func (s *batchSuite) TestBatchVersion(c *check.C) {
+	_, err := NewJSONEventBatchDecoder([]byte{0, 0, 0, 0, 0, 0, 0, 3}, nil)
+	c.Assert(err, check.ErrorMatches, ".*unsupported batch version 3.*")
+	_, err = NewJSONEventBatchDecoder([]byte{0, 1}, nil)
+	c.Assert(err, check.ErrorMatches, ".*too short.*")
+	_, err = NewJSONEventBatchDecoder([]byte{0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 9}, nil)
+	c.Assert(err, check.ErrorMatches, ".*unknown compression type 9.*")
+
+	// the truncated batch can't be decoded
+	decoder, err := NewJSONEventBatchDecoder([]byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 9, '{'}, nil)
+	c.Assert(err, check.IsNil)
+	_, _, err = decoder.HasNext()
+	c.Assert(err, check.ErrorMatches, ".*truncated.*")
+}
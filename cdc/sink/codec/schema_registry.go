@@ -0,0 +1,91 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+const (
+	schemaRegistryContentType = "application/vnd.schemaregistry.v1+json"
+	schemaRegistryTimeout     = 10 * time.Second
+)
+
+// SchemaRegistry is a client of the Confluent compatible schema registry
+type SchemaRegistry struct {
+	url    string
+	client *http.Client
+}
+
+// NewSchemaRegistry creates a new SchemaRegistry of the registry url
+func NewSchemaRegistry(registryURL string) *SchemaRegistry {
+	return &SchemaRegistry{
+		url:    strings.TrimRight(registryURL, "/"),
+		client: &http.Client{Timeout: schemaRegistryTimeout},
+	}
+}
+
+type schemaRegistryRequest struct {
+	Schema string `json:"schema"`
+}
+
+type schemaRegistryResponse struct {
+	ID        int32  `json:"id"`
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+// Register registers the schema as a new version of the subject, and returns the
+// ID of the schema. Registering a registered schema returns the same ID.
+func (r *SchemaRegistry) Register(ctx context.Context, subject string, schema string) (int32, error) {
+	body, err := json.Marshal(&schemaRegistryRequest{Schema: schema})
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	uri := fmt.Sprintf("%s/subjects/%s/versions", r.url, url.PathEscape(subject))
+	req, err := http.NewRequest(http.MethodPost, uri, bytes.NewReader(body))
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	req.Header.Set("Content-Type", schemaRegistryContentType)
+	req.Header.Set("Accept", schemaRegistryContentType)
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, errors.Annotatef(err, "register the schema of subject %s", subject)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	result := new(schemaRegistryResponse)
+	if err := json.Unmarshal(data, result); err != nil {
+		return 0, errors.Annotatef(err, "register the schema of subject %s, status %s", subject, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, errors.Errorf("register the schema of subject %s failed, status %s, error code %d: %s",
+			subject, resp.Status, result.ErrorCode, result.Message)
+	}
+	return result.ID, nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"encoding/json"
+	"hash"
+	"hash/crc32"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+	"go.uber.org/zap"
+)
+
+// Dispatcher dispatches the rows to the partitions of the MQ
+type Dispatcher interface {
+	// Dispatch returns the partition of the row
+	Dispatch(row *model.RowChangedEvent) int32
+}
+
+// The names of the dispatchers in the dispatch rules
+const (
+	dispatcherDefault    = "default"
+	dispatcherTable      = "table"
+	dispatcherPrimaryKey = "primary-key"
+	dispatcherTs         = "ts"
+	dispatcherColumns    = "columns"
+	dispatcherPartition  = "partition"
+)
+
+// hashWriteValue writes the column value in JSON to the hash
+func hashWriteValue(h hash.Hash32, value interface{}) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		log.Fatal("calculate hash of message key failed, please report a bug", zap.Error(err))
+	}
+	// the write of a hash never fails
+	_, _ = h.Write(b)
+}
+
+func hashTable(row *model.RowChangedEvent) hash.Hash32 {
+	h := crc32.NewIEEE()
+	_, _ = h.Write([]byte(row.Schema))
+	_, _ = h.Write([]byte(row.Table))
+	return h
+}
+
+func rowColumns(row *model.RowChangedEvent) map[string]*model.Column {
+	if row.IsDelete() {
+		return row.PreColumns
+	}
+	return row.Columns
+}
+
+// defaultDispatcher dispatches the rows by table, and by the value of the only
+// unique column if the table has one.
+type defaultDispatcher struct {
+	partitionNum int32
+}
+
+func (d *defaultDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
+	h := hashTable(row)
+	if len(row.IndieMarkCol) > 0 {
+		if col, ok := rowColumns(row)[row.IndieMarkCol]; ok {
+			hashWriteValue(h, col.Value)
+		}
+	}
+	return int32(h.Sum32() % uint32(d.partitionNum))
+}
+
+// tableDispatcher dispatches all the rows of a table to one partition
+type tableDispatcher struct {
+	partitionNum int32
+}
+
+func (d *tableDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
+	return int32(hashTable(row).Sum32() % uint32(d.partitionNum))
+}
+
+// primaryKeyDispatcher dispatches the rows by the values of the handle columns
+type primaryKeyDispatcher struct {
+	partitionNum int32
+}
+
+func (d *primaryKeyDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
+	h := hashTable(row)
+	cols := rowColumns(row)
+	names := make([]string, 0, len(cols))
+	for name, col := range cols {
+		if col.WhereHandle {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = h.Write([]byte(name))
+		hashWriteValue(h, cols[name].Value)
+	}
+	return int32(h.Sum32() % uint32(d.partitionNum))
+}
+
+// tsDispatcher dispatches the rows by the commit ts, the rows of a transaction
+// are in the same partition.
+type tsDispatcher struct {
+	partitionNum int32
+}
+
+func (d *tsDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
+	return int32(row.Ts % uint64(d.partitionNum))
+}
+
+// columnsDispatcher dispatches the rows by the values of the columns
+type columnsDispatcher struct {
+	partitionNum int32
+	columns      []string
+}
+
+func (d *columnsDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
+	h := hashTable(row)
+	cols := rowColumns(row)
+	for _, name := range d.columns {
+		var value interface{}
+		if col, ok := cols[name]; ok {
+			value = col.Value
+		}
+		hashWriteValue(h, value)
+	}
+	return int32(h.Sum32() % uint32(d.partitionNum))
+}
+
+// partitionDispatcher dispatches all the rows to the fixed partition
+type partitionDispatcher struct {
+	partition int32
+}
+
+func (d *partitionDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
+	return d.partition
+}
+
+func newRuleDispatcher(rule *util.DispatchRule, partitionNum int32) (Dispatcher, error) {
+	switch strings.ToLower(rule.Dispatcher) {
+	case "", dispatcherDefault:
+		return &defaultDispatcher{partitionNum: partitionNum}, nil
+	case dispatcherTable:
+		return &tableDispatcher{partitionNum: partitionNum}, nil
+	case dispatcherPrimaryKey:
+		return &primaryKeyDispatcher{partitionNum: partitionNum}, nil
+	case dispatcherTs:
+		return &tsDispatcher{partitionNum: partitionNum}, nil
+	case dispatcherColumns:
+		if len(rule.Columns) == 0 {
+			return nil, errors.New("the columns of the columns dispatcher can not be empty")
+		}
+		return &columnsDispatcher{partitionNum: partitionNum, columns: rule.Columns}, nil
+	case dispatcherPartition:
+		if rule.Partition < 0 || rule.Partition >= partitionNum {
+			return nil, errors.Errorf("the partition %d of the partition dispatcher is out of range [0, %d)", rule.Partition, partitionNum)
+		}
+		return &partitionDispatcher{partition: rule.Partition}, nil
+	default:
+		return nil, errors.Errorf("the dispatcher (%s) is not supported", rule.Dispatcher)
+	}
+}
+
+type ruleDispatcher struct {
+	matcher *filter.Filter
+	Dispatcher
+}
+
+// ruleDispatchers dispatches the rows by the first rule matching the table
+type ruleDispatchers struct {
+	rules             []*ruleDispatcher
+	defaultDispatcher Dispatcher
+}
+
+func (d *ruleDispatchers) Dispatch(row *model.RowChangedEvent) int32 {
+	table := []*filter.Table{{Schema: row.Schema, Name: row.Table}}
+	for _, rule := range d.rules {
+		if len(rule.matcher.ApplyOn(table)) > 0 {
+			return rule.Dispatch(row)
+		}
+	}
+	return d.defaultDispatcher.Dispatch(row)
+}
+
+// NewDispatcher creates the dispatcher of the dispatch rules in the config, the
+// rows of the tables matching no rule are dispatched by the default dispatcher.
+func NewDispatcher(config *util.ReplicaConfig, partitionNum int32) (Dispatcher, error) {
+	if partitionNum <= 0 {
+		return nil, errors.Errorf("invalid partition number %d", partitionNum)
+	}
+	d := &ruleDispatchers{defaultDispatcher: &defaultDispatcher{partitionNum: partitionNum}}
+	if config == nil || config.Sink == nil {
+		return d, nil
+	}
+	for i, rule := range config.Sink.DispatchRules {
+		matcher, err := filter.New(config.FilterCaseSensitive, rule.Matcher)
+		if err != nil {
+			return nil, errors.Annotatef(err, "invalid matcher of dispatch rule %d", i)
+		}
+		dispatcher, err := newRuleDispatcher(rule, partitionNum)
+		if err != nil {
+			return nil, errors.Annotatef(err, "invalid dispatch rule %d", i)
+		}
+		d.rules = append(d.rules, &ruleDispatcher{matcher: matcher, Dispatcher: dispatcher})
+	}
+	return d, nil
+}
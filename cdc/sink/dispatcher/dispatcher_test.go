@@ -0,0 +1,135 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"hash/crc32"
+	"testing"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type dispatcherSuite struct{}
+
+var _ = check.Suite(&dispatcherSuite{})
+
+func newTestRow(ts uint64, schema, table string, id int64, name string) *model.RowChangedEvent {
+	return &model.RowChangedEvent{
+		Ts:           ts,
+		Schema:       schema,
+		Table:        table,
+		Type:         model.InsertDMLType,
+		IndieMarkCol: "id",
+		Columns: map[string]*model.Column{
+			"id":   {WhereHandle: true, Value: id},
+			"name": {Value: name},
+		},
+	}
+}
+
+func newTestDispatcher(c *check.C, rules ...*util.DispatchRule) Dispatcher {
+	d, err := NewDispatcher(&util.ReplicaConfig{Sink: &util.SinkConfig{DispatchRules: rules}}, 16)
+	c.Assert(err, check.IsNil)
+	return d
+}
+
+func (s *dispatcherSuite) TestDefaultDispatcher(c *check.C) {
+	d, err := NewDispatcher(nil, 16)
+	c.Assert(err, check.IsNil)
+	// the rows are dispatched by the table and the value of the only unique column
+	row := newTestRow(1, "test", "t", 1, "a")
+	c.Assert(d.Dispatch(row), check.Equals, int32(crc32.ChecksumIEEE([]byte("testt1"))%16))
+	row.IndieMarkCol = ""
+	c.Assert(d.Dispatch(row), check.Equals, int32(crc32.ChecksumIEEE([]byte("testt"))%16))
+
+	_, err = NewDispatcher(nil, 0)
+	c.Assert(err, check.ErrorMatches, ".*invalid partition number.*")
+}
+
+func (s *dispatcherSuite) TestStrategies(c *check.C) {
+	testCases := []struct {
+		rule     *util.DispatchRule
+		rows     []*model.RowChangedEvent
+		expected []int32
+	}{
+		{
+			rule:     &util.DispatchRule{Dispatcher: "table"},
+			rows:     []*model.RowChangedEvent{newTestRow(1, "test", "t", 1, "a"), newTestRow(2, "test", "t", 2, "b")},
+			expected: []int32{int32(crc32.ChecksumIEEE([]byte("testt")) % 16), int32(crc32.ChecksumIEEE([]byte("testt")) % 16)},
+		},
+		{
+			rule:     &util.DispatchRule{Dispatcher: "primary-key"},
+			rows:     []*model.RowChangedEvent{newTestRow(1, "test", "t", 1, "a"), newTestRow(2, "test", "t", 1, "b")},
+			expected: []int32{int32(crc32.ChecksumIEEE([]byte("testtid1")) % 16), int32(crc32.ChecksumIEEE([]byte("testtid1")) % 16)},
+		},
+		{
+			rule:     &util.DispatchRule{Dispatcher: "ts"},
+			rows:     []*model.RowChangedEvent{newTestRow(17, "test", "t", 1, "a"), newTestRow(18, "test", "t", 1, "a")},
+			expected: []int32{1, 2},
+		},
+		{
+			rule:     &util.DispatchRule{Dispatcher: "columns", Columns: []string{"name"}},
+			rows:     []*model.RowChangedEvent{newTestRow(1, "test", "t", 1, "a"), newTestRow(2, "test", "t", 2, "a")},
+			expected: []int32{int32(crc32.ChecksumIEEE([]byte(`testt"a"`)) % 16), int32(crc32.ChecksumIEEE([]byte(`testt"a"`)) % 16)},
+		},
+		{
+			rule:     &util.DispatchRule{Dispatcher: "Partition", Partition: 3},
+			rows:     []*model.RowChangedEvent{newTestRow(1, "test", "t", 1, "a"), newTestRow(2, "test", "t2", 2, "b")},
+			expected: []int32{3, 3},
+		},
+	}
+	for _, tc := range testCases {
+		d := newTestDispatcher(c, tc.rule)
+		for i, row := range tc.rows {
+			c.Assert(d.Dispatch(row), check.Equals, tc.expected[i], check.Commentf("dispatcher %s row %d", tc.rule.Dispatcher, i))
+		}
+	}
+}
+
+func (s *dispatcherSuite) TestRuleMatching(c *check.C) {
+	d := newTestDispatcher(c,
+		&util.DispatchRule{
+			Matcher:    &filter.Rules{DoTables: []*filter.Table{{Schema: "test", Name: "t1"}}},
+			Dispatcher: "partition",
+			Partition:  5,
+		},
+		&util.DispatchRule{
+			Matcher:    &filter.Rules{DoDBs: []string{"test"}},
+			Dispatcher: "partition",
+			Partition:  6,
+		},
+	)
+	// the first matched rule is used
+	c.Assert(d.Dispatch(newTestRow(1, "test", "t1", 1, "a")), check.Equals, int32(5))
+	c.Assert(d.Dispatch(newTestRow(1, "test", "t2", 1, "a")), check.Equals, int32(6))
+	// the tables matching no rule are dispatched by the default dispatcher
+	c.Assert(d.Dispatch(newTestRow(1, "other", "t1", 1, "a")), check.Equals, int32(crc32.ChecksumIEEE([]byte("othert11"))%16))
+}
+
+func (s *dispatcherSuite) TestInvalidRules(c *check.C) {
+	for _, rule := range []*util.DispatchRule{
+		{Dispatcher: "random"},
+		{Dispatcher: "columns"},
+		{Dispatcher: "partition", Partition: 16},
+		{Dispatcher: "partition", Partition: -1},
+	} {
+		_, err := NewDispatcher(&util.ReplicaConfig{Sink: &util.SinkConfig{DispatchRules: []*util.DispatchRule{rule}}}, 16)
+		c.Assert(err, check.NotNil)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+)
+
+// The placeholders in the topic expressions
+const (
+	topicSchemaPlaceholder = "{schema}"
+	topicTablePlaceholder  = "{table}"
+)
+
+// topicExpression is a topic name, in which the placeholders are replaced by
+// the schema and the table of the event.
+type topicExpression string
+
+func (e topicExpression) isStatic() bool {
+	return !strings.Contains(string(e), topicSchemaPlaceholder) && !strings.Contains(string(e), topicTablePlaceholder)
+}
+
+func (e topicExpression) substitute(schema, table string) string {
+	return strings.NewReplacer(
+		topicSchemaPlaceholder, sanitizeTopicName(schema),
+		topicTablePlaceholder, sanitizeTopicName(table),
+	).Replace(string(e))
+}
+
+// sanitizeTopicName replaces the characters which are illegal in the topic names
+// of Kafka, only ASCII alphanumerics, '.', '_' and '-' are legal.
+func sanitizeTopicName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+type topicRule struct {
+	matcher *filter.Filter
+	topic   topicExpression
+}
+
+// TopicDispatcher dispatches the events to the topics by the topic rules, the
+// events of the tables matching no rule are sent to the default topic.
+type TopicDispatcher struct {
+	rules        []*topicRule
+	defaultTopic topicExpression
+}
+
+// NewTopicDispatcher creates the topic dispatcher of the topic rules in the
+// config, the default topic is the topic of sink-uri, which can be an
+// expression like `{schema}_{table}` as well.
+func NewTopicDispatcher(config *util.ReplicaConfig, defaultTopic string) (*TopicDispatcher, error) {
+	d := &TopicDispatcher{defaultTopic: topicExpression(defaultTopic)}
+	if config == nil || config.Sink == nil {
+		return d, nil
+	}
+	for i, rule := range config.Sink.TopicRules {
+		if rule.Topic == "" {
+			return nil, errors.Errorf("the topic of topic rule %d can not be empty", i)
+		}
+		matcher, err := filter.New(config.FilterCaseSensitive, rule.Matcher)
+		if err != nil {
+			return nil, errors.Annotatef(err, "invalid matcher of topic rule %d", i)
+		}
+		d.rules = append(d.rules, &topicRule{matcher: matcher, topic: topicExpression(rule.Topic)})
+	}
+	return d, nil
+}
+
+// StaticTopic returns the default topic if it is not an expression
+func (d *TopicDispatcher) StaticTopic() (string, bool) {
+	if d.defaultTopic == "" || !d.defaultTopic.isStatic() {
+		return "", false
+	}
+	return string(d.defaultTopic), true
+}
+
+// IsSingleTopic returns whether all the events are sent to the default topic
+func (d *TopicDispatcher) IsSingleTopic() bool {
+	_, ok := d.StaticTopic()
+	return ok && len(d.rules) == 0
+}
+
+// Topic returns the topic of the events of the table
+func (d *TopicDispatcher) Topic(schema, table string) (string, error) {
+	tables := []*filter.Table{{Schema: schema, Name: table}}
+	expr := d.defaultTopic
+	for _, rule := range d.rules {
+		if len(rule.matcher.ApplyOn(tables)) > 0 {
+			expr = rule.topic
+			break
+		}
+	}
+	if expr == "" {
+		return "", errors.Errorf("no topic for the table %s.%s, the topic of sink-uri is empty", schema, table)
+	}
+	return expr.substitute(schema, table), nil
+}
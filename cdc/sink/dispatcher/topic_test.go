@@ -0,0 +1,65 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+)
+
+type topicDispatcherSuite struct{}
+
+var _ = check.Suite(&topicDispatcherSuite{})
+
+func (s *topicDispatcherSuite) TestTopic(c *check.C) {
+	d, err := NewTopicDispatcher(nil, "cdc")
+	c.Assert(err, check.IsNil)
+	c.Assert(d.IsSingleTopic(), check.IsTrue)
+	topic, err := d.Topic("test", "t1")
+	c.Assert(err, check.IsNil)
+	c.Assert(topic, check.Equals, "cdc")
+
+	d, err = NewTopicDispatcher(&util.ReplicaConfig{Sink: &util.SinkConfig{TopicRules: []*util.TopicRule{
+		{Matcher: &filter.Rules{DoDBs: []string{"audit"}}, Topic: "audit-{table}"},
+		{Matcher: &filter.Rules{DoTables: []*filter.Table{{Schema: "test", Name: "t2"}}}, Topic: "t2"},
+	}}}, "{schema}_{table}")
+	c.Assert(err, check.IsNil)
+	c.Assert(d.IsSingleTopic(), check.IsFalse)
+	_, ok := d.StaticTopic()
+	c.Assert(ok, check.IsFalse)
+	for _, tc := range []struct {
+		schema, table, topic string
+	}{
+		{"audit", "log", "audit-log"},
+		{"test", "t2", "t2"},
+		{"test", "t1", "test_t1"},
+		// the illegal characters of the topic names are replaced
+		{"测试", "t$1", "___t_1"},
+	} {
+		topic, err := d.Topic(tc.schema, tc.table)
+		c.Assert(err, check.IsNil)
+		c.Assert(topic, check.Equals, tc.topic)
+	}
+
+	// the events must have a topic
+	d, err = NewTopicDispatcher(&util.ReplicaConfig{Sink: &util.SinkConfig{TopicRules: []*util.TopicRule{
+		{Matcher: &filter.Rules{DoDBs: []string{"audit"}}, Topic: "audit"},
+	}}}, "")
+	c.Assert(err, check.IsNil)
+	_, err = d.Topic("test", "t1")
+	c.Assert(err, check.ErrorMatches, ".*no topic for the table test.t1.*")
+	_, err = NewTopicDispatcher(&util.ReplicaConfig{Sink: &util.SinkConfig{TopicRules: []*util.TopicRule{{}}}}, "cdc")
+	c.Assert(err, check.ErrorMatches, ".*can not be empty.*")
+}
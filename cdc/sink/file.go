@@ -0,0 +1,390 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxFileSize    = 64 * 1024 * 1024
+	defaultRotateInterval = 10 * time.Minute
+
+	// fileCheckpointName is the name of the checkpoint marker in the root directory
+	fileCheckpointName = "checkpoint"
+	// fileTmpSuffix is the suffix of the files being written
+	fileTmpSuffix = ".tmp"
+)
+
+// fileCheckpoint is the content of the checkpoint marker, all the changes
+// committed before the checkpoint ts have been written to the change files.
+type fileCheckpoint struct {
+	CheckpointTs uint64 `json:"checkpoint-ts"`
+}
+
+// fileSink writes the changes to the local files. The row changes of a table are
+// written to `<dir>/<schema>/<table>/cdc-<first commit ts>-<last commit ts>.<ext>`,
+// the DDLs are written to `ddl-<commit ts>.<ext>` in the directory of the table,
+// or the schema if it's a schema DDL, and the checkpoint marker is `<dir>/checkpoint`.
+// The change files are written with a `.tmp` suffix and renamed when they are
+// rotated by the size or the resolved ts. The change files are never overwritten,
+// a sequence is appended to the name if the file of the name exists, such as
+// `cdc-<first commit ts>-<last commit ts>-1.<ext>` for the rows with a new header
+// in the same commit ts. The changes may be written more than once if the
+// changefeed is restarted from the checkpoint.
+type fileSink struct {
+	*resolvedRowsBuffer
+
+	dir            string
+	encoder        fileEncoder
+	maxFileSize    int64
+	rotateInterval time.Duration
+
+	checkpointTs uint64
+
+	// writers is only accessed by Run
+	writers map[string]*tableFileWriter
+}
+
+func newFileSink(sinkURI *url.URL, filter *util.Filter, opts map[string]string) (*fileSink, error) {
+	dir := sinkURI.Host + sinkURI.Path
+	if dir == "" {
+		return nil, errors.New("the directory of the file sink is not specified")
+	}
+	encoder, err := newFileEncoder(sinkURI.Query().Get("format"))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s := &fileSink{
+		resolvedRowsBuffer: newResolvedRowsBuffer("file sink", filter, opts),
+		dir:                dir,
+		encoder:            encoder,
+		maxFileSize:        defaultMaxFileSize,
+		rotateInterval:     defaultRotateInterval,
+		writers:            make(map[string]*tableFileWriter),
+	}
+
+	if v := sinkURI.Query().Get("max-file-size"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, errors.Annotatef(err, "invalid max-file-size %s", v)
+		}
+		s.maxFileSize = size
+	}
+	if v := sinkURI.Query().Get("rotate-interval"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, errors.Annotatef(err, "invalid rotate-interval %s", v)
+		}
+		s.rotateInterval = interval
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Annotatef(err, "create directory %s", dir)
+	}
+	return s, nil
+}
+
+func (s *fileSink) EmitCheckpointEvent(ctx context.Context, ts uint64) error {
+	data, err := json.Marshal(&fileCheckpoint{CheckpointTs: ts})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return writeFileAtomic(filepath.Join(s.dir, fileCheckpointName), data)
+}
+
+func (s *fileSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	if s.filter.ShouldIgnoreEvent(ddl.Ts, ddl.Schema, ddl.Table) {
+		log.Info(
+			"DDL event ignored",
+			zap.String("query", ddl.Query),
+			zap.Uint64("ts", ddl.Ts),
+		)
+		return nil
+	}
+	data, err := s.encoder.EncodeDDL(ddl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	dir := s.tableDir(ddl.Schema, ddl.Table)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Annotatef(err, "create directory %s", dir)
+	}
+	name := fmt.Sprintf("ddl-%d%s", ddl.Ts, s.encoder.Extension())
+	if err := writeFileAtomic(filepath.Join(dir, name), data); err != nil {
+		return errors.Trace(err)
+	}
+	atomic.AddInt64(&s.count, 1)
+	return nil
+}
+
+func (s *fileSink) CheckpointTs() uint64 {
+	return atomic.LoadUint64(&s.checkpointTs)
+}
+
+func (s *fileSink) Run(ctx context.Context) error {
+	defer s.closeWriters()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.globalForwardCh:
+		}
+		globalResolvedTs := atomic.LoadUint64(&s.globalResolvedTs)
+		if globalResolvedTs == atomic.LoadUint64(&s.checkpointTs) {
+			continue
+		}
+		resolvedRowsMap, err := s.resolvedRows(ctx, globalResolvedTs)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err := s.writeRows(globalResolvedTs, resolvedRowsMap); err != nil {
+			return errors.Trace(err)
+		}
+		atomic.StoreUint64(&s.checkpointTs, globalResolvedTs)
+	}
+}
+
+// writeRows appends the resolved rows to the change files of the tables, and
+// rotates the files which are large enough or cover a long enough time.
+func (s *fileSink) writeRows(resolvedTs uint64, rowsMap map[string][]*model.RowChangedEvent) error {
+	for key, rows := range rowsMap {
+		w, ok := s.writers[key]
+		if !ok {
+			var err error
+			w, err = newTableFileWriter(s.tableDir(rows[0].Schema, rows[0].Table), s.encoder)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			s.writers[key] = w
+		}
+		for _, row := range rows {
+			if err := w.write(row); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		atomic.AddInt64(&s.count, int64(len(rows)))
+	}
+	for _, w := range s.writers {
+		if err := w.flush(); err != nil {
+			return errors.Trace(err)
+		}
+		if w.shouldRotate(resolvedTs, s.maxFileSize, s.rotateInterval) {
+			if err := w.rotate(); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *fileSink) closeWriters() {
+	for _, w := range s.writers {
+		if err := w.rotate(); err != nil {
+			log.Warn("rotate change file failed", zap.String("dir", w.dir), zap.Error(err))
+		}
+	}
+}
+
+func (s *fileSink) tableDir(schema, table string) string {
+	if table == "" {
+		return filepath.Join(s.dir, url.PathEscape(schema))
+	}
+	return filepath.Join(s.dir, url.PathEscape(schema), url.PathEscape(table))
+}
+
+// tableFileWriter appends the rows of a table to the active change file
+type tableFileWriter struct {
+	dir     string
+	encoder fileEncoder
+
+	file   *os.File
+	writer *bufio.Writer
+	// path is the current path of the active change file
+	path    string
+	header  []byte
+	size    int64
+	firstTs uint64
+	lastTs  uint64
+}
+
+func newTableFileWriter(dir string, encoder fileEncoder) (*tableFileWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Annotatef(err, "create directory %s", dir)
+	}
+	// the change files left by the last run are complete, since the changes
+	// after them will be written to the new files.
+	leftovers, err := filepath.Glob(filepath.Join(dir, "cdc-*"+fileTmpSuffix))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, path := range leftovers {
+		target, err := uniqueChangeFilePath(strings.TrimSuffix(path, fileTmpSuffix))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := os.Rename(path, target); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return &tableFileWriter{dir: dir, encoder: encoder}, nil
+}
+
+func (w *tableFileWriter) changeFilePath(lastTs uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("cdc-%d-%d%s", w.firstTs, lastTs, w.encoder.Extension()))
+}
+
+func (w *tableFileWriter) write(row *model.RowChangedEvent) error {
+	header, err := w.encoder.RowHeader(row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if w.file != nil && !bytes.Equal(header, w.header) {
+		if err := w.rotate(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if w.file == nil {
+		w.firstTs = row.Ts
+		w.lastTs = row.Ts
+		w.path = w.changeFilePath(row.Ts) + fileTmpSuffix
+		w.file, err = os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		w.writer = bufio.NewWriter(w.file)
+		w.header = header
+		w.size = 0
+		if err := w.append(header); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	data, err := w.encoder.EncodeRow(row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	w.lastTs = row.Ts
+	return w.append(data)
+}
+
+func (w *tableFileWriter) append(data []byte) error {
+	n, err := w.writer.Write(data)
+	w.size += int64(n)
+	return errors.Trace(err)
+}
+
+// flush syncs the written rows to the disk, and renames the active change
+// file with the commit ts range of the rows in it.
+func (w *tableFileWriter) flush() error {
+	if w.file == nil {
+		return nil
+	}
+	if err := w.writer.Flush(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return errors.Trace(err)
+	}
+	path := w.changeFilePath(w.lastTs) + fileTmpSuffix
+	if path != w.path {
+		if err := os.Rename(w.path, path); err != nil {
+			return errors.Trace(err)
+		}
+		w.path = path
+	}
+	return nil
+}
+
+func (w *tableFileWriter) shouldRotate(resolvedTs uint64, maxFileSize int64, rotateInterval time.Duration) bool {
+	if w.file == nil {
+		return false
+	}
+	if w.size >= maxFileSize {
+		return true
+	}
+	elapsed := oracle.ExtractPhysical(resolvedTs) - oracle.ExtractPhysical(w.firstTs)
+	return time.Duration(elapsed)*time.Millisecond >= rotateInterval
+}
+
+// rotate closes the active change file and removes the `.tmp` suffix
+func (w *tableFileWriter) rotate() error {
+	if w.file == nil {
+		return nil
+	}
+	if err := w.flush(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := w.file.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	target, err := uniqueChangeFilePath(strings.TrimSuffix(w.path, fileTmpSuffix))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.Rename(w.path, target); err != nil {
+		return errors.Trace(err)
+	}
+	w.file = nil
+	w.writer = nil
+	w.path = ""
+	return nil
+}
+
+// uniqueChangeFilePath returns the path if no file exists there, otherwise a
+// sequence is appended to the name to avoid overwriting the existing file.
+func uniqueChangeFilePath(path string) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for seq := 0; ; seq++ {
+		target := path
+		if seq > 0 {
+			target = fmt.Sprintf("%s-%d%s", base, seq, ext)
+		}
+		_, err := os.Lstat(target)
+		if os.IsNotExist(err) {
+			return target, nil
+		}
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+	}
+}
+
+// writeFileAtomic writes the data to a temporary file and renames it to the path
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + fileTmpSuffix
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Rename(tmpPath, path))
+}
@@ -0,0 +1,206 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+// The formats of the files written by the file sink
+const (
+	fileFormatJSON = "json"
+	fileFormatCSV  = "csv"
+)
+
+// csvNull represents the NULL value in the csv files, the same as `LOAD DATA` of MySQL
+const csvNull = `\N`
+
+// fileEncoder encodes the events to the lines written to the change files
+type fileEncoder interface {
+	// Extension returns the extension of the files
+	Extension() string
+	// RowHeader returns the header line of the file the row is written to, the
+	// file is rotated when the header changes. nil means no header is needed.
+	RowHeader(row *model.RowChangedEvent) ([]byte, error)
+	// EncodeRow encodes the row changed event to a line
+	EncodeRow(row *model.RowChangedEvent) ([]byte, error)
+	// EncodeDDL encodes the DDL event to the whole content of a DDL file
+	EncodeDDL(ddl *model.DDLEvent) ([]byte, error)
+}
+
+func newFileEncoder(format string) (fileEncoder, error) {
+	switch strings.ToLower(format) {
+	case "", fileFormatJSON:
+		return jsonFileEncoder{}, nil
+	case fileFormatCSV:
+		return csvFileEncoder{}, nil
+	default:
+		return nil, errors.Errorf("the file format (%s) is not supported", format)
+	}
+}
+
+func dmlTypeString(tp model.DMLType) string {
+	switch tp {
+	case model.InsertDMLType:
+		return "insert"
+	case model.UpdateDMLType:
+		return "update"
+	case model.DeleteDMLType:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+type fileRowEvent struct {
+	CommitTs   uint64                   `json:"commit-ts"`
+	StartTs    uint64                   `json:"start-ts"`
+	Type       string                   `json:"type"`
+	Schema     string                   `json:"schema"`
+	Table      string                   `json:"table"`
+	Columns    map[string]*model.Column `json:"columns,omitempty"`
+	PreColumns map[string]*model.Column `json:"pre-columns,omitempty"`
+}
+
+type fileDDLEvent struct {
+	CommitTs uint64 `json:"commit-ts"`
+	Schema   string `json:"schema"`
+	Table    string `json:"table"`
+	Query    string `json:"query"`
+}
+
+// jsonFileEncoder encodes each event to a line of JSON object
+type jsonFileEncoder struct{}
+
+func (jsonFileEncoder) Extension() string {
+	return ".json"
+}
+
+func (jsonFileEncoder) RowHeader(row *model.RowChangedEvent) ([]byte, error) {
+	return nil, nil
+}
+
+func (jsonFileEncoder) EncodeRow(row *model.RowChangedEvent) ([]byte, error) {
+	data, err := json.Marshal(&fileRowEvent{
+		CommitTs:   row.Ts,
+		StartTs:    row.StartTs,
+		Type:       dmlTypeString(row.Type),
+		Schema:     row.Schema,
+		Table:      row.Table,
+		Columns:    row.Columns,
+		PreColumns: row.PreColumns,
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return append(data, '\n'), nil
+}
+
+func (jsonFileEncoder) EncodeDDL(ddl *model.DDLEvent) ([]byte, error) {
+	data, err := json.Marshal(&fileDDLEvent{
+		CommitTs: ddl.Ts,
+		Schema:   ddl.Schema,
+		Table:    ddl.Table,
+		Query:    ddl.Query,
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return append(data, '\n'), nil
+}
+
+// csvFileEncoder encodes each row to a csv record of the operation, commit ts
+// and the column values ordered by the column names in the header. The values
+// before update are not written, and the values of the deleted row are written.
+type csvFileEncoder struct{}
+
+func (csvFileEncoder) Extension() string {
+	return ".csv"
+}
+
+func csvColumns(row *model.RowChangedEvent) map[string]*model.Column {
+	if row.IsDelete() {
+		return row.PreColumns
+	}
+	return row.Columns
+}
+
+func sortedColumnNames(cols map[string]*model.Column) []string {
+	names := make([]string, 0, len(cols))
+	for name := range cols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func encodeCSVRecord(record []string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	if err := w.Write(record); err != nil {
+		return nil, errors.Trace(err)
+	}
+	w.Flush()
+	return buf.Bytes(), errors.Trace(w.Error())
+}
+
+func (csvFileEncoder) RowHeader(row *model.RowChangedEvent) ([]byte, error) {
+	header := append([]string{"op", "commit-ts"}, sortedColumnNames(csvColumns(row))...)
+	return encodeCSVRecord(header)
+}
+
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return csvNull
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func (csvFileEncoder) EncodeRow(row *model.RowChangedEvent) ([]byte, error) {
+	cols := csvColumns(row)
+	names := sortedColumnNames(cols)
+	record := make([]string, 0, len(names)+2)
+	record = append(record, strings.ToUpper(dmlTypeString(row.Type)[:1]), strconv.FormatUint(row.Ts, 10))
+	for _, name := range names {
+		record = append(record, formatCSVValue(cols[name].Value))
+	}
+	return encodeCSVRecord(record)
+}
+
+func (csvFileEncoder) EncodeDDL(ddl *model.DDLEvent) ([]byte, error) {
+	header, err := encodeCSVRecord([]string{"commit-ts", "query"})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	record, err := encodeCSVRecord([]string{strconv.FormatUint(ddl.Ts, 10), ddl.Query})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return append(header, record...), nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+)
+
+type fileSinkSuite struct{}
+
+var _ = check.Suite(&fileSinkSuite{})
+
+func newFileSinkForTest(c *check.C, query string) *fileSink {
+	filter, err := util.NewFilter(&util.ReplicaConfig{})
+	c.Assert(err, check.IsNil)
+	sinkURI, err := url.Parse("file://" + c.MkDir() + "?" + query)
+	c.Assert(err, check.IsNil)
+	s, err := newFileSink(sinkURI, filter, nil)
+	c.Assert(err, check.IsNil)
+	return s
+}
+
+func (s *fileSinkSuite) TestCSVEncoder(c *check.C) {
+	encoder, err := newFileEncoder("csv")
+	c.Assert(err, check.IsNil)
+	row := &model.RowChangedEvent{
+		Ts:   10,
+		Type: model.UpdateDMLType,
+		Columns: map[string]*model.Column{
+			"name": {Value: []byte("a,b")},
+			"id":   {Value: int64(1)},
+			"note": {Value: nil},
+		},
+		PreColumns: map[string]*model.Column{
+			"name": {Value: []byte("a")},
+		},
+	}
+	header, err := encoder.RowHeader(row)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(header), check.Equals, "op,commit-ts,id,name,note\n")
+	data, err := encoder.EncodeRow(row)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "U,10,1,\"a,b\",\\N\n")
+
+	_, err = newFileEncoder("xml")
+	c.Assert(err, check.ErrorMatches, ".*not supported.*")
+}
+
+func (s *fileSinkSuite) TestWriteAndRotate(c *check.C) {
+	sink := newFileSinkForTest(c, "format=json&max-file-size=100")
+	rows := map[string][]*model.RowChangedEvent{
+		"`test`.`t`": {
+			{Ts: 10, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: map[string]*model.Column{"id": {Value: 1}}},
+			{Ts: 11, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: map[string]*model.Column{"id": {Value: 2}}},
+		},
+	}
+	c.Assert(sink.writeRows(11, rows), check.IsNil)
+	// the file exceeds the max size and is rotated
+	files, err := filepath.Glob(filepath.Join(sink.dir, "test", "t", "*"))
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.DeepEquals, []string{filepath.Join(sink.dir, "test", "t", "cdc-10-11.json")})
+	data, err := ioutil.ReadFile(files[0])
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Matches, `\{"commit-ts":10,.*"type":"insert".*\}\n\{"commit-ts":11,.*\}\n`)
+
+	// the active file is renamed with the commit ts range after each flush
+	sink.maxFileSize = defaultMaxFileSize
+	rows["`test`.`t`"] = []*model.RowChangedEvent{
+		{Ts: 12, Schema: "test", Table: "t", Type: model.DeleteDMLType, PreColumns: map[string]*model.Column{"id": {Value: 1}}},
+	}
+	c.Assert(sink.writeRows(12, rows), check.IsNil)
+	_, err = ioutil.ReadFile(filepath.Join(sink.dir, "test", "t", "cdc-12-12.json.tmp"))
+	c.Assert(err, check.IsNil)
+
+	// the file is rotated by the resolved ts
+	resolvedTs := oracle.ComposeTS(oracle.ExtractPhysical(12)+int64(defaultRotateInterval/1e6), 0)
+	c.Assert(sink.writeRows(resolvedTs, nil), check.IsNil)
+	_, err = ioutil.ReadFile(filepath.Join(sink.dir, "test", "t", "cdc-12-12.json"))
+	c.Assert(err, check.IsNil)
+}
+
+func (s *fileSinkSuite) TestLeftoverFiles(c *check.C) {
+	sink := newFileSinkForTest(c, "")
+	dir := sink.tableDir("test", "t")
+	w, err := newTableFileWriter(dir, sink.encoder)
+	c.Assert(err, check.IsNil)
+	c.Assert(w.write(&model.RowChangedEvent{Ts: 5, Schema: "test", Table: "t"}), check.IsNil)
+	c.Assert(w.flush(), check.IsNil)
+
+	// the writer of the new run finishes the files left by the last run
+	_, err = newTableFileWriter(dir, sink.encoder)
+	c.Assert(err, check.IsNil)
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.DeepEquals, []string{filepath.Join(dir, "cdc-5-5.json")})
+}
+
+func (s *fileSinkSuite) TestDDLAndCheckpoint(c *check.C) {
+	ctx := context.Background()
+	sink := newFileSinkForTest(c, "format=csv")
+	err := sink.EmitDDLEvent(ctx, &model.DDLEvent{Ts: 20, Schema: "test", Table: "t", Query: "alter table t add column a int"})
+	c.Assert(err, check.IsNil)
+	data, err := ioutil.ReadFile(filepath.Join(sink.dir, "test", "t", "ddl-20.csv"))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "commit-ts,query\n20,alter table t add column a int\n")
+
+	err = sink.EmitDDLEvent(ctx, &model.DDLEvent{Ts: 21, Schema: "test2", Query: "create database test2"})
+	c.Assert(err, check.IsNil)
+	_, err = ioutil.ReadFile(filepath.Join(sink.dir, "test2", "ddl-21.csv"))
+	c.Assert(err, check.IsNil)
+
+	c.Assert(sink.EmitCheckpointEvent(ctx, 30), check.IsNil)
+	data, err = ioutil.ReadFile(filepath.Join(sink.dir, fileCheckpointName))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, `{"checkpoint-ts":30}`)
+}
+
+func (s *fileSinkSuite) TestHeaderChangedInSameTs(c *check.C) {
+	sink := newFileSinkForTest(c, "format=csv")
+	rows := map[string][]*model.RowChangedEvent{
+		"`test`.`t`": {
+			{Ts: 10, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: map[string]*model.Column{"id": {Value: 1}}},
+			{Ts: 10, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: map[string]*model.Column{"id": {Value: 2}, "name": {Value: "a"}}},
+		},
+	}
+	c.Assert(sink.writeRows(10, rows), check.IsNil)
+	sink.closeWriters()
+
+	// the file of the new header doesn't overwrite the previous one
+	dir := sink.tableDir("test", "t")
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.DeepEquals, []string{filepath.Join(dir, "cdc-10-10-1.csv"), filepath.Join(dir, "cdc-10-10.csv")})
+	data, err := ioutil.ReadFile(filepath.Join(dir, "cdc-10-10.csv"))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "op,commit-ts,id\nI,10,1\n")
+	data, err = ioutil.ReadFile(filepath.Join(dir, "cdc-10-10-1.csv"))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "op,commit-ts,id,name\nI,10,2,a\n")
+}
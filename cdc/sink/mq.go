@@ -2,11 +2,11 @@ package sink
 
 import (
 	"context"
-	"encoding/json"
-	"hash/crc32"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -16,13 +16,34 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/cdc/sink/dispatcher"
 	"github.com/pingcap/ticdc/cdc/sink/mqProducer"
 )
 
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
 type mqSink struct {
-	mqProducer   mqProducer.Producer
-	partitionNum int32
+	mqProducer      mqProducer.Producer
+	topicDispatcher *dispatcher.TopicDispatcher
+	encoder         codec.EventEncoder
+	replicaConfig   *util.ReplicaConfig
+
+	mu sync.Mutex
+	// dispatchers are the partition dispatchers of the topics
+	dispatchers map[string]dispatcher.Dispatcher
+	// tables are the tables of the changefeed told by the owner, the
+	// checkpoints are sent to the topics of them.
+	tables []entry.TableName
+
+	// batches are the pending batches of the partitions, if the encoder packs
+	// the events in batches.
+	batches map[topicPartition]codec.MessageBatch
 
 	sinkCheckpointTsCh chan uint64
 	globalResolvedTs   uint64
@@ -34,16 +55,109 @@ type mqSink struct {
 	count int64
 }
 
-func newMqSink(mqProducer mqProducer.Producer, filter *util.Filter, opts map[string]string) *mqSink {
-	partitionNum := mqProducer.GetPartitionNum()
+func newMqSink(mqProducer mqProducer.Producer, filter *util.Filter, encoder codec.EventEncoder, topic string, config *util.ReplicaConfig, opts map[string]string) (*mqSink, error) {
+	topicDispatcher, err := dispatcher.NewTopicDispatcher(config, topic)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	changefeedID := opts[OptChangefeedID]
-	return &mqSink{
+	k := &mqSink{
 		mqProducer:         mqProducer,
-		partitionNum:       partitionNum,
+		topicDispatcher:    topicDispatcher,
+		encoder:            encoder,
+		replicaConfig:      config,
+		dispatchers:        make(map[string]dispatcher.Dispatcher),
+		batches:            make(map[topicPartition]codec.MessageBatch),
 		sinkCheckpointTsCh: make(chan uint64, 128),
 		filter:             filter,
 		changefeedID:       changefeedID,
 	}
+	// check the topic of sink-uri at the beginning, other topics are checked
+	// when the events are sent to them.
+	if topic, ok := topicDispatcher.StaticTopic(); ok {
+		if _, err := k.getDispatcher(topic); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return k, nil
+}
+
+// getDispatcher returns the partition dispatcher of the topic
+func (k *mqSink) getDispatcher(topic string) (dispatcher.Dispatcher, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if d, ok := k.dispatchers[topic]; ok {
+		return d, nil
+	}
+	partitionNum, err := k.mqProducer.GetPartitionNum(topic)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	d, err := dispatcher.NewDispatcher(k.replicaConfig, partitionNum)
+	if err != nil {
+		return nil, errors.Annotatef(err, "create the dispatcher of topic %s", topic)
+	}
+	k.dispatchers[topic] = d
+	return d, nil
+}
+
+// UpdateTables implements the TableAwareSink interface
+func (k *mqSink) UpdateTables(tables []entry.TableName) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.tables = tables
+}
+
+// activeTopics returns the topics of the tables in the schema, or of all the
+// tables if the schema is empty. The topic of sink-uri is always active.
+func (k *mqSink) activeTopics(schema string) ([]string, error) {
+	topics := make(map[string]struct{})
+	if topic, ok := k.topicDispatcher.StaticTopic(); ok {
+		topics[topic] = struct{}{}
+	}
+	if !k.topicDispatcher.IsSingleTopic() {
+		k.mu.Lock()
+		tables := k.tables
+		k.mu.Unlock()
+		for _, table := range tables {
+			if schema != "" && table.Schema != schema {
+				continue
+			}
+			topic, err := k.topicDispatcher.Topic(table.Schema, table.Table)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			topics[topic] = struct{}{}
+		}
+	}
+	result := make([]string, 0, len(topics))
+	for topic := range topics {
+		result = append(result, topic)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// broadcastMessage sends the message to all the partitions of the topics, and
+// waits for the acks.
+func (k *mqSink) broadcastMessage(ctx context.Context, topics []string, msg *codec.MQMessage) error {
+	// the message is sent as a batch of itself, so all the messages are batches
+	if encoder, ok := k.encoder.(codec.BatchEventEncoder); ok {
+		batch := encoder.NewBatch()
+		batch.Append(msg)
+		var err error
+		msg, err = batch.Build()
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, topic := range topics {
+		err := k.mqProducer.BroadcastMessage(ctx, topic, msg.Key, msg.Value)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(k.mqProducer.Flush(ctx))
 }
 
 func (k *mqSink) EmitResolvedEvent(ctx context.Context, ts uint64) error {
@@ -52,15 +166,19 @@ func (k *mqSink) EmitResolvedEvent(ctx context.Context, ts uint64) error {
 }
 
 func (k *mqSink) EmitCheckpointEvent(ctx context.Context, ts uint64) error {
-	keyByte, err := model.NewResolvedMessage(ts).Encode()
+	msg, err := k.encoder.EncodeResolvedEvent(ts)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	err = k.mqProducer.BroadcastMessage(ctx, keyByte, nil)
+	// some protocols, like canal, have no resolved message
+	if msg == nil {
+		return nil
+	}
+	topics, err := k.activeTopics("")
 	if err != nil {
 		return errors.Trace(err)
 	}
-	return nil
+	return errors.Trace(k.broadcastMessage(ctx, topics, msg))
 }
 
 func (k *mqSink) EmitRowChangedEvent(ctx context.Context, rows ...*model.RowChangedEvent) error {
@@ -74,26 +192,35 @@ func (k *mqSink) EmitRowChangedEvent(ctx context.Context, rows ...*model.RowChan
 			log.Info("Row changed event ignored", zap.Uint64("ts", row.Ts))
 			continue
 		}
-		partition := k.calPartition(row)
-		key, value := row.ToMqMessage()
-		keyByte, err := key.Encode()
+		topic, err := k.topicDispatcher.Topic(row.Schema, row.Table)
 		if err != nil {
 			return errors.Trace(err)
 		}
-		valueByte, err := value.Encode()
+		d, err := k.getDispatcher(topic)
 		if err != nil {
 			return errors.Trace(err)
 		}
-		err = k.mqProducer.SendMessage(ctx, keyByte, valueByte, partition)
+		partition := d.Dispatch(row)
+		msg, err := k.encoder.EncodeRowChangedEvent(row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		err = k.sendRowMessage(ctx, topicPartition{topic: topic, partition: partition}, msg)
 		if err != nil {
-			log.Error("send message failed", zap.ByteStrings("row", [][]byte{keyByte, valueByte}), zap.Int32("partition", partition))
 			return errors.Trace(err)
 		}
 		atomic.AddInt64(&k.count, 1)
+		if err := k.emitTombstone(ctx, topic, row, partition); err != nil {
+			return errors.Trace(err)
+		}
 	}
 	if sinkCheckpointTs == 0 {
 		return nil
 	}
+	// all the rows before the checkpoint must be sent
+	if err := k.flushBatches(ctx); err != nil {
+		return errors.Trace(err)
+	}
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -102,31 +229,75 @@ func (k *mqSink) EmitRowChangedEvent(ctx context.Context, rows ...*model.RowChan
 	return nil
 }
 
-func (k *mqSink) calPartition(row *model.RowChangedEvent) int32 {
-	hash := crc32.NewIEEE()
-	// distribute partition by table
-	_, err := hash.Write([]byte(row.Schema))
-	if err != nil {
-		log.Fatal("calculate hash of message key failed, please report a bug", zap.Error(err))
+// sendRowMessage sends the message of a row to the partition, or appends it to the
+// batch of the partition if the encoder packs the events in batches.
+func (k *mqSink) sendRowMessage(ctx context.Context, tp topicPartition, msg *codec.MQMessage) error {
+	encoder, ok := k.encoder.(codec.BatchEventEncoder)
+	if !ok {
+		return errors.Trace(k.sendMessage(ctx, tp, msg))
 	}
-	_, err = hash.Write([]byte(row.Table))
-	if err != nil {
-		log.Fatal("calculate hash of message key failed, please report a bug", zap.Error(err))
+	batch, ok := k.batches[tp]
+	if !ok {
+		batch = encoder.NewBatch()
+		k.batches[tp] = batch
+	}
+	if batch.Append(msg) {
+		return nil
 	}
+	// the batch is full
+	if err := k.sendBatch(ctx, tp, batch); err != nil {
+		return errors.Trace(err)
+	}
+	batch.Append(msg)
+	return nil
+}
 
-	if len(row.IndieMarkCol) > 0 {
-		// distribute partition by rowid or unique column value
-		value := row.Columns[row.IndieMarkCol].Value
-		b, err := json.Marshal(value)
-		if err != nil {
-			log.Fatal("calculate hash of message key failed, please report a bug", zap.Error(err))
+// flushBatches sends the pending batches of all the partitions
+func (k *mqSink) flushBatches(ctx context.Context) error {
+	for tp, batch := range k.batches {
+		if batch.Len() == 0 {
+			continue
 		}
-		_, err = hash.Write(b)
-		if err != nil {
-			log.Fatal("calculate hash of message key failed, please report a bug", zap.Error(err))
+		if err := k.sendBatch(ctx, tp, batch); err != nil {
+			return errors.Trace(err)
 		}
 	}
-	return int32(hash.Sum32() % uint32(k.partitionNum))
+	return nil
+}
+
+func (k *mqSink) sendBatch(ctx context.Context, tp topicPartition, batch codec.MessageBatch) error {
+	msg, err := batch.Build()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(k.sendMessage(ctx, tp, msg))
+}
+
+func (k *mqSink) sendMessage(ctx context.Context, tp topicPartition, msg *codec.MQMessage) error {
+	err := k.mqProducer.SendMessage(ctx, tp.topic, msg.Key, msg.Value, tp.partition)
+	if err != nil {
+		log.Error("send message failed", zap.ByteStrings("row", [][]byte{msg.Key, msg.Value}),
+			zap.String("topic", tp.topic), zap.Int32("partition", tp.partition))
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// emitTombstone sends the tombstone of the row to the partition of the row
+// if the encoder needs it.
+func (k *mqSink) emitTombstone(ctx context.Context, topic string, row *model.RowChangedEvent, partition int32) error {
+	encoder, ok := k.encoder.(codec.TombstoneEncoder)
+	if !ok || !row.IsDelete() {
+		return nil
+	}
+	msg, err := encoder.EncodeTombstone(row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if msg == nil {
+		return nil
+	}
+	return errors.Trace(k.sendRowMessage(ctx, topicPartition{topic: topic, partition: partition}, msg))
 }
 
 func (k *mqSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
@@ -138,16 +309,30 @@ func (k *mqSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
 		)
 		return nil
 	}
-	key, value := ddl.ToMqMessage()
-	keyByte, err := key.Encode()
+	msg, err := k.encoder.EncodeDDLEvent(ddl)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	valueByte, err := value.Encode()
-	if err != nil {
-		return errors.Trace(err)
+	// some protocols, like avro, send no message for DDLs
+	if msg == nil {
+		return nil
 	}
-	err = k.mqProducer.BroadcastMessage(ctx, keyByte, valueByte)
+	// the DDL of a table is sent to the topic of the table, and the DDL of a
+	// schema is sent to the topics of all the tables in the schema.
+	var topics []string
+	if ddl.Table != "" {
+		topic, err := k.topicDispatcher.Topic(ddl.Schema, ddl.Table)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		topics = []string{topic}
+	} else {
+		topics, err = k.activeTopics(ddl.Schema)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	err = k.broadcastMessage(ctx, topics, msg)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -160,17 +345,37 @@ func (k *mqSink) CheckpointTs() uint64 {
 }
 
 func (k *mqSink) Run(ctx context.Context) error {
+	defer func() {
+		err := k.mqProducer.Close()
+		if err != nil {
+			log.Error("close MQ Producer failed", zap.Error(err))
+		}
+	}()
 	for {
 		var sinkCheckpointTs uint64
 		select {
 		case <-ctx.Done():
-			err := k.mqProducer.Close()
-			if err != nil {
-				log.Error("close MQ Producer failed", zap.Error(err))
-			}
 			return ctx.Err()
 		case sinkCheckpointTs = <-k.sinkCheckpointTsCh:
 		}
+		// merge the pending checkpoints to flush the producer only once
+	drain:
+		for {
+			select {
+			case ts := <-k.sinkCheckpointTsCh:
+				sinkCheckpointTs = ts
+			default:
+				break drain
+			}
+		}
+		// the checkpoint can be advanced only after all the messages before it are acked
+		err := k.mqProducer.Flush(ctx)
+		if err != nil {
+			if errors.Cause(err) != context.Canceled {
+				log.Error("flush MQ Producer failed", zap.Error(err))
+			}
+			return errors.Trace(err)
+		}
 		globalResolvedTs := atomic.LoadUint64(&k.globalResolvedTs)
 		// when local resolvedTS is fallback, we will postpone to pushing global resolvedTS
 		// check if the global resolvedTS is postponed
@@ -209,7 +414,17 @@ func (k *mqSink) PrintStatus(ctx context.Context) error {
 	}
 }
 
-func newKafkaSaramaSink(sinkURI *url.URL, filter *util.Filter, opts map[string]string) (*mqSink, error) {
+// newMqEventEncoder creates the encoder of the `protocol` parameter of sink-uri
+func newMqEventEncoder(sinkURI *url.URL, opts map[string]string) (codec.EventEncoder, error) {
+	var protocol codec.Protocol
+	if err := protocol.FromString(sinkURI.Query().Get("protocol")); err != nil {
+		return nil, errors.Trace(err)
+	}
+	encoder, err := codec.NewEventEncoder(protocol, sinkURI.Query(), opts[OptCaptureID])
+	return encoder, errors.Trace(err)
+}
+
+func newKafkaSaramaSink(sinkURI *url.URL, filter *util.Filter, replicaConfig *util.ReplicaConfig, opts map[string]string) (*mqSink, error) {
 	config := mqProducer.DefaultKafkaConfig
 
 	scheme := strings.ToLower(sinkURI.Scheme)
@@ -248,12 +463,103 @@ func newKafkaSaramaSink(sinkURI *url.URL, filter *util.Filter, opts map[string]s
 		config.MaxMessageBytes = c
 	}
 
+	s = sinkURI.Query().Get("compression")
+	if s != "" {
+		config.Compression = s
+	}
+
+	security, err := mqProducer.NewKafkaSecurityConfig(sinkURI.Query())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	config.Security = security
+
+	encoder, err := newMqEventEncoder(sinkURI, opts)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	// the topic can be an expression like `{schema}_{table}`, which sends the
+	// events of each table to its own topic.
 	topic := strings.TrimFunc(sinkURI.Path, func(r rune) bool {
 		return r == '/'
 	})
-	producer, err := mqProducer.NewKafkaSaramaProducer(sinkURI.Host, topic, config)
+	producer, err := mqProducer.NewKafkaSaramaProducer(sinkURI.Host, config)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	sink, err := newMqSink(producer, filter, encoder, topic, replicaConfig, opts)
 	if err != nil {
+		if closeErr := producer.Close(); closeErr != nil {
+			log.Warn("close MQ Producer failed", zap.Error(closeErr))
+		}
+		return nil, errors.Trace(err)
+	}
+	return sink, nil
+}
+
+func newPulsarSink(sinkURI *url.URL, filter *util.Filter, replicaConfig *util.ReplicaConfig, opts map[string]string) (*mqSink, error) {
+	config := mqProducer.DefaultPulsarConfig
+
+	scheme := strings.ToLower(sinkURI.Scheme)
+	if scheme != "pulsar" && scheme != "pulsar+ssl" {
+		return nil, errors.New("can not create MQ sink with unsupported scheme")
+	}
+	s := sinkURI.Query().Get("partition-num")
+	if s != "" {
+		c, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		config.PartitionNum = int32(c)
+	}
+
+	s = sinkURI.Query().Get("operation-timeout")
+	if s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		config.OperationTimeout = d
+	}
+
+	s = sinkURI.Query().Get("connection-timeout")
+	if s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		config.ConnectionTimeout = d
+	}
+
+	encoder, err := newMqEventEncoder(sinkURI, opts)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	// the topic can be a short name like `topic`, or a full name like
+	// `persistent://tenant/namespace/topic` escaped as the path of the sink-uri.
+	topic := strings.TrimFunc(sinkURI.Path, func(r rune) bool {
+		return r == '/'
+	})
+	topicDispatcher, err := dispatcher.NewTopicDispatcher(replicaConfig, topic)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !topicDispatcher.IsSingleTopic() {
+		return nil, errors.New("the topic expressions and the topic rules are not supported by the pulsar sink")
+	}
+	serviceURL := scheme + "://" + sinkURI.Host
+	producer, err := mqProducer.NewPulsarProducer(serviceURL, topic, config)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	sink, err := newMqSink(producer, filter, encoder, topic, replicaConfig, opts)
+	if err != nil {
+		if closeErr := producer.Close(); closeErr != nil {
+			log.Warn("close MQ Producer failed", zap.Error(closeErr))
+		}
 		return nil, errors.Trace(err)
 	}
-	return newMqSink(producer, filter, opts), nil
+	return sink, nil
 }
@@ -0,0 +1,271 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqConsumer
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/pkg/util"
+	"go.uber.org/zap"
+)
+
+type partitionSink struct {
+	sink.Sink
+	resolvedTs uint64
+}
+
+// Consumer replays the messages of the open protocol consumed from the
+// partitions of a MQ topic into the downstream sinks, the rows of each
+// partition are written by a sink of its own, and the DDLs are executed
+// once all the sinks have caught up with them.
+type Consumer struct {
+	ddlList          []*model.DDLEvent
+	maxDDLReceivedTs uint64
+	ddlListMu        sync.Mutex
+
+	sinks   []*partitionSink
+	sinksMu sync.Mutex
+
+	ddlSink sink.Sink
+
+	globalResolvedTs uint64
+}
+
+// NewConsumer creates a new Consumer writing the messages of partitionNum partitions to the downstream
+func NewConsumer(downstreamURI string, partitionNum int32) (*Consumer, error) {
+	// TODO support filter in downstream sink
+	config := new(util.ReplicaConfig)
+	filter, err := util.NewFilter(config)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c := new(Consumer)
+	c.sinks = make([]*partitionSink, partitionNum)
+	for i := 0; i < int(partitionNum); i++ {
+		s, err := sink.NewSink(downstreamURI, filter, config, nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		c.sinks[i] = &partitionSink{Sink: s}
+	}
+	ddlSink, err := sink.NewSink(downstreamURI, filter, config, nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c.ddlSink = ddlSink
+	return c, nil
+}
+
+// ConsumeMessage decodes a message received from the partition and replays the events in it
+func (c *Consumer) ConsumeMessage(ctx context.Context, partition int32, key, value []byte) error {
+	c.sinksMu.Lock()
+	if partition < 0 || int(partition) >= len(c.sinks) {
+		c.sinksMu.Unlock()
+		return errors.Errorf("unexpected partition %d, the partition number is %d", partition, len(c.sinks))
+	}
+	sink := c.sinks[partition]
+	c.sinksMu.Unlock()
+
+	log.Debug("Message received", zap.Int32("partition", partition), zap.ByteString("key", key), zap.ByteString("value", value))
+	// a message of the open protocol is a batch of events, or a single event
+	decoder, err := codec.NewOpenProtocolEventDecoder(key, value)
+	if err != nil {
+		return errors.Annotate(err, "decode message key failed")
+	}
+
+	for {
+		tp, hasNext, err := decoder.HasNext()
+		if err != nil {
+			return errors.Annotate(err, "decode message key failed")
+		}
+		if !hasNext {
+			return nil
+		}
+
+		switch tp {
+		case model.MqMessageTypeDDL:
+			ddl, err := decoder.NextDDLEvent()
+			if err != nil {
+				return errors.Annotate(err, "decode message value failed")
+			}
+			c.appendDDL(ddl)
+		case model.MqMessageTypeRow:
+			row, err := decoder.NextRowChangedEvent()
+			if err != nil {
+				return errors.Annotate(err, "decode message value failed")
+			}
+			globalResolvedTs := atomic.LoadUint64(&c.globalResolvedTs)
+			sinkResolvedTs := atomic.LoadUint64(&sink.resolvedTs)
+			if row.Ts <= globalResolvedTs || row.Ts <= sinkResolvedTs {
+				log.Info("filter fallback row", zap.ByteString("row", key),
+					zap.Uint64("globalResolvedTs", globalResolvedTs),
+					zap.Uint64("sinkResolvedTs", sinkResolvedTs))
+				break
+			}
+			if err := sink.EmitRowChangedEvent(ctx, row); err != nil {
+				return errors.Trace(err)
+			}
+		case model.MqMessageTypeResolved:
+			ts, err := decoder.NextResolvedEvent()
+			if err != nil {
+				return errors.Annotate(err, "decode message key failed")
+			}
+			if err := sink.EmitRowChangedEvent(ctx, &model.RowChangedEvent{Ts: ts, Resolved: true}); err != nil {
+				return errors.Trace(err)
+			}
+			resolvedTs := atomic.LoadUint64(&sink.resolvedTs)
+			if resolvedTs < ts {
+				atomic.StoreUint64(&sink.resolvedTs, ts)
+			}
+		default:
+			return errors.Errorf("unknown message type %d", tp)
+		}
+	}
+}
+
+func (c *Consumer) appendDDL(ddl *model.DDLEvent) {
+	c.ddlListMu.Lock()
+	defer c.ddlListMu.Unlock()
+	if ddl.Ts <= c.maxDDLReceivedTs {
+		return
+	}
+	globalResolvedTs := atomic.LoadUint64(&c.globalResolvedTs)
+	if ddl.Ts <= globalResolvedTs {
+		log.Error("unexpected ddl job", zap.Uint64("ddlts", ddl.Ts), zap.Uint64("globalResolvedTs", globalResolvedTs))
+		return
+	}
+	c.ddlList = append(c.ddlList, ddl)
+	c.maxDDLReceivedTs = ddl.Ts
+}
+
+func (c *Consumer) getFrontDDL() *model.DDLEvent {
+	c.ddlListMu.Lock()
+	defer c.ddlListMu.Unlock()
+	if len(c.ddlList) > 0 {
+		return c.ddlList[0]
+	}
+	return nil
+}
+
+func (c *Consumer) popDDL() *model.DDLEvent {
+	c.ddlListMu.Lock()
+	defer c.ddlListMu.Unlock()
+	if len(c.ddlList) > 0 {
+		ddl := c.ddlList[0]
+		c.ddlList = c.ddlList[1:]
+		return ddl
+	}
+	return nil
+}
+
+func (c *Consumer) forEachSink(fn func(sink *partitionSink) error) error {
+	c.sinksMu.Lock()
+	defer c.sinksMu.Unlock()
+	for _, sink := range c.sinks {
+		if err := fn(sink); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// Run runs the sinks, executes the DDLs and forwards the global resolved ts to the sinks until the context is done
+func (c *Consumer) Run(ctx context.Context) error {
+	wg := &sync.WaitGroup{}
+	err := c.forEachSink(func(sink *partitionSink) error {
+		wg.Add(1)
+		go func() {
+			if err := sink.Run(ctx); err != nil && errors.Cause(err) != context.Canceled {
+				log.Fatal("sink running error", zap.Error(err))
+			}
+			wg.Done()
+		}()
+		return nil
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	var lastGlobalResolvedTs uint64
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		default:
+		}
+		time.Sleep(100 * time.Millisecond)
+		// handle ddl
+		globalCheckpointTs := uint64(math.MaxUint64)
+		err = c.forEachSink(func(sink *partitionSink) error {
+			checkpointTs := sink.CheckpointTs()
+			if checkpointTs < globalCheckpointTs {
+				globalCheckpointTs = checkpointTs
+			}
+			return nil
+		})
+		if err != nil {
+			return errors.Trace(err)
+		}
+		todoDDL := c.getFrontDDL()
+		if todoDDL != nil && globalCheckpointTs == todoDDL.Ts {
+			// execute ddl
+			err := c.ddlSink.EmitDDLEvent(ctx, todoDDL)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			c.popDDL()
+		}
+
+		//handle global resolvedTs
+		globalResolvedTs := uint64(math.MaxUint64)
+		err = c.forEachSink(func(sink *partitionSink) error {
+			resolvedTs := atomic.LoadUint64(&sink.resolvedTs)
+			if resolvedTs < globalResolvedTs {
+				globalResolvedTs = resolvedTs
+			}
+			return nil
+		})
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		todoDDL = c.getFrontDDL()
+		if todoDDL != nil && todoDDL.Ts < globalResolvedTs {
+			globalResolvedTs = todoDDL.Ts
+		}
+		if lastGlobalResolvedTs == globalResolvedTs {
+			continue
+		}
+		lastGlobalResolvedTs = globalResolvedTs
+		atomic.StoreUint64(&c.globalResolvedTs, globalResolvedTs)
+		log.Debug("update globalResolvedTs", zap.Uint64("ts", globalResolvedTs))
+
+		err = c.forEachSink(func(sink *partitionSink) error {
+			return sink.EmitResolvedEvent(ctx, globalResolvedTs)
+		})
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
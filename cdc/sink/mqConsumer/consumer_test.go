@@ -0,0 +1,62 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqConsumer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type consumerSuite struct{}
+
+var _ = check.Suite(&consumerSuite{})
+
+func (s *consumerSuite) TestConsumeMessage(c *check.C) {
+	ctx := context.Background()
+	consumer, err := NewConsumer("blackhole://", 2)
+	c.Assert(err, check.IsNil)
+	encoder := codec.NewJSONEventEncoder()
+
+	ddl, err := encoder.EncodeDDLEvent(&model.DDLEvent{Ts: 15, Schema: "test", Table: "t", Query: "create table t(id int primary key)"})
+	c.Assert(err, check.IsNil)
+	c.Assert(consumer.ConsumeMessage(ctx, 0, ddl.Key, ddl.Value), check.IsNil)
+	// the same DDL received from another partition is appended only once
+	c.Assert(consumer.ConsumeMessage(ctx, 1, ddl.Key, ddl.Value), check.IsNil)
+	c.Assert(consumer.ddlList, check.HasLen, 1)
+	c.Assert(consumer.getFrontDDL().Ts, check.Equals, uint64(15))
+
+	row, err := encoder.EncodeRowChangedEvent(&model.RowChangedEvent{
+		Ts: 10, Schema: "test", Table: "t", Type: model.InsertDMLType,
+		Columns: map[string]*model.Column{"id": {Type: mysql.TypeLong, WhereHandle: true, Value: int64(1)}},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(consumer.ConsumeMessage(ctx, 1, row.Key, row.Value), check.IsNil)
+
+	resolved, err := encoder.EncodeResolvedEvent(20)
+	c.Assert(err, check.IsNil)
+	c.Assert(consumer.ConsumeMessage(ctx, 1, resolved.Key, resolved.Value), check.IsNil)
+	c.Assert(consumer.sinks[0].resolvedTs, check.Equals, uint64(0))
+	c.Assert(consumer.sinks[1].resolvedTs, check.Equals, uint64(20))
+
+	// the messages of an unknown partition are rejected
+	err = consumer.ConsumeMessage(ctx, 2, resolved.Key, resolved.Value)
+	c.Assert(err, check.ErrorMatches, ".*unexpected partition 2.*")
+}
@@ -3,6 +3,8 @@ package mqProducer
 import (
 	"context"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Shopify/sarama"
@@ -18,6 +20,11 @@ type KafkaConfig struct {
 
 	Version         string
 	MaxMessageBytes int
+	// Compression is the compression codec of the producer, which is one of
+	// `none`, `gzip`, `snappy`, `lz4` and `zstd`.
+	Compression string
+	// Security is the SASL and TLS config, nil means neither is enabled
+	Security *KafkaSecurityConfig
 }
 
 // DefaultKafkaConfig is the default Kafka configuration
@@ -27,68 +34,147 @@ var DefaultKafkaConfig = KafkaConfig{
 	ReplicationFactor: 1,
 }
 
+// kafkaTopic tracks the in-flight messages of each partition of a topic
+type kafkaTopic struct {
+	partitionNum    int32
+	partitionOffset []kafkaPartition
+}
+
+// kafkaPartition tracks the in-flight messages of a partition, sent is the
+// number of messages handed to the producer, and acked is the number of them
+// acked by Kafka. The acks of a partition are in order, so all the messages of
+// a partition are acked if acked reaches sent.
+type kafkaPartition struct {
+	// mu keeps the sequences of the messages in the order they are handed to
+	// the producer
+	mu    sync.Mutex
+	sent  uint64
+	acked uint64
+}
+
+// ack advances acked to seq, acked never moves backwards
+func (p *kafkaPartition) ack(seq uint64) {
+	for {
+		acked := atomic.LoadUint64(&p.acked)
+		if seq <= acked || atomic.CompareAndSwapUint64(&p.acked, acked, seq) {
+			return
+		}
+	}
+}
+
 type kafkaSaramaProducer struct {
-	client       sarama.SyncProducer
-	topic        string
-	partitionNum int32
+	client sarama.AsyncProducer
+	admin  sarama.ClusterAdmin
+	config KafkaConfig
+
+	topicsMu sync.RWMutex
+	topics   map[string]*kafkaTopic
+
+	mu sync.Mutex
+	// ackedCh is closed and renewed whenever a message is acked or failed
+	ackedCh chan struct{}
+	// err is the first error of sending, the producer can't be used after it
+	err error
+
+	closeCh chan struct{}
 }
 
-// NewKafkaSaramaProducer creates a kafka sarama producer
-func NewKafkaSaramaProducer(address string, topic string, config KafkaConfig) (*kafkaSaramaProducer, error) {
+// NewKafkaSaramaProducer creates a kafka sarama producer, the topics are created
+// automatically when the messages are sent to them.
+func NewKafkaSaramaProducer(address string, config KafkaConfig) (*kafkaSaramaProducer, error) {
 	cfg, err := newSaramaConfig(config)
 	if err != nil {
 		return nil, err
 	}
-	client, err := sarama.NewSyncProducer(strings.Split(address, ","), cfg)
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
 
-	// get partition number or create topic automatically
 	admin, err := sarama.NewClusterAdmin(strings.Split(address, ","), cfg)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	topics, err := admin.ListTopics()
+	client, err := sarama.NewAsyncProducer(strings.Split(address, ","), cfg)
 	if err != nil {
+		if closeErr := admin.Close(); closeErr != nil {
+			log.Warn("close kafka cluster admin failed", zap.Error(closeErr))
+		}
 		return nil, errors.Trace(err)
 	}
-	partitionNum := config.PartitionNum
+	k := &kafkaSaramaProducer{
+		client:  client,
+		admin:   admin,
+		config:  config,
+		topics:  make(map[string]*kafkaTopic),
+		ackedCh: make(chan struct{}),
+		closeCh: make(chan struct{}),
+	}
+	go k.run()
+	return k, nil
+}
+
+// ensureTopic gets the partition number of the topic, or creates the topic
+// if it doesn't exist.
+func (k *kafkaSaramaProducer) ensureTopic(topic string) (int32, error) {
+	topics, err := k.admin.ListTopics()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	partitionNum := k.config.PartitionNum
 	topicDetail, exist := topics[topic]
 	if exist {
 		log.Info("get partition number of topic", zap.String("topic", topic), zap.Int32("partition_num", topicDetail.NumPartitions))
 		if partitionNum == 0 {
 			partitionNum = topicDetail.NumPartitions
 		} else if partitionNum < topicDetail.NumPartitions {
-			log.Warn("partition number assigned in sink-uri is less than that of topic")
+			log.Warn("partition number assigned in sink-uri is less than that of topic", zap.String("topic", topic))
 		} else if partitionNum > topicDetail.NumPartitions {
-			return nil, errors.Errorf("partition number(%d) assigned in sink-uri is more than that of topic(%d)", partitionNum, topicDetail.NumPartitions)
-		}
-	} else {
-		if partitionNum == 0 {
-			partitionNum = 4
-			log.Warn("topic not found and partition number is not specified, using default partition number", zap.String("topic", topic), zap.Int32("partition_num", partitionNum))
-		}
-		err := admin.CreateTopic(topic, &sarama.TopicDetail{
-			NumPartitions:     partitionNum,
-			ReplicationFactor: config.ReplicationFactor,
-		}, false)
-		log.Info("create a topic", zap.String("topic", topic), zap.Int32("partition_num", partitionNum), zap.Int16("replication_factor", config.ReplicationFactor))
-		if err != nil {
-			return nil, errors.Trace(err)
+			return 0, errors.Errorf("partition number(%d) assigned in sink-uri is more than that of topic %s(%d)", partitionNum, topic, topicDetail.NumPartitions)
 		}
+		return partitionNum, nil
+	}
+	if partitionNum == 0 {
+		partitionNum = 4
+		log.Warn("topic not found and partition number is not specified, using default partition number", zap.String("topic", topic), zap.Int32("partition_num", partitionNum))
+	}
+	err = k.admin.CreateTopic(topic, &sarama.TopicDetail{
+		NumPartitions:     partitionNum,
+		ReplicationFactor: k.config.ReplicationFactor,
+	}, false)
+	if topicErr, ok := err.(*sarama.TopicError); ok && topicErr.Err == sarama.ErrTopicAlreadyExists {
+		// the topic is created by another capture of the changefeed at the same
+		// time, with the same partition number.
+		log.Info("topic already exists", zap.String("topic", topic))
+		return partitionNum, nil
+	}
+	if err != nil {
+		return 0, errors.Trace(err)
 	}
+	log.Info("create a topic", zap.String("topic", topic), zap.Int32("partition_num", partitionNum), zap.Int16("replication_factor", k.config.ReplicationFactor))
+	return partitionNum, nil
+}
 
-	err = admin.Close()
+// getTopic returns the state of the topic, and creates the topic at the first time
+func (k *kafkaSaramaProducer) getTopic(topic string) (*kafkaTopic, error) {
+	k.topicsMu.RLock()
+	t, ok := k.topics[topic]
+	k.topicsMu.RUnlock()
+	if ok {
+		return t, nil
+	}
+
+	k.topicsMu.Lock()
+	defer k.topicsMu.Unlock()
+	if t, ok := k.topics[topic]; ok {
+		return t, nil
+	}
+	partitionNum, err := k.ensureTopic(topic)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-
-	return &kafkaSaramaProducer{
-		client:       client,
-		topic:        topic,
-		partitionNum: partitionNum,
-	}, nil
+	t = &kafkaTopic{
+		partitionNum:    partitionNum,
+		partitionOffset: make([]kafkaPartition, partitionNum),
+	}
+	k.topics[topic] = t
+	return t, nil
 }
 
 // NewSaramaConfig return the default config and set the according version and metrics
@@ -111,26 +197,124 @@ func newSaramaConfig(c KafkaConfig) (*sarama.Config, error) {
 	config.Producer.Partitioner = sarama.NewManualPartitioner
 	config.Producer.MaxMessageBytes = 1 << 30
 	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
 	config.Producer.RequiredAcks = sarama.WaitForAll
+	// only one request in flight for each broker keeps the messages and the acks
+	// of a partition in order, even if the requests are retried
+	config.Net.MaxOpenRequests = 1
 
 	config.Producer.Retry.Max = 10000
 	config.Producer.Retry.Backoff = 500 * time.Millisecond
+
+	switch strings.ToLower(strings.TrimSpace(c.Compression)) {
+	case "", "none":
+		config.Producer.Compression = sarama.CompressionNone
+	case "gzip":
+		config.Producer.Compression = sarama.CompressionGZIP
+	case "snappy":
+		config.Producer.Compression = sarama.CompressionSnappy
+	case "lz4":
+		config.Producer.Compression = sarama.CompressionLZ4
+	case "zstd":
+		// zstd is supported since Kafka 2.1.0, which is checked by sarama
+		config.Producer.Compression = sarama.CompressionZSTD
+	default:
+		return nil, errors.Errorf("the compression (%s) is not supported", c.Compression)
+	}
+
+	if c.Security != nil {
+		if err := c.Security.Apply(config); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
 	return config, err
 }
 
-func (k *kafkaSaramaProducer) SendMessage(ctx context.Context, key []byte, value []byte, partition int32) error {
-	_, _, err := k.client.SendMessage(&sarama.ProducerMessage{
-		Topic:     k.topic,
+// run receives the acks and the errors of the messages
+func (k *kafkaSaramaProducer) run() {
+	for {
+		select {
+		case <-k.closeCh:
+			return
+		case msg, ok := <-k.client.Successes():
+			if !ok {
+				return
+			}
+			k.topicsMu.RLock()
+			t := k.topics[msg.Topic]
+			k.topicsMu.RUnlock()
+			t.partitionOffset[msg.Partition].ack(msg.Metadata.(uint64))
+			k.notifyAcked(nil)
+		case err, ok := <-k.client.Errors():
+			if !ok {
+				return
+			}
+			log.Error("send message to kafka failed", zap.String("topic", err.Msg.Topic),
+				zap.Int32("partition", err.Msg.Partition), zap.Error(err.Err))
+			k.notifyAcked(err.Err)
+		}
+	}
+}
+
+// notifyAcked wakes up the waiters of the acks, and records the first error
+func (k *kafkaSaramaProducer) notifyAcked(err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err != nil && k.err == nil {
+		k.err = err
+	}
+	close(k.ackedCh)
+	k.ackedCh = make(chan struct{})
+}
+
+func (k *kafkaSaramaProducer) getState() (<-chan struct{}, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.ackedCh, k.err
+}
+
+func (k *kafkaSaramaProducer) SendMessage(ctx context.Context, topic string, key []byte, value []byte, partition int32) error {
+	if _, err := k.getState(); err != nil {
+		return errors.Trace(err)
+	}
+	t, err := k.getTopic(topic)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if partition < 0 || partition >= t.partitionNum {
+		return errors.Errorf("partition %d of topic %s is out of range", partition, topic)
+	}
+	msg := &sarama.ProducerMessage{
+		Topic:     topic,
 		Key:       sarama.ByteEncoder(key),
 		Value:     sarama.ByteEncoder(value),
 		Partition: partition,
-	})
-	return errors.Trace(err)
+	}
+	p := &t.partitionOffset[partition]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// the metadata is the sequence of the message in the partition, which is
+	// counted in sent only after the message is handed to the producer
+	seq := p.sent + 1
+	msg.Metadata = seq
+	select {
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	case <-k.closeCh:
+		return errors.New("the kafka producer is closed")
+	case k.client.Input() <- msg:
+	}
+	atomic.StoreUint64(&p.sent, seq)
+	return nil
 }
 
-func (k *kafkaSaramaProducer) BroadcastMessage(ctx context.Context, key []byte, value []byte) error {
-	for i := int32(0); i < k.partitionNum; i++ {
-		err := k.SendMessage(ctx, key, value, i)
+func (k *kafkaSaramaProducer) BroadcastMessage(ctx context.Context, topic string, key []byte, value []byte) error {
+	partitionNum, err := k.GetPartitionNum(topic)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for i := int32(0); i < partitionNum; i++ {
+		err := k.SendMessage(ctx, topic, key, value, i)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -138,10 +322,61 @@ func (k *kafkaSaramaProducer) BroadcastMessage(ctx context.Context, key []byte,
 	return nil
 }
 
-func (k *kafkaSaramaProducer) GetPartitionNum() int32 {
-	return k.partitionNum
+func (k *kafkaSaramaProducer) Flush(ctx context.Context) error {
+	type flushTarget struct {
+		topic   *kafkaTopic
+		targets []uint64
+	}
+	k.topicsMu.RLock()
+	flushTargets := make([]flushTarget, 0, len(k.topics))
+	for _, t := range k.topics {
+		targets := make([]uint64, t.partitionNum)
+		for i := range targets {
+			targets[i] = atomic.LoadUint64(&t.partitionOffset[i].sent)
+		}
+		flushTargets = append(flushTargets, flushTarget{topic: t, targets: targets})
+	}
+	k.topicsMu.RUnlock()
+	for {
+		ackedCh, err := k.getState()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		flushed := true
+	check:
+		for _, f := range flushTargets {
+			for i, target := range f.targets {
+				if atomic.LoadUint64(&f.topic.partitionOffset[i].acked) < target {
+					flushed = false
+					break check
+				}
+			}
+		}
+		if flushed {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-k.closeCh:
+			return errors.New("the kafka producer is closed")
+		case <-ackedCh:
+		}
+	}
+}
+
+func (k *kafkaSaramaProducer) GetPartitionNum(topic string) (int32, error) {
+	t, err := k.getTopic(topic)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return t.partitionNum, nil
 }
 
 func (k *kafkaSaramaProducer) Close() error {
+	close(k.closeCh)
+	if err := k.admin.Close(); err != nil {
+		log.Warn("close kafka cluster admin failed", zap.Error(err))
+	}
 	return k.client.Close()
 }
@@ -0,0 +1,154 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqProducer
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/pkg/security"
+)
+
+// KafkaSecurityConfig is the SASL and TLS config of connecting to Kafka. The
+// password is read from a file on the host of the capture, so it is not in the
+// sink-uri stored in etcd.
+type KafkaSecurityConfig struct {
+	// SASLMechanism is one of `PLAIN`, `SCRAM-SHA-256` and `SCRAM-SHA-512`,
+	// SASL is disabled if it is empty.
+	SASLMechanism    string
+	SASLUser         string
+	SASLPasswordFile string
+
+	// TLS is enabled if EnableTLS is true or the CA is set, the certificate of
+	// the server is verified by the system CAs if the CA is not set.
+	EnableTLS          bool
+	Credential         security.Credential
+	InsecureSkipVerify bool
+}
+
+// NewKafkaSecurityConfig parses the security config from the parameters of
+// the sink-uri, it returns nil if neither SASL nor TLS is enabled.
+func NewKafkaSecurityConfig(params url.Values) (*KafkaSecurityConfig, error) {
+	if params.Get("sasl-password") != "" {
+		return nil, errors.New("the SASL password can not be in the uri, please use sasl-password-file")
+	}
+	c := &KafkaSecurityConfig{
+		SASLMechanism:    strings.ToUpper(params.Get("sasl-mechanism")),
+		SASLUser:         params.Get("sasl-user"),
+		SASLPasswordFile: params.Get("sasl-password-file"),
+		Credential: security.Credential{
+			CAPath:   params.Get("ca"),
+			CertPath: params.Get("cert"),
+			KeyPath:  params.Get("key"),
+		},
+	}
+	for name, v := range map[string]*bool{
+		"enable-tls":           &c.EnableTLS,
+		"insecure-skip-verify": &c.InsecureSkipVerify,
+	} {
+		s := params.Get(name)
+		if s == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.Annotatef(err, "invalid %s of uri", name)
+		}
+		*v = b
+	}
+
+	switch c.SASLMechanism {
+	case "":
+		if c.SASLUser != "" || c.SASLPasswordFile != "" {
+			return nil, errors.New("the sasl-mechanism is required by SASL")
+		}
+	case sarama.SASLTypePlaintext, sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512:
+		if c.SASLUser == "" || c.SASLPasswordFile == "" {
+			return nil, errors.New("the sasl-user and sasl-password-file are required by SASL")
+		}
+	default:
+		return nil, errors.Errorf("the SASL mechanism (%s) is not supported", c.SASLMechanism)
+	}
+	if (c.Credential.CertPath == "") != (c.Credential.KeyPath == "") {
+		return nil, errors.New("the cert and the key of the client must be set together")
+	}
+	if c.Credential.CAPath != "" || c.Credential.CertPath != "" || c.InsecureSkipVerify {
+		c.EnableTLS = true
+	}
+	if c.SASLMechanism == "" && !c.EnableTLS {
+		return nil, nil
+	}
+	return c, nil
+}
+
+func (c *KafkaSecurityConfig) toTLSConfig() (*tls.Config, error) {
+	tlsCfg, err := c.Credential.ToTLSConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if tlsCfg == nil {
+		// the certificate of the server is verified by the system CAs
+		tlsCfg = &tls.Config{MinVersion: tls.VersionTLS12}
+		if c.Credential.CertPath != "" {
+			cert, err := tls.LoadX509KeyPair(c.Credential.CertPath, c.Credential.KeyPath)
+			if err != nil {
+				return nil, errors.Annotate(err, "load x509 key pair")
+			}
+			tlsCfg.Certificates = []tls.Certificate{cert}
+		}
+	}
+	tlsCfg.InsecureSkipVerify = c.InsecureSkipVerify
+	return tlsCfg, nil
+}
+
+// Apply sets the SASL and TLS config of sarama, the password is read from the
+// password file.
+func (c *KafkaSecurityConfig) Apply(config *sarama.Config) error {
+	if c.EnableTLS {
+		tlsCfg, err := c.toTLSConfig()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsCfg
+	}
+	if c.SASLMechanism == "" {
+		return nil
+	}
+	password, err := ioutil.ReadFile(c.SASLPasswordFile)
+	if err != nil {
+		return errors.Annotatef(err, "read SASL password file %s", c.SASLPasswordFile)
+	}
+	config.Net.SASL.Enable = true
+	config.Net.SASL.Handshake = true
+	config.Net.SASL.Mechanism = sarama.SASLMechanism(c.SASLMechanism)
+	config.Net.SASL.User = c.SASLUser
+	config.Net.SASL.Password = strings.TrimRight(string(password), "\r\n")
+	// the SaslAuthenticate request is supported since Kafka 1.0.0
+	if config.Version.IsAtLeast(sarama.V1_0_0_0) {
+		config.Net.SASL.Version = sarama.SASLHandshakeV1
+	}
+	switch c.SASLMechanism {
+	case sarama.SASLTypeSCRAMSHA256:
+		config.Net.SASL.SCRAMClientGeneratorFunc = scramSHA256ClientGenerator
+	case sarama.SASLTypeSCRAMSHA512:
+		config.Net.SASL.SCRAMClientGeneratorFunc = scramSHA512ClientGenerator
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqProducer
+
+import (
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+
+	"github.com/Shopify/sarama"
+	"github.com/pingcap/check"
+)
+
+type kafkaSecuritySuite struct{}
+
+var _ = check.Suite(&kafkaSecuritySuite{})
+
+func (s *kafkaSecuritySuite) TestParseSecurityConfig(c *check.C) {
+	config, err := NewKafkaSecurityConfig(url.Values{"partition-num": {"4"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(config, check.IsNil)
+
+	for _, tc := range []struct {
+		params url.Values
+		err    string
+	}{
+		{url.Values{"sasl-mechanism": {"plain"}, "sasl-user": {"cdc"}, "sasl-password": {"secret"}}, ".*can not be in the uri.*"},
+		{url.Values{"sasl-mechanism": {"plain"}, "sasl-user": {"cdc"}}, ".*required by SASL.*"},
+		{url.Values{"sasl-user": {"cdc"}, "sasl-password-file": {"/tmp/password"}}, ".*sasl-mechanism is required.*"},
+		{url.Values{"sasl-mechanism": {"GSSAPI"}}, ".*not supported.*"},
+		{url.Values{"cert": {"/tmp/cert.pem"}}, ".*must be set together.*"},
+		{url.Values{"enable-tls": {"yes"}}, ".*invalid enable-tls.*"},
+	} {
+		_, err := NewKafkaSecurityConfig(tc.params)
+		c.Assert(err, check.ErrorMatches, tc.err)
+	}
+}
+
+func (s *kafkaSecuritySuite) TestApplySASL(c *check.C) {
+	passwordFile := filepath.Join(c.MkDir(), "password")
+	err := ioutil.WriteFile(passwordFile, []byte("secret\n"), 0600)
+	c.Assert(err, check.IsNil)
+	security, err := NewKafkaSecurityConfig(url.Values{
+		"sasl-mechanism":     {"scram-sha-512"},
+		"sasl-user":          {"cdc"},
+		"sasl-password-file": {passwordFile},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(security.EnableTLS, check.IsFalse)
+
+	kafkaConfig := DefaultKafkaConfig
+	kafkaConfig.Security = security
+	config, err := newSaramaConfig(kafkaConfig)
+	c.Assert(err, check.IsNil)
+	c.Assert(config.Net.SASL.Enable, check.IsTrue)
+	c.Assert(config.Net.SASL.Mechanism, check.Equals, sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA512))
+	c.Assert(config.Net.SASL.User, check.Equals, "cdc")
+	c.Assert(config.Net.SASL.Password, check.Equals, "secret")
+	c.Assert(config.Net.SASL.Version, check.Equals, sarama.SASLHandshakeV1)
+	c.Assert(config.Net.SASL.SCRAMClientGeneratorFunc, check.NotNil)
+	c.Assert(config.Net.TLS.Enable, check.IsFalse)
+
+	// the password file must exist
+	security.SASLPasswordFile = filepath.Join(c.MkDir(), "not-exist")
+	_, err = newSaramaConfig(kafkaConfig)
+	c.Assert(err, check.ErrorMatches, ".*read SASL password file.*")
+}
+
+func (s *kafkaSecuritySuite) TestApplyTLS(c *check.C) {
+	security, err := NewKafkaSecurityConfig(url.Values{"insecure-skip-verify": {"true"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(security.EnableTLS, check.IsTrue)
+	config := sarama.NewConfig()
+	c.Assert(security.Apply(config), check.IsNil)
+	c.Assert(config.Net.TLS.Enable, check.IsTrue)
+	c.Assert(config.Net.TLS.Config.InsecureSkipVerify, check.IsTrue)
+	c.Assert(config.Net.TLS.Config.RootCAs, check.IsNil)
+	c.Assert(config.Net.SASL.Enable, check.IsFalse)
+
+	// the CA must be valid
+	security, err = NewKafkaSecurityConfig(url.Values{"ca": {filepath.Join(c.MkDir(), "ca.pem")}})
+	c.Assert(err, check.IsNil)
+	c.Assert(security.Apply(sarama.NewConfig()), check.ErrorMatches, ".*read ca file.*")
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqProducer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type kafkaSuite struct{}
+
+var _ = check.Suite(&kafkaSuite{})
+
+func (s *kafkaSuite) TestCompression(c *check.C) {
+	testCases := []struct {
+		compression string
+		expected    sarama.CompressionCodec
+	}{
+		{"", sarama.CompressionNone},
+		{"none", sarama.CompressionNone},
+		{"gzip", sarama.CompressionGZIP},
+		{"Snappy", sarama.CompressionSnappy},
+		{"lz4", sarama.CompressionLZ4},
+		{"zstd", sarama.CompressionZSTD},
+	}
+	for _, tc := range testCases {
+		config := DefaultKafkaConfig
+		config.Compression = tc.compression
+		cfg, err := newSaramaConfig(config)
+		c.Assert(err, check.IsNil)
+		c.Assert(cfg.Producer.Compression, check.Equals, tc.expected)
+	}
+
+	config := DefaultKafkaConfig
+	config.Compression = "brotli"
+	_, err := newSaramaConfig(config)
+	c.Assert(err, check.ErrorMatches, ".*compression \\(brotli\\) is not supported.*")
+}
+
+// mockAsyncProducer is a sarama.AsyncProducer whose input is read by the test
+type mockAsyncProducer struct {
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+}
+
+func newMockAsyncProducer(inputSize int) *mockAsyncProducer {
+	return &mockAsyncProducer{
+		input:     make(chan *sarama.ProducerMessage, inputSize),
+		successes: make(chan *sarama.ProducerMessage, inputSize),
+		errors:    make(chan *sarama.ProducerError, inputSize),
+	}
+}
+
+func (m *mockAsyncProducer) AsyncClose()                               {}
+func (m *mockAsyncProducer) Close() error                              { return nil }
+func (m *mockAsyncProducer) Input() chan<- *sarama.ProducerMessage     { return m.input }
+func (m *mockAsyncProducer) Successes() <-chan *sarama.ProducerMessage { return m.successes }
+func (m *mockAsyncProducer) Errors() <-chan *sarama.ProducerError      { return m.errors }
+
+func newTestKafkaProducer(client sarama.AsyncProducer, topic string, partitionNum int32) *kafkaSaramaProducer {
+	k := &kafkaSaramaProducer{
+		client: client,
+		topics: map[string]*kafkaTopic{
+			topic: {partitionNum: partitionNum, partitionOffset: make([]kafkaPartition, partitionNum)},
+		},
+		ackedCh: make(chan struct{}),
+		closeCh: make(chan struct{}),
+	}
+	go k.run()
+	return k
+}
+
+func (s *kafkaSuite) TestSendMessageCanceled(c *check.C) {
+	client := newMockAsyncProducer(0)
+	k := newTestKafkaProducer(client, "test", 1)
+	defer close(k.closeCh)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := k.SendMessage(ctx, "test", []byte("key"), []byte("value"), 0)
+	c.Assert(errors.Cause(err), check.Equals, context.Canceled)
+
+	// the canceled message isn't counted, so flush doesn't wait for its ack
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c.Assert(k.Flush(ctx), check.IsNil)
+}
+
+func (s *kafkaSuite) TestFlushOutOfOrderAcks(c *check.C) {
+	client := newMockAsyncProducer(2)
+	k := newTestKafkaProducer(client, "test", 1)
+	defer close(k.closeCh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		c.Assert(k.SendMessage(ctx, "test", []byte("key"), []byte("value"), 0), check.IsNil)
+	}
+	first, second := <-client.input, <-client.input
+	c.Assert(first.Metadata, check.Equals, uint64(1))
+	c.Assert(second.Metadata, check.Equals, uint64(2))
+
+	client.successes <- second
+	c.Assert(k.Flush(ctx), check.IsNil)
+
+	// the late ack of the first message doesn't move acked backwards
+	ackedCh, err := k.getState()
+	c.Assert(err, check.IsNil)
+	client.successes <- first
+	<-ackedCh
+	c.Assert(atomic.LoadUint64(&k.topics["test"].partitionOffset[0].acked), check.Equals, uint64(2))
+}
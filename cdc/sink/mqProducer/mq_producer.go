@@ -4,8 +4,15 @@ import "context"
 
 // Producer is a interface of mq producer
 type Producer interface {
-	SendMessage(ctx context.Context, key []byte, value []byte, partition int32) error
-	BroadcastMessage(ctx context.Context, key []byte, value []byte) error
-	GetPartitionNum() int32
+	// SendMessage sends the message to the partition of the topic asynchronously, the
+	// error of the sending is returned by the following SendMessage or Flush.
+	SendMessage(ctx context.Context, topic string, key []byte, value []byte, partition int32) error
+	// BroadcastMessage sends the message to all partitions of the topic asynchronously
+	BroadcastMessage(ctx context.Context, topic string, key []byte, value []byte) error
+	// Flush waits until all the messages sent before are acked
+	Flush(ctx context.Context) error
+	// GetPartitionNum returns the partition number of the topic, the topic is
+	// created if it doesn't exist and the producer is able to.
+	GetPartitionNum(topic string) (int32, error)
 	Close() error
 }
@@ -0,0 +1,185 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqProducer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// PulsarConfig stores the Pulsar configuration
+type PulsarConfig struct {
+	PartitionNum int32
+
+	OperationTimeout  time.Duration
+	ConnectionTimeout time.Duration
+}
+
+// DefaultPulsarConfig is the default Pulsar configuration
+var DefaultPulsarConfig = PulsarConfig{
+	OperationTimeout:  30 * time.Second,
+	ConnectionTimeout: 5 * time.Second,
+}
+
+type pulsarProducer struct {
+	client pulsar.Client
+	// producers contains a producer for each partition of the topic,
+	// the messages are sent to the partitions by the index of the producer.
+	producers []pulsar.Producer
+	topic     string
+
+	mu sync.Mutex
+	// err is the first error of the asynchronous sending
+	err error
+}
+
+// NewPulsarProducer creates a pulsar producer, the url is the service url of
+// the pulsar cluster, like `pulsar://127.0.0.1:6650`.
+func NewPulsarProducer(url string, topic string, config PulsarConfig) (*pulsarProducer, error) {
+	client, err := pulsar.NewClient(pulsar.ClientOptions{
+		URL:               url,
+		OperationTimeout:  config.OperationTimeout,
+		ConnectionTimeout: config.ConnectionTimeout,
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newPulsarProducer(client, topic, config)
+}
+
+// newPulsarProducer creates the producers of the partitions of the topic with
+// the client, the client is closed if it fails.
+func newPulsarProducer(client pulsar.Client, topic string, config PulsarConfig) (*pulsarProducer, error) {
+	// A partitioned topic is made up of internal topics for each partition,
+	// a non-partitioned topic is regarded as a topic with one partition.
+	partitions, err := client.TopicPartitions(topic)
+	if err != nil {
+		client.Close()
+		return nil, errors.Trace(err)
+	}
+	log.Info("get partition number of topic", zap.String("topic", topic), zap.Int("partition_num", len(partitions)))
+	partitionNum := config.PartitionNum
+	if partitionNum == 0 {
+		partitionNum = int32(len(partitions))
+	} else if int(partitionNum) < len(partitions) {
+		log.Warn("partition number assigned in sink-uri is less than that of topic")
+	} else if int(partitionNum) > len(partitions) {
+		client.Close()
+		return nil, errors.Errorf("partition number(%d) assigned in sink-uri is more than that of topic(%d)", partitionNum, len(partitions))
+	}
+
+	p := &pulsarProducer{
+		client:    client,
+		producers: make([]pulsar.Producer, 0, partitionNum),
+		topic:     topic,
+	}
+	for i := int32(0); i < partitionNum; i++ {
+		producer, err := client.CreateProducer(pulsar.ProducerOptions{
+			Topic: partitions[i],
+		})
+		if err != nil {
+			closeErr := p.Close()
+			if closeErr != nil {
+				log.Warn("close pulsar producer failed", zap.Error(closeErr))
+			}
+			return nil, errors.Annotatef(err, "create producer of %s", partitions[i])
+		}
+		p.producers = append(p.producers, producer)
+	}
+	return p, nil
+}
+
+func (p *pulsarProducer) getError() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// checkTopic checks the topic is the topic of the producer, the pulsar producer
+// sends the messages to one topic only.
+func (p *pulsarProducer) checkTopic(topic string) error {
+	if topic != p.topic {
+		return errors.Errorf("the pulsar producer of topic %s can not send messages to topic %s", p.topic, topic)
+	}
+	return nil
+}
+
+func (p *pulsarProducer) SendMessage(ctx context.Context, topic string, key []byte, value []byte, partition int32) error {
+	if err := p.checkTopic(topic); err != nil {
+		return errors.Trace(err)
+	}
+	if partition < 0 || int(partition) >= len(p.producers) {
+		return errors.Errorf("partition %d of topic %s is out of range", partition, p.topic)
+	}
+	if err := p.getError(); err != nil {
+		return errors.Trace(err)
+	}
+	p.producers[partition].SendAsync(ctx, &pulsar.ProducerMessage{
+		Key:     string(key),
+		Payload: value,
+	}, func(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
+		if err == nil {
+			return
+		}
+		log.Error("send message to pulsar failed", zap.String("topic", p.topic),
+			zap.Int32("partition", partition), zap.Error(err))
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.err == nil {
+			p.err = err
+		}
+	})
+	return nil
+}
+
+func (p *pulsarProducer) BroadcastMessage(ctx context.Context, topic string, key []byte, value []byte) error {
+	for i := range p.producers {
+		err := p.SendMessage(ctx, topic, key, value, int32(i))
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func (p *pulsarProducer) Flush(ctx context.Context) error {
+	// the pulsar producer flushes the messages sent before and waits for the acks
+	for _, producer := range p.producers {
+		if err := producer.Flush(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(p.getError())
+}
+
+func (p *pulsarProducer) GetPartitionNum(topic string) (int32, error) {
+	if err := p.checkTopic(topic); err != nil {
+		return 0, errors.Trace(err)
+	}
+	return int32(len(p.producers)), nil
+}
+
+func (p *pulsarProducer) Close() error {
+	for _, producer := range p.producers {
+		producer.Close()
+	}
+	p.client.Close()
+	return nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqProducer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+)
+
+type pulsarSuite struct{}
+
+var _ = check.Suite(&pulsarSuite{})
+
+type mockPulsarProducer struct {
+	pulsar.Producer
+	topic   string
+	sendErr error
+
+	mu       sync.Mutex
+	messages []*pulsar.ProducerMessage
+	closed   bool
+}
+
+func (p *mockPulsarProducer) SendAsync(_ context.Context, msg *pulsar.ProducerMessage, callback func(pulsar.MessageID, *pulsar.ProducerMessage, error)) {
+	p.mu.Lock()
+	p.messages = append(p.messages, msg)
+	p.mu.Unlock()
+	callback(nil, msg, p.sendErr)
+}
+
+func (p *mockPulsarProducer) Flush() error {
+	return nil
+}
+
+func (p *mockPulsarProducer) Close() {
+	p.closed = true
+}
+
+type mockPulsarClient struct {
+	pulsar.Client
+	partitions []string
+	// failTopic is the topic the producer of which fails to be created
+	failTopic string
+
+	producers []*mockPulsarProducer
+	closed    bool
+}
+
+func (c *mockPulsarClient) CreateProducer(options pulsar.ProducerOptions) (pulsar.Producer, error) {
+	if options.Topic == c.failTopic {
+		return nil, errors.New("create producer failed")
+	}
+	producer := &mockPulsarProducer{topic: options.Topic}
+	c.producers = append(c.producers, producer)
+	return producer, nil
+}
+
+func (c *mockPulsarClient) TopicPartitions(topic string) ([]string, error) {
+	return c.partitions, nil
+}
+
+func (c *mockPulsarClient) Close() {
+	c.closed = true
+}
+
+func newMockPulsarClient() *mockPulsarClient {
+	return &mockPulsarClient{
+		partitions: []string{"t-partition-0", "t-partition-1", "t-partition-2"},
+	}
+}
+
+func (s *pulsarSuite) TestPartitionNum(c *check.C) {
+	config := DefaultPulsarConfig
+	client := newMockPulsarClient()
+	p, err := newPulsarProducer(client, "t", config)
+	c.Assert(err, check.IsNil)
+	num, err := p.GetPartitionNum("t")
+	c.Assert(err, check.IsNil)
+	c.Assert(num, check.Equals, int32(3))
+	_, err = p.GetPartitionNum("t1")
+	c.Assert(err, check.ErrorMatches, ".*can not send messages to topic t1.*")
+
+	config.PartitionNum = 2
+	client = newMockPulsarClient()
+	p, err = newPulsarProducer(client, "t", config)
+	c.Assert(err, check.IsNil)
+	num, err = p.GetPartitionNum("t")
+	c.Assert(err, check.IsNil)
+	c.Assert(num, check.Equals, int32(2))
+	c.Assert(client.producers[1].topic, check.Equals, "t-partition-1")
+
+	config.PartitionNum = 4
+	client = newMockPulsarClient()
+	_, err = newPulsarProducer(client, "t", config)
+	c.Assert(err, check.ErrorMatches, ".*partition number\\(4\\) assigned in sink-uri is more than that of topic\\(3\\).*")
+	c.Assert(client.closed, check.IsTrue)
+}
+
+func (s *pulsarSuite) TestCreateProducerFailed(c *check.C) {
+	client := newMockPulsarClient()
+	client.failTopic = "t-partition-1"
+	_, err := newPulsarProducer(client, "t", DefaultPulsarConfig)
+	c.Assert(err, check.ErrorMatches, ".*create producer of t-partition-1.*")
+	// the producers created before are closed with the client
+	c.Assert(client.producers, check.HasLen, 1)
+	c.Assert(client.producers[0].closed, check.IsTrue)
+	c.Assert(client.closed, check.IsTrue)
+}
+
+func (s *pulsarSuite) TestSendMessage(c *check.C) {
+	ctx := context.Background()
+	client := newMockPulsarClient()
+	p, err := newPulsarProducer(client, "t", DefaultPulsarConfig)
+	c.Assert(err, check.IsNil)
+
+	err = p.SendMessage(ctx, "t", []byte("key"), []byte("value"), 1)
+	c.Assert(err, check.IsNil)
+	c.Assert(client.producers[0].messages, check.HasLen, 0)
+	c.Assert(client.producers[1].messages, check.HasLen, 1)
+	c.Assert(client.producers[1].messages[0].Key, check.Equals, "key")
+	c.Assert(client.producers[1].messages[0].Payload, check.DeepEquals, []byte("value"))
+
+	err = p.SendMessage(ctx, "t1", []byte("key"), []byte("value"), 1)
+	c.Assert(err, check.ErrorMatches, ".*can not send messages to topic t1.*")
+	err = p.SendMessage(ctx, "t", []byte("key"), []byte("value"), 3)
+	c.Assert(err, check.ErrorMatches, ".*partition 3 of topic t is out of range.*")
+
+	err = p.BroadcastMessage(ctx, "t", []byte("resolved"), nil)
+	c.Assert(err, check.IsNil)
+	for _, producer := range client.producers {
+		c.Assert(producer.messages[len(producer.messages)-1].Key, check.Equals, "resolved")
+	}
+	c.Assert(p.Flush(ctx), check.IsNil)
+
+	// the error of the asynchronous sending is returned by the later calls
+	client.producers[2].sendErr = errors.New("send failed")
+	err = p.SendMessage(ctx, "t", []byte("key"), []byte("value"), 2)
+	c.Assert(err, check.IsNil)
+	c.Assert(p.Flush(ctx), check.ErrorMatches, ".*send failed.*")
+	err = p.SendMessage(ctx, "t", []byte("key"), []byte("value"), 0)
+	c.Assert(err, check.ErrorMatches, ".*send failed.*")
+
+	c.Assert(p.Close(), check.IsNil)
+	for _, producer := range client.producers {
+		c.Assert(producer.closed, check.IsTrue)
+	}
+	c.Assert(client.closed, check.IsTrue)
+}
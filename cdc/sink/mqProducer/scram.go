@@ -0,0 +1,177 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqProducer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/pingcap/errors"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// scramClient is the client of the SCRAM authentication in RFC 5802, the user
+// name and the password are used without the SASLprep normalization.
+type scramClient struct {
+	hashGen func() hash.Hash
+	// newNonce generates the nonce of the client
+	newNonce func() (string, error)
+
+	step     int
+	done     bool
+	password string
+
+	gs2Header       string
+	clientNonce     string
+	clientFirstBare string
+	serverSignature []byte
+}
+
+func newSCRAMClientGenerator(hashGen func() hash.Hash) func() sarama.SCRAMClient {
+	return func() sarama.SCRAMClient {
+		return &scramClient{hashGen: hashGen, newNonce: newSCRAMNonce}
+	}
+}
+
+var (
+	scramSHA256ClientGenerator = newSCRAMClientGenerator(sha256.New)
+	scramSHA512ClientGenerator = newSCRAMClientGenerator(sha512.New)
+)
+
+func newSCRAMNonce() (string, error) {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Trace(err)
+	}
+	return base64.RawStdEncoding.EncodeToString(nonce), nil
+}
+
+// escapeSCRAMName escapes the user name as the saslname in RFC 5802
+func escapeSCRAMName(name string) string {
+	return strings.NewReplacer("=", "=3D", ",", "=2C").Replace(name)
+}
+
+// Begin implements the sarama.SCRAMClient interface
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	nonce, err := c.newNonce()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.step = 0
+	c.done = false
+	c.password = password
+	c.gs2Header = "n,,"
+	if authzID != "" {
+		c.gs2Header = "n,a=" + escapeSCRAMName(authzID) + ","
+	}
+	c.clientNonce = nonce
+	c.clientFirstBare = "n=" + escapeSCRAMName(userName) + ",r=" + nonce
+	return nil
+}
+
+// Step implements the sarama.SCRAMClient interface
+func (c *scramClient) Step(challenge string) (string, error) {
+	c.step++
+	switch c.step {
+	case 1:
+		return c.gs2Header + c.clientFirstBare, nil
+	case 2:
+		return c.clientFinal(challenge)
+	case 3:
+		c.done = true
+		return "", c.verifyServerFinal(challenge)
+	default:
+		return "", errors.New("the SCRAM authentication is done")
+	}
+}
+
+// Done implements the sarama.SCRAMClient interface
+func (c *scramClient) Done() bool {
+	return c.done
+}
+
+// parseSCRAMAttributes parses the attributes like `r=...,s=...,i=...`
+func parseSCRAMAttributes(msg string) map[byte]string {
+	attrs := make(map[byte]string)
+	for _, field := range strings.Split(msg, ",") {
+		if len(field) >= 2 && field[1] == '=' {
+			attrs[field[0]] = field[2:]
+		}
+	}
+	return attrs
+}
+
+func (c *scramClient) hmac(key []byte, data string) []byte {
+	h := hmac.New(c.hashGen, key)
+	// the write of a hash never fails
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func (c *scramClient) clientFinal(serverFirst string) (string, error) {
+	attrs := parseSCRAMAttributes(serverFirst)
+	if e, ok := attrs['e']; ok {
+		return "", errors.Errorf("SCRAM authentication failed: %s", e)
+	}
+	nonce := attrs['r']
+	if !strings.HasPrefix(nonce, c.clientNonce) || len(nonce) == len(c.clientNonce) {
+		return "", errors.New("SCRAM authentication failed: invalid server nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(attrs['s'])
+	if err != nil {
+		return "", errors.Annotate(err, "SCRAM authentication failed: invalid salt")
+	}
+	iterations, err := strconv.Atoi(attrs['i'])
+	if err != nil || iterations <= 0 {
+		return "", errors.Errorf("SCRAM authentication failed: invalid iteration count %s", attrs['i'])
+	}
+
+	saltedPassword := pbkdf2.Key([]byte(c.password), salt, iterations, c.hashGen().Size(), c.hashGen)
+	clientKey := c.hmac(saltedPassword, "Client Key")
+	h := c.hashGen()
+	_, _ = h.Write(clientKey)
+	storedKey := h.Sum(nil)
+
+	clientFinalWithoutProof := "c=" + base64.StdEncoding.EncodeToString([]byte(c.gs2Header)) + ",r=" + nonce
+	authMessage := c.clientFirstBare + "," + serverFirst + "," + clientFinalWithoutProof
+	clientSignature := c.hmac(storedKey, authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ clientSignature[i]
+	}
+	c.serverSignature = c.hmac(c.hmac(saltedPassword, "Server Key"), authMessage)
+	return clientFinalWithoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+func (c *scramClient) verifyServerFinal(serverFinal string) error {
+	attrs := parseSCRAMAttributes(serverFinal)
+	if e, ok := attrs['e']; ok {
+		return errors.Errorf("SCRAM authentication failed: %s", e)
+	}
+	signature, err := base64.StdEncoding.DecodeString(attrs['v'])
+	if err != nil {
+		return errors.Annotate(err, "SCRAM authentication failed: invalid server signature")
+	}
+	if !hmac.Equal(signature, c.serverSignature) {
+		return errors.New("SCRAM authentication failed: the server signature mismatches")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqProducer
+
+import (
+	"crypto/sha256"
+
+	"github.com/pingcap/check"
+)
+
+type scramSuite struct{}
+
+var _ = check.Suite(&scramSuite{})
+
+func (s *scramSuite) TestSCRAMSHA256(c *check.C) {
+	// the test vector in RFC 7677
+	client := &scramClient{
+		hashGen:  sha256.New,
+		newNonce: func() (string, error) { return "rOprNGfwEbeRWgbNEkqO", nil },
+	}
+	c.Assert(client.Begin("user", "pencil", ""), check.IsNil)
+	msg, err := client.Step("")
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.Equals, "n,,n=user,r=rOprNGfwEbeRWgbNEkqO")
+	c.Assert(client.Done(), check.IsFalse)
+
+	msg, err = client.Step("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.Equals, "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=")
+	c.Assert(client.Done(), check.IsFalse)
+
+	msg, err = client.Step("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=")
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.Equals, "")
+	c.Assert(client.Done(), check.IsTrue)
+}
+
+func (s *scramSuite) TestSCRAMFailures(c *check.C) {
+	newClient := func() *scramClient {
+		client := &scramClient{
+			hashGen:  sha256.New,
+			newNonce: func() (string, error) { return "rOprNGfwEbeRWgbNEkqO", nil },
+		}
+		c.Assert(client.Begin("user,1", "pencil", ""), check.IsNil)
+		msg, err := client.Step("")
+		c.Assert(err, check.IsNil)
+		c.Assert(msg, check.Equals, "n,,n=user=2C1,r=rOprNGfwEbeRWgbNEkqO")
+		return client
+	}
+
+	// the server nonce must start with the client nonce
+	_, err := newClient().Step("r=fyko+d2lbbFgONRv9qkxdawL,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	c.Assert(err, check.ErrorMatches, ".*invalid server nonce.*")
+	_, err = newClient().Step("e=unknown-user")
+	c.Assert(err, check.ErrorMatches, ".*unknown-user.*")
+
+	// the server signature must be verified
+	client := newClient()
+	_, err = client.Step("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	c.Assert(err, check.IsNil)
+	_, err = client.Step("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=")
+	c.Assert(err, check.ErrorMatches, ".*server signature mismatches.*")
+}
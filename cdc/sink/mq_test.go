@@ -0,0 +1,247 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+)
+
+// mockProducer records the messages, and its Flush blocks until the result
+// is sent to flushCh.
+type mockProducer struct {
+	mu       sync.Mutex
+	messages []*codec.MQMessage
+	// topics are the topics of the messages
+	topics []string
+	closed bool
+
+	flushCh chan error
+}
+
+func (m *mockProducer) SendMessage(ctx context.Context, topic string, key []byte, value []byte, partition int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, &codec.MQMessage{Key: key, Value: value})
+	m.topics = append(m.topics, topic)
+	return nil
+}
+
+func (m *mockProducer) BroadcastMessage(ctx context.Context, topic string, key []byte, value []byte) error {
+	partitionNum, err := m.GetPartitionNum(topic)
+	if err != nil {
+		return err
+	}
+	for i := int32(0); i < partitionNum; i++ {
+		if err := m.SendMessage(ctx, topic, key, value, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// topicMessageCount returns the number of messages sent to each topic
+func (m *mockProducer) topicMessageCount() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := make(map[string]int)
+	for _, topic := range m.topics {
+		count[topic]++
+	}
+	m.messages = nil
+	m.topics = nil
+	return count
+}
+
+func (m *mockProducer) Flush(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-m.flushCh:
+		return err
+	}
+}
+
+func (m *mockProducer) GetPartitionNum(topic string) (int32, error) {
+	return 2, nil
+}
+
+func (m *mockProducer) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+type mqSinkSuite struct{}
+
+var _ = check.Suite(&mqSinkSuite{})
+
+func waitCheckpointTs(c *check.C, sink Sink, ts uint64) {
+	for i := 0; i < 100; i++ {
+		if sink.CheckpointTs() == ts {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("the checkpoint ts %d is not reached, current %d", ts, sink.CheckpointTs())
+}
+
+func (s *mqSinkSuite) TestCheckpointAfterFlush(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	filter, err := util.NewFilter(&util.ReplicaConfig{})
+	c.Assert(err, check.IsNil)
+	producer := &mockProducer{flushCh: make(chan error)}
+	sink, err := newMqSink(producer, filter, codec.NewJSONEventEncoder(), "test", nil, map[string]string{})
+	c.Assert(err, check.IsNil)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- sink.Run(ctx)
+	}()
+
+	err = sink.EmitResolvedEvent(ctx, 20)
+	c.Assert(err, check.IsNil)
+	err = sink.EmitRowChangedEvent(ctx,
+		&model.RowChangedEvent{Ts: 10, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: map[string]*model.Column{"id": {Value: int64(1)}}},
+		&model.RowChangedEvent{Ts: 20, Resolved: true},
+	)
+	c.Assert(err, check.IsNil)
+
+	// the checkpoint is not advanced until the messages are acked
+	time.Sleep(50 * time.Millisecond)
+	c.Assert(sink.CheckpointTs(), check.Equals, uint64(0))
+	producer.flushCh <- nil
+	waitCheckpointTs(c, sink, 20)
+
+	// the error of the producer is returned by Run
+	err = sink.EmitResolvedEvent(ctx, 30)
+	c.Assert(err, check.IsNil)
+	err = sink.EmitRowChangedEvent(ctx, &model.RowChangedEvent{Ts: 30, Resolved: true})
+	c.Assert(err, check.IsNil)
+	producer.flushCh <- errors.New("kafka: broker not available")
+	select {
+	case err := <-errCh:
+		c.Assert(err, check.ErrorMatches, ".*broker not available.*")
+	case <-time.After(time.Second):
+		c.Fatal("the error of the producer is not returned")
+	}
+	c.Assert(sink.CheckpointTs(), check.Equals, uint64(20))
+	c.Assert(producer.closed, check.IsTrue)
+	c.Assert(producer.messages, check.HasLen, 1)
+}
+
+func (s *mqSinkSuite) TestTopicPerTable(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sinkFilter, err := util.NewFilter(&util.ReplicaConfig{})
+	c.Assert(err, check.IsNil)
+	producer := &mockProducer{flushCh: make(chan error, 16)}
+	for i := 0; i < cap(producer.flushCh); i++ {
+		producer.flushCh <- nil
+	}
+	config := &util.ReplicaConfig{Sink: &util.SinkConfig{TopicRules: []*util.TopicRule{{
+		Matcher: &filter.Rules{DoDBs: []string{"audit"}},
+		Topic:   "audit-log",
+	}}}}
+	sink, err := newMqSink(producer, sinkFilter, codec.NewJSONEventEncoder(), "{schema}_{table}", config, map[string]string{})
+	c.Assert(err, check.IsNil)
+
+	// the rows are sent to the topics of the tables
+	newRow := func(schema, table string) *model.RowChangedEvent {
+		return &model.RowChangedEvent{Ts: 10, Schema: schema, Table: table, Type: model.InsertDMLType, Columns: map[string]*model.Column{"id": {Value: int64(1)}}}
+	}
+	err = sink.EmitRowChangedEvent(ctx, newRow("test", "t1"), newRow("test", "t2"), newRow("test", "t2"), newRow("audit", "t1"))
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.topicMessageCount(), check.DeepEquals, map[string]int{"test_t1": 1, "test_t2": 2, "audit-log": 1})
+
+	// the checkpoints are sent to all the partitions of the topics of the tables
+	sink.UpdateTables([]entry.TableName{{Schema: "test", Table: "t1"}, {Schema: "test", Table: "t2"}, {Schema: "audit", Table: "t1"}})
+	err = sink.EmitCheckpointEvent(ctx, 10)
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.topicMessageCount(), check.DeepEquals, map[string]int{"test_t1": 2, "test_t2": 2, "audit-log": 2})
+
+	// the DDL of a table is sent to the topic of the table, even if it is a new table
+	err = sink.EmitDDLEvent(ctx, &model.DDLEvent{Ts: 20, Schema: "test", Table: "t3", Query: "create table t3(id int)", Type: timodel.ActionCreateTable})
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.topicMessageCount(), check.DeepEquals, map[string]int{"test_t3": 2})
+
+	// the DDL of a schema is sent to the topics of the tables in the schema
+	err = sink.EmitDDLEvent(ctx, &model.DDLEvent{Ts: 30, Schema: "test", Query: "drop database test", Type: timodel.ActionDropSchema})
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.topicMessageCount(), check.DeepEquals, map[string]int{"test_t1": 2, "test_t2": 2})
+}
+
+func (s *mqSinkSuite) TestBatch(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sinkFilter, err := util.NewFilter(&util.ReplicaConfig{})
+	c.Assert(err, check.IsNil)
+	producer := &mockProducer{flushCh: make(chan error, 1)}
+	producer.flushCh <- nil
+	sink, err := newMqSink(producer, sinkFilter, codec.NewJSONEventBatchEncoder(1<<20, 2, codec.CompressionNone), "test", nil, map[string]string{})
+	c.Assert(err, check.IsNil)
+
+	// the rows of a partition are sent when the batch is full or resolved
+	rows := make([]*model.RowChangedEvent, 0, 3)
+	for i := 0; i < 3; i++ {
+		rows = append(rows, &model.RowChangedEvent{Ts: 10, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: map[string]*model.Column{"id": {Value: int64(i)}}})
+	}
+	err = sink.EmitRowChangedEvent(ctx, rows...)
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.messages, check.HasLen, 1)
+	err = sink.EmitRowChangedEvent(ctx, &model.RowChangedEvent{Ts: 10, Resolved: true})
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.messages, check.HasLen, 2)
+
+	var decoded []*model.RowChangedEvent
+	for _, msg := range producer.messages {
+		decoder, err := codec.NewOpenProtocolEventDecoder(msg.Key, msg.Value)
+		c.Assert(err, check.IsNil)
+		for {
+			_, hasNext, err := decoder.HasNext()
+			c.Assert(err, check.IsNil)
+			if !hasNext {
+				break
+			}
+			row, err := decoder.NextRowChangedEvent()
+			c.Assert(err, check.IsNil)
+			decoded = append(decoded, row)
+		}
+	}
+	c.Assert(decoded, check.HasLen, 3)
+	for i, row := range decoded {
+		// the numbers are decoded as json.Number
+		c.Assert(fmt.Sprint(row.Columns["id"].Value), check.Equals, strconv.Itoa(i))
+	}
+
+	// the checkpoint is sent as a batch as well
+	producer.topicMessageCount()
+	err = sink.EmitCheckpointEvent(ctx, 10)
+	c.Assert(err, check.IsNil)
+	c.Assert(producer.messages, check.HasLen, 2)
+	c.Assert(codec.IsBatchMessage(producer.messages[0].Key), check.IsTrue)
+}
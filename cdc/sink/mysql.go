@@ -189,7 +189,13 @@ func (s *mysqlSink) Run(ctx context.Context) error {
 				continue
 			}
 
-			if err := s.concurrentExec(ctx, resolvedRowsMap); err != nil {
+			var err error
+			if s.params.txnAtomic {
+				err = s.concurrentExecTxns(ctx, groupTxns(resolvedRowsMap))
+			} else {
+				err = s.concurrentExec(ctx, resolvedRowsMap)
+			}
+			if err != nil {
 				return errors.Trace(err)
 			}
 			atomic.StoreUint64(&s.checkpointTs, globalResolvedTs)
@@ -228,8 +234,11 @@ func splitRowsGroup(resolvedTs uint64, unresolvedRows map[string][]*model.RowCha
 var _ Sink = &mysqlSink{}
 
 type params struct {
-	workerCount  int
-	maxTxnRow    int
+	workerCount int
+	maxTxnRow   int
+	// txnAtomic makes each upstream transaction be applied as one downstream transaction,
+	// the atomicity only covers the tables replicated by this capture
+	txnAtomic    bool
 	changefeedID string
 	captureID    string
 }
@@ -283,6 +292,14 @@ func newMySQLSink(sinkURI *url.URL, dsn *dmysql.Config, filter *util.Filter, opt
 			}
 			params.maxTxnRow = c
 		}
+		s = sinkURI.Query().Get("txn-atomic")
+		if s != "" {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			params.txnAtomic = b
+		}
 		// dsn format of the driver:
 		// [username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]
 		username := sinkURI.User.Username()
@@ -328,38 +345,151 @@ func newMySQLSink(sinkURI *url.URL, dsn *dmysql.Config, filter *util.Filter, opt
 	return sink, nil
 }
 
+// concurrentExec applies the rows concurrently, the rows conflicting with each
+// other are applied by one worker in the order of commit ts
 func (s *mysqlSink) concurrentExec(ctx context.Context, rowGroups map[string][]*model.RowChangedEvent) error {
-	jobs := make(chan []*model.RowChangedEvent, len(rowGroups))
-	for _, dmls := range rowGroups {
-		jobs <- dmls
+	rows := mergeRowGroups(rowGroups)
+	groups := make([][]*model.RowChangedEvent, 0, len(rows))
+	for _, row := range rows {
+		groups = append(groups, []*model.RowChangedEvent{row})
 	}
-	close(jobs)
+	return s.causalityExec(ctx, groups, func(groups [][]*model.RowChangedEvent) error {
+		rows := make([]*model.RowChangedEvent, 0, len(groups))
+		for _, group := range groups {
+			rows = append(rows, group...)
+		}
+		return rowLimitIterator(rows, s.params.maxTxnRow,
+			func(rows []*model.RowChangedEvent) error {
+				// TODO: Add retry
+				return errors.Trace(s.execDMLs(ctx, rows))
+			})
+	})
+}
+
+// mergeRowGroups merges the rows of all tables, the returned rows are sorted by commit ts
+func mergeRowGroups(rowGroups map[string][]*model.RowChangedEvent) []*model.RowChangedEvent {
+	tables := make([]string, 0, len(rowGroups))
+	size := 0
+	for table, rows := range rowGroups {
+		tables = append(tables, table)
+		size += len(rows)
+	}
+	sort.Strings(tables)
+
+	rows := make([]*model.RowChangedEvent, 0, size)
+	for _, table := range tables {
+		rows = append(rows, rowGroups[table]...)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Ts < rows[j].Ts
+	})
+	return rows
+}
 
+// causalityExec dispatches the row groups to the workers, the groups conflicting with
+// each other are dispatched to the same worker. If a group conflicts with the groups
+// on more than one worker, all of the dispatched groups are applied before it.
+func (s *mysqlSink) causalityExec(
+	ctx context.Context,
+	groups [][]*model.RowChangedEvent,
+	exec func(groups [][]*model.RowChangedEvent) error,
+) error {
 	nWorkers := s.params.workerCount
 	if nWorkers == 0 {
 		nWorkers = defaultParams.workerCount
 	}
+	workerGroups := make([][][]*model.RowChangedEvent, nWorkers)
+	c := newCausality()
+
+	flush := func() error {
+		eg, _ := errgroup.WithContext(ctx)
+		for i := range workerGroups {
+			groups := workerGroups[i]
+			if len(groups) == 0 {
+				continue
+			}
+			eg.Go(func() error {
+				return errors.Trace(exec(groups))
+			})
+		}
+		err := eg.Wait()
+		for i := range workerGroups {
+			workerGroups[i] = nil
+		}
+		c.reset()
+		return err
+	}
 
-	if len(rowGroups) < nWorkers {
-		nWorkers = len(rowGroups)
-	}
-	eg, _ := errgroup.WithContext(ctx)
-	for i := 0; i < nWorkers; i++ {
-		eg.Go(func() error {
-			for rows := range jobs {
-				err := rowLimitIterator(rows, s.params.maxTxnRow,
-					func(rows []*model.RowChangedEvent) error {
-						// TODO: Add retry
-						return errors.Trace(s.execDMLs(ctx, rows))
-					})
-				if err != nil {
-					return errors.Trace(err)
-				}
+	next := 0
+	for _, group := range groups {
+		keys := genTxnKeys(group)
+		conflict, idx := c.detectConflict(keys)
+		if conflict && idx < 0 {
+			if err := flush(); err != nil {
+				return errors.Trace(err)
 			}
-			return nil
-		})
+			conflict = false
+		}
+		if !conflict {
+			idx = next
+			next = (next + 1) % nWorkers
+		}
+		c.add(keys, idx)
+		workerGroups[idx] = append(workerGroups[idx], group)
+	}
+	return errors.Trace(flush())
+}
+
+type txnKey struct {
+	startTs  uint64
+	commitTs uint64
+}
+
+// groupTxns groups the rows by the upstream transactions, the returned transactions are sorted by commit ts
+func groupTxns(rowGroups map[string][]*model.RowChangedEvent) [][]*model.RowChangedEvent {
+	tables := make([]string, 0, len(rowGroups))
+	for table := range rowGroups {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	var keys []txnKey
+	txns := make(map[txnKey][]*model.RowChangedEvent)
+	for _, table := range tables {
+		for _, row := range rowGroups[table] {
+			key := txnKey{startTs: row.StartTs, commitTs: row.Ts}
+			if _, exist := txns[key]; !exist {
+				keys = append(keys, key)
+			}
+			txns[key] = append(txns[key], row)
+		}
 	}
-	return eg.Wait()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].commitTs != keys[j].commitTs {
+			return keys[i].commitTs < keys[j].commitTs
+		}
+		return keys[i].startTs < keys[j].startTs
+	})
+
+	result := make([][]*model.RowChangedEvent, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, txns[key])
+	}
+	return result
+}
+
+// concurrentExecTxns applies each transaction as one downstream transaction,
+// the transactions conflicting with each other are applied in order,
+// and the others are applied concurrently.
+func (s *mysqlSink) concurrentExecTxns(ctx context.Context, txns [][]*model.RowChangedEvent) error {
+	return s.causalityExec(ctx, txns, func(txns [][]*model.RowChangedEvent) error {
+		for _, txn := range txns {
+			if err := s.execDMLs(ctx, txn); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		return nil
+	})
 }
 
 func rowLimitIterator(rows []*model.RowChangedEvent, maxTxnRow int, fn func([]*model.RowChangedEvent) error) error {
@@ -429,9 +559,12 @@ func (s *mysqlSink) execDMLs(ctx context.Context, rows []*model.RowChangedEvent)
 		var query string
 		var args []interface{}
 		var err error
-		if row.Delete {
-			query, args, err = s.prepareDelete(row.Schema, row.Table, row.Columns)
-		} else {
+		switch {
+		case row.Type == model.DeleteDMLType:
+			query, args, err = s.prepareDelete(row.Schema, row.Table, row.PreColumns)
+		case row.Type == model.UpdateDMLType && len(row.PreColumns) != 0:
+			query, args, err = s.prepareUpdate(row.Schema, row.Table, row.PreColumns, row.Columns)
+		default:
 			query, args, err = s.prepareReplace(row.Schema, row.Table, row.Columns)
 		}
 		if err != nil {
@@ -477,12 +610,45 @@ func (s *mysqlSink) prepareReplace(schema, table string, cols map[string]*model.
 	return builder.String(), args, nil
 }
 
+func (s *mysqlSink) prepareUpdate(schema, table string, preCols, cols map[string]*model.Column) (string, []interface{}, error) {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("UPDATE %s SET ", util.QuoteSchema(schema, table)))
+
+	columnNames := make([]string, 0, len(cols))
+	for k := range cols {
+		columnNames = append(columnNames, k)
+	}
+	sort.Strings(columnNames)
+	args := make([]interface{}, 0, len(cols)+len(preCols))
+	for i, colName := range columnNames {
+		if i > 0 {
+			builder.WriteString(",")
+		}
+		builder.WriteString(util.QuoteName(colName) + " = ?")
+		args = append(args, cols[colName].Value)
+	}
+
+	builder.WriteString(" WHERE ")
+	args = writeWhereCondition(&builder, preCols, args)
+	builder.WriteString(" LIMIT 1;")
+	sql := builder.String()
+	return sql, args, nil
+}
+
 func (s *mysqlSink) prepareDelete(schema, table string, cols map[string]*model.Column) (string, []interface{}, error) {
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("DELETE FROM %s WHERE ", util.QuoteSchema(schema, table)))
 
+	args := writeWhereCondition(&builder, cols, nil)
+	builder.WriteString(" LIMIT 1;")
+	sql := builder.String()
+	return sql, args, nil
+}
+
+// writeWhereCondition writes the condition matching the unique key values of cols
+// to builder and returns args appended with the values of the condition
+func writeWhereCondition(builder *strings.Builder, cols map[string]*model.Column, args []interface{}) []interface{} {
 	colNames, wargs := whereSlice(cols)
-	args := make([]interface{}, 0, len(wargs))
 	for i := 0; i < len(colNames); i++ {
 		if i > 0 {
 			builder.WriteString(" AND ")
@@ -494,9 +660,7 @@ func (s *mysqlSink) prepareDelete(schema, table string, cols map[string]*model.C
 			args = append(args, wargs[i])
 		}
 	}
-	builder.WriteString(" LIMIT 1;")
-	sql := builder.String()
-	return sql, args, nil
+	return args
 }
 
 func whereSlice(cols map[string]*model.Column) (colNames []string, args []interface{}) {
@@ -17,6 +17,7 @@ import (
 	"testing"
 
 	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/ticdc/cdc/model"
 )
 
@@ -150,6 +151,40 @@ func (s EmitSuite) TestTxnRowLimiter(c *check.C) {
 	}
 }
 
+func (s EmitSuite) TestGroupTxns(c *check.C) {
+	rowGroups := map[string][]*model.RowChangedEvent{
+		"a": {{StartTs: 1, Ts: 2, Table: "a"}, {StartTs: 3, Ts: 5, Table: "a"}, {StartTs: 3, Ts: 5, Table: "a"}},
+		"b": {{StartTs: 1, Ts: 2, Table: "b"}, {StartTs: 4, Ts: 5, Table: "b"}},
+	}
+	expected := [][]*model.RowChangedEvent{
+		{{StartTs: 1, Ts: 2, Table: "a"}, {StartTs: 1, Ts: 2, Table: "b"}},
+		{{StartTs: 3, Ts: 5, Table: "a"}, {StartTs: 3, Ts: 5, Table: "a"}},
+		{{StartTs: 4, Ts: 5, Table: "b"}},
+	}
+	c.Assert(groupTxns(rowGroups), check.DeepEquals, expected)
+}
+
+func (s EmitSuite) TestPrepareDML(c *check.C) {
+	sink := &mysqlSink{}
+	preCols := map[string]*model.Column{
+		"id": {Type: mysql.TypeLong, WhereHandle: true, Value: 1},
+	}
+	cols := map[string]*model.Column{
+		"id":   {Type: mysql.TypeLong, WhereHandle: true, Value: 2},
+		"name": {Type: mysql.TypeVarchar, Value: "a"},
+	}
+
+	query, args, err := sink.prepareUpdate("test", "t", preCols, cols)
+	c.Assert(err, check.IsNil)
+	c.Assert(query, check.Equals, "UPDATE `test`.`t` SET `id` = ?,`name` = ? WHERE `id` = ? LIMIT 1;")
+	c.Assert(args, check.DeepEquals, []interface{}{2, "a", 1})
+
+	query, args, err = sink.prepareDelete("test", "t", preCols)
+	c.Assert(err, check.IsNil)
+	c.Assert(query, check.Equals, "DELETE FROM `test`.`t` WHERE `id` = ? LIMIT 1;")
+	c.Assert(args, check.DeepEquals, []interface{}{1})
+}
+
 /*
    import (
    	"context"
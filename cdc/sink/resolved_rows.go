@@ -0,0 +1,133 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+	"go.uber.org/zap"
+)
+
+// resolvedRowsBuffer buffers the row changes of each table until they are
+// resolved by the global resolved ts, it's shared by the sinks which write
+// the resolved rows in batches, such as the file sink and the s3 sink.
+type resolvedRowsBuffer struct {
+	// name is the name of the sink in the logs
+	name   string
+	filter *util.Filter
+
+	globalResolvedTs uint64
+	sinkResolvedTs   uint64
+
+	globalForwardCh chan struct{}
+
+	unresolvedRowsMu sync.Mutex
+	unresolvedRows   map[string][]*model.RowChangedEvent
+
+	changefeedID string
+	// count is the number of the rows and DDLs written by the sink
+	count int64
+}
+
+func newResolvedRowsBuffer(name string, filter *util.Filter, opts map[string]string) *resolvedRowsBuffer {
+	return &resolvedRowsBuffer{
+		name:            name,
+		filter:          filter,
+		globalForwardCh: make(chan struct{}, 1),
+		unresolvedRows:  make(map[string][]*model.RowChangedEvent),
+		changefeedID:    opts[OptChangefeedID],
+	}
+}
+
+func (b *resolvedRowsBuffer) EmitResolvedEvent(ctx context.Context, ts uint64) error {
+	atomic.StoreUint64(&b.globalResolvedTs, ts)
+	select {
+	case b.globalForwardCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (b *resolvedRowsBuffer) EmitRowChangedEvent(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	var resolvedTs uint64
+	b.unresolvedRowsMu.Lock()
+	defer b.unresolvedRowsMu.Unlock()
+	for _, row := range rows {
+		if row.Resolved {
+			resolvedTs = row.Ts
+			continue
+		}
+		if b.filter.ShouldIgnoreEvent(row.Ts, row.Schema, row.Table) {
+			log.Info("Row changed event ignored", zap.Uint64("ts", row.Ts))
+			continue
+		}
+		key := util.QuoteSchema(row.Schema, row.Table)
+		b.unresolvedRows[key] = append(b.unresolvedRows[key], row)
+	}
+	if resolvedTs != 0 {
+		atomic.StoreUint64(&b.sinkResolvedTs, resolvedTs)
+	}
+	return nil
+}
+
+// resolvedRows waits until all the rows before the resolved ts are emitted,
+// and takes the resolved rows grouped by the tables out of the buffer.
+func (b *resolvedRowsBuffer) resolvedRows(ctx context.Context, resolvedTs uint64) (map[string][]*model.RowChangedEvent, error) {
+	for resolvedTs > atomic.LoadUint64(&b.sinkResolvedTs) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	b.unresolvedRowsMu.Lock()
+	defer b.unresolvedRowsMu.Unlock()
+	_, resolvedRowsMap := splitRowsGroup(resolvedTs, b.unresolvedRows)
+	return resolvedRowsMap, nil
+}
+
+func (b *resolvedRowsBuffer) PrintStatus(ctx context.Context) error {
+	lastTime := time.Now()
+	var lastCount int64
+	timer := time.NewTicker(printStatusInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			now := time.Now()
+			seconds := now.Unix() - lastTime.Unix()
+			total := atomic.LoadInt64(&b.count)
+			count := total - lastCount
+			qps := int64(0)
+			if seconds > 0 {
+				qps = count / seconds
+			}
+			lastCount = total
+			lastTime = now
+			log.Info(b.name+" replication status",
+				zap.String("changefeed", b.changefeedID),
+				zap.Int64("count", count),
+				zap.Int64("qps", qps))
+		}
+	}
+}
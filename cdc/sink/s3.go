@@ -0,0 +1,345 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxObjectSize = 64 * 1024 * 1024
+	defaultFlushInterval = time.Minute
+)
+
+// objectStorage is the storage the objects are put to
+type objectStorage interface {
+	// PutObject puts the object with the key, the existing object is overwritten
+	PutObject(ctx context.Context, key string, data []byte) error
+	// ObjectExists checks whether the object of the key exists
+	ObjectExists(ctx context.Context, key string) (bool, error)
+}
+
+// s3Storage puts the objects to a bucket of S3 or any S3-compatible service
+type s3Storage struct {
+	client *s3.S3
+	bucket string
+}
+
+// newS3Storage creates the s3 storage with the options in the query of sink-uri.
+// The `endpoint` is set for the S3-compatible services, and the path-style
+// addressing is used by default for them. The credential is set by `access-key`
+// and `secret-access-key-file`, the secret is read from the file on the host of
+// the capture so it is not in the uri stored in etcd. The credential is loaded
+// from the environment variables or the shared credential file by default.
+func newS3Storage(bucket string, query url.Values) (*s3Storage, error) {
+	config := aws.NewConfig().WithRegion("us-east-1")
+	if v := query.Get("region"); v != "" {
+		config.WithRegion(v)
+	}
+	if v := query.Get("endpoint"); v != "" {
+		// most of the S3-compatible services only support the path-style addressing
+		config.WithEndpoint(v).WithS3ForcePathStyle(true)
+	}
+	if v := query.Get("force-path-style"); v != "" {
+		forcePathStyle, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.Annotatef(err, "invalid force-path-style %s", v)
+		}
+		config.WithS3ForcePathStyle(forcePathStyle)
+	}
+	if query.Get("secret-access-key") != "" {
+		return nil, errors.New("the secret access key can not be in the uri, please use secret-access-key-file")
+	}
+	accessKey, secretKeyFile := query.Get("access-key"), query.Get("secret-access-key-file")
+	if accessKey != "" || secretKeyFile != "" {
+		if accessKey == "" || secretKeyFile == "" {
+			return nil, errors.New("the access-key and secret-access-key-file must be set together")
+		}
+		secretKey, err := ioutil.ReadFile(secretKeyFile)
+		if err != nil {
+			return nil, errors.Annotatef(err, "read secret access key file %s", secretKeyFile)
+		}
+		config.WithCredentials(credentials.NewStaticCredentials(accessKey, strings.TrimRight(string(secretKey), "\r\n"), ""))
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &s3Storage{client: s3.New(sess), bucket: bucket}, nil
+}
+
+func (s *s3Storage) PutObject(ctx context.Context, key string, data []byte) error {
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	return errors.Annotatef(err, "put object %s to bucket %s", key, s.bucket)
+}
+
+func (s *s3Storage) ObjectExists(ctx context.Context, key string) (bool, error) {
+	_, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.RequestFailure); ok && aerr.StatusCode() == http.StatusNotFound {
+			return false, nil
+		}
+		return false, errors.Annotatef(err, "head object %s in bucket %s", key, s.bucket)
+	}
+	return true, nil
+}
+
+// s3Sink batches the row changes of each table in memory, and puts them to the
+// storage as `<prefix>/<schema>/<table>/cdc-<first commit ts>-<last commit ts>.<ext>`
+// when the batch is large enough or has been kept for the flush interval. The DDLs
+// are put as `ddl-<commit ts>.<ext>` under the prefix of the table, or the schema
+// if it's a schema DDL, and the global checkpoint is put as `<prefix>/checkpoint`.
+// Like the file sink, the existing objects of the rows are never overwritten, a
+// sequence is appended to the key if the object of the key exists.
+// The checkpoint of the sink doesn't exceed the commit ts of the rows not put yet.
+type s3Sink struct {
+	*resolvedRowsBuffer
+
+	storage       objectStorage
+	prefix        string
+	encoder       fileEncoder
+	maxObjectSize int
+	flushInterval time.Duration
+
+	checkpointTs uint64
+
+	// batches is only accessed by Run
+	batches map[string]*tableBatch
+}
+
+// tableBatch is the encoded rows of a table not put to the storage yet
+type tableBatch struct {
+	prefix    string
+	buf       bytes.Buffer
+	header    []byte
+	firstTs   uint64
+	lastTs    uint64
+	startTime time.Time
+}
+
+func newS3Sink(sinkURI *url.URL, filter *util.Filter, opts map[string]string) (*s3Sink, error) {
+	bucket := sinkURI.Host
+	if bucket == "" {
+		return nil, errors.New("the bucket of the s3 sink is not specified")
+	}
+	storage, err := newS3Storage(bucket, sinkURI.Query())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newS3SinkWithStorage(storage, sinkURI, filter, opts)
+}
+
+func newS3SinkWithStorage(storage objectStorage, sinkURI *url.URL, filter *util.Filter, opts map[string]string) (*s3Sink, error) {
+	query := sinkURI.Query()
+	encoder, err := newFileEncoder(query.Get("format"))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s := &s3Sink{
+		resolvedRowsBuffer: newResolvedRowsBuffer("s3 sink", filter, opts),
+		storage:            storage,
+		prefix:             path.Clean("/" + sinkURI.Path)[1:],
+		encoder:            encoder,
+		maxObjectSize:      defaultMaxObjectSize,
+		flushInterval:      defaultFlushInterval,
+		batches:            make(map[string]*tableBatch),
+	}
+	if v := query.Get("max-object-size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Annotatef(err, "invalid max-object-size %s", v)
+		}
+		s.maxObjectSize = size
+	}
+	if v := query.Get("flush-interval"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, errors.Annotatef(err, "invalid flush-interval %s", v)
+		}
+		s.flushInterval = interval
+	}
+	return s, nil
+}
+
+func (s *s3Sink) EmitCheckpointEvent(ctx context.Context, ts uint64) error {
+	data, err := json.Marshal(&fileCheckpoint{CheckpointTs: ts})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return s.storage.PutObject(ctx, path.Join(s.prefix, fileCheckpointName), data)
+}
+
+func (s *s3Sink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	if s.filter.ShouldIgnoreEvent(ddl.Ts, ddl.Schema, ddl.Table) {
+		log.Info(
+			"DDL event ignored",
+			zap.String("query", ddl.Query),
+			zap.Uint64("ts", ddl.Ts),
+		)
+		return nil
+	}
+	data, err := s.encoder.EncodeDDL(ddl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	key := path.Join(s.tablePrefix(ddl.Schema, ddl.Table), fmt.Sprintf("ddl-%d%s", ddl.Ts, s.encoder.Extension()))
+	if err := s.storage.PutObject(ctx, key, data); err != nil {
+		return errors.Trace(err)
+	}
+	atomic.AddInt64(&s.count, 1)
+	return nil
+}
+
+func (s *s3Sink) CheckpointTs() uint64 {
+	return atomic.LoadUint64(&s.checkpointTs)
+}
+
+func (s *s3Sink) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.globalForwardCh:
+		case <-ticker.C:
+			// put the batches kept for the flush interval even if no new resolved ts
+		}
+		globalResolvedTs := atomic.LoadUint64(&s.globalResolvedTs)
+		resolvedRowsMap, err := s.resolvedRows(ctx, globalResolvedTs)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		checkpointTs, err := s.flushRows(ctx, globalResolvedTs, resolvedRowsMap)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		atomic.StoreUint64(&s.checkpointTs, checkpointTs)
+	}
+}
+
+// flushRows appends the resolved rows to the batches of the tables, and puts the
+// batches which are large enough or have been kept for the flush interval. It
+// returns the checkpoint ts which is less than the commit ts of the rows not put.
+func (s *s3Sink) flushRows(
+	ctx context.Context, resolvedTs uint64, rowsMap map[string][]*model.RowChangedEvent,
+) (uint64, error) {
+	for key, rows := range rowsMap {
+		batch, ok := s.batches[key]
+		if !ok {
+			batch = &tableBatch{prefix: s.tablePrefix(rows[0].Schema, rows[0].Table)}
+			s.batches[key] = batch
+		}
+		for _, row := range rows {
+			header, err := s.encoder.RowHeader(row)
+			if err != nil {
+				return 0, errors.Trace(err)
+			}
+			if batch.buf.Len() > 0 && !bytes.Equal(header, batch.header) {
+				if err := s.putBatch(ctx, batch); err != nil {
+					return 0, errors.Trace(err)
+				}
+			}
+			if batch.buf.Len() == 0 {
+				batch.header = header
+				batch.firstTs = row.Ts
+				batch.startTime = time.Now()
+				batch.buf.Write(header)
+			}
+			data, err := s.encoder.EncodeRow(row)
+			if err != nil {
+				return 0, errors.Trace(err)
+			}
+			batch.buf.Write(data)
+			batch.lastTs = row.Ts
+		}
+		atomic.AddInt64(&s.count, int64(len(rows)))
+	}
+
+	checkpointTs := resolvedTs
+	for key, batch := range s.batches {
+		if batch.buf.Len() == 0 {
+			delete(s.batches, key)
+			continue
+		}
+		if batch.buf.Len() >= s.maxObjectSize || time.Since(batch.startTime) >= s.flushInterval {
+			if err := s.putBatch(ctx, batch); err != nil {
+				return 0, errors.Trace(err)
+			}
+			delete(s.batches, key)
+			continue
+		}
+		if batch.firstTs-1 < checkpointTs {
+			checkpointTs = batch.firstTs - 1
+		}
+	}
+	return checkpointTs, nil
+}
+
+func (s *s3Sink) putBatch(ctx context.Context, batch *tableBatch) error {
+	name := fmt.Sprintf("cdc-%d-%d", batch.firstTs, batch.lastTs)
+	key := path.Join(batch.prefix, name+s.encoder.Extension())
+	// the object of the same key is put if the header changes in the same commit ts
+	for seq := 1; ; seq++ {
+		exists, err := s.storage.ObjectExists(ctx, key)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !exists {
+			break
+		}
+		key = path.Join(batch.prefix, fmt.Sprintf("%s-%d%s", name, seq, s.encoder.Extension()))
+	}
+	if err := s.storage.PutObject(ctx, key, batch.buf.Bytes()); err != nil {
+		return errors.Trace(err)
+	}
+	batch.buf.Reset()
+	return nil
+}
+
+func (s *s3Sink) tablePrefix(schema, table string) string {
+	if table == "" {
+		return path.Join(s.prefix, url.PathEscape(schema))
+	}
+	return path.Join(s.prefix, url.PathEscape(schema), url.PathEscape(table))
+}
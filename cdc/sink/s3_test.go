@@ -0,0 +1,151 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util"
+)
+
+// memStorage is an in-process fake of the object storage
+type memStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (m *memStorage) PutObject(ctx context.Context, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memStorage) ObjectExists(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.objects[key]
+	return ok, nil
+}
+
+func (m *memStorage) keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.objects))
+	for key := range m.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type s3SinkSuite struct{}
+
+var _ = check.Suite(&s3SinkSuite{})
+
+func newS3SinkForTest(c *check.C, uri string) (*s3Sink, *memStorage) {
+	filter, err := util.NewFilter(&util.ReplicaConfig{})
+	c.Assert(err, check.IsNil)
+	sinkURI, err := url.Parse(uri)
+	c.Assert(err, check.IsNil)
+	storage := &memStorage{objects: make(map[string][]byte)}
+	s, err := newS3SinkWithStorage(storage, sinkURI, filter, nil)
+	c.Assert(err, check.IsNil)
+	return s, storage
+}
+
+func (s *s3SinkSuite) TestFlushRows(c *check.C) {
+	ctx := context.Background()
+	sink, storage := newS3SinkForTest(c, "s3://bucket/prefix/?max-object-size=200")
+	rows := map[string][]*model.RowChangedEvent{
+		"`test`.`t1`": {
+			{Ts: 10, Schema: "test", Table: "t1", Type: model.InsertDMLType, Columns: map[string]*model.Column{"id": {Value: 1}}},
+			{Ts: 11, Schema: "test", Table: "t1", Type: model.InsertDMLType, Columns: map[string]*model.Column{"id": {Value: 2}}},
+		},
+		"`test`.`t2`": {
+			{Ts: 11, Schema: "test", Table: "t2", Type: model.InsertDMLType, Columns: map[string]*model.Column{"id": {Value: 1}}},
+		},
+	}
+	// the batch of t1 is large enough to be put, and t2 is kept in memory
+	checkpointTs, err := sink.flushRows(ctx, 12, rows)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpointTs, check.Equals, uint64(10))
+	c.Assert(storage.keys(), check.DeepEquals, []string{"prefix/test/t1/cdc-10-11.json"})
+
+	// the batch of t2 is put after the flush interval
+	sink.batches["`test`.`t2`"].startTime = time.Now().Add(-sink.flushInterval)
+	checkpointTs, err = sink.flushRows(ctx, 13, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpointTs, check.Equals, uint64(13))
+	c.Assert(storage.keys(), check.DeepEquals, []string{"prefix/test/t1/cdc-10-11.json", "prefix/test/t2/cdc-11-11.json"})
+	c.Assert(sink.batches, check.HasLen, 0)
+}
+
+func (s *s3SinkSuite) TestDDLAndCheckpoint(c *check.C) {
+	ctx := context.Background()
+	sink, storage := newS3SinkForTest(c, "s3://bucket?format=csv")
+	err := sink.EmitDDLEvent(ctx, &model.DDLEvent{Ts: 20, Schema: "test", Table: "t", Query: "truncate table t"})
+	c.Assert(err, check.IsNil)
+	c.Assert(sink.EmitCheckpointEvent(ctx, 30), check.IsNil)
+	c.Assert(storage.keys(), check.DeepEquals, []string{"checkpoint", "test/t/ddl-20.csv"})
+	c.Assert(string(storage.objects["test/t/ddl-20.csv"]), check.Equals, "commit-ts,query\n20,truncate table t\n")
+	c.Assert(string(storage.objects["checkpoint"]), check.Equals, `{"checkpoint-ts":30}`)
+}
+
+func (s *s3SinkSuite) TestHeaderChangedInSameTs(c *check.C) {
+	ctx := context.Background()
+	sink, storage := newS3SinkForTest(c, "s3://bucket?format=csv")
+	rows := map[string][]*model.RowChangedEvent{
+		"`test`.`t`": {
+			{Ts: 10, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: map[string]*model.Column{"id": {Value: 1}}},
+			{Ts: 10, Schema: "test", Table: "t", Type: model.InsertDMLType, Columns: map[string]*model.Column{"id": {Value: 2}, "name": {Value: "a"}}},
+		},
+	}
+	_, err := sink.flushRows(ctx, 10, rows)
+	c.Assert(err, check.IsNil)
+	sink.batches["`test`.`t`"].startTime = time.Now().Add(-sink.flushInterval)
+	_, err = sink.flushRows(ctx, 11, nil)
+	c.Assert(err, check.IsNil)
+
+	// the object of the new header doesn't overwrite the previous one
+	c.Assert(storage.keys(), check.DeepEquals, []string{"test/t/cdc-10-10-1.csv", "test/t/cdc-10-10.csv"})
+	c.Assert(string(storage.objects["test/t/cdc-10-10.csv"]), check.Equals, "op,commit-ts,id\nI,10,1\n")
+	c.Assert(string(storage.objects["test/t/cdc-10-10-1.csv"]), check.Equals, "op,commit-ts,id,name\nI,10,2,a\n")
+}
+
+func (s *s3SinkSuite) TestCredential(c *check.C) {
+	_, err := newS3Storage("bucket", url.Values{"access-key": {"ak"}, "secret-access-key": {"sk"}})
+	c.Assert(err, check.ErrorMatches, ".*can not be in the uri.*")
+	_, err = newS3Storage("bucket", url.Values{"access-key": {"ak"}})
+	c.Assert(err, check.ErrorMatches, ".*must be set together.*")
+	_, err = newS3Storage("bucket", url.Values{"access-key": {"ak"}, "secret-access-key-file": {"/not-exist"}})
+	c.Assert(err, check.ErrorMatches, ".*read secret access key file.*")
+
+	secretFile := filepath.Join(c.MkDir(), "secret")
+	c.Assert(ioutil.WriteFile(secretFile, []byte("sk\n"), 0600), check.IsNil)
+	storage, err := newS3Storage("bucket", url.Values{"access-key": {"ak"}, "secret-access-key-file": {secretFile}})
+	c.Assert(err, check.IsNil)
+	value, err := storage.client.Config.Credentials.Get()
+	c.Assert(err, check.IsNil)
+	c.Assert(value.AccessKeyID, check.Equals, "ak")
+	c.Assert(value.SecretAccessKey, check.Equals, "sk")
+}
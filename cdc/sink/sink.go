@@ -23,6 +23,7 @@ import (
 	dmysql "github.com/go-sql-driver/mysql"
 	"github.com/pingcap/errors"
 
+	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/model"
 )
 
@@ -50,8 +51,33 @@ type Sink interface {
 	PrintStatus(ctx context.Context) error
 }
 
+// TableAwareSink is implemented by the sinks sending the events of the tables to
+// different places, the owner tells it the tables of the changefeed when they change.
+type TableAwareSink interface {
+	// UpdateTables updates the tables of the changefeed
+	UpdateTables(tables []entry.TableName)
+}
+
+// ValidateSinkURI checks that the sink-uri can be parsed and its scheme is
+// supported, without connecting to the downstream
+func ValidateSinkURI(sinkURIStr string) error {
+	sinkURI, err := url.Parse(sinkURIStr)
+	if err != nil {
+		if _, err := dmysql.ParseDSN(sinkURIStr); err != nil {
+			return errors.Annotatef(err, "parse sinkURI failed")
+		}
+		return nil
+	}
+	switch strings.ToLower(sinkURI.Scheme) {
+	case "blackhole", "mysql", "tidb", "kafka", "pulsar", "pulsar+ssl", "file", "local", "s3":
+		return nil
+	default:
+		return errors.Errorf("the sink scheme (%s) is not supported", sinkURI.Scheme)
+	}
+}
+
 // NewSink creates a new sink with the sink-uri
-func NewSink(sinkURIStr string, filter *util.Filter, opts map[string]string) (Sink, error) {
+func NewSink(sinkURIStr string, filter *util.Filter, config *util.ReplicaConfig, opts map[string]string) (Sink, error) {
 	sinkURI, err := url.Parse(sinkURIStr)
 	if err != nil {
 		// try to parse the sinkURI as DSN
@@ -67,7 +93,13 @@ func NewSink(sinkURIStr string, filter *util.Filter, opts map[string]string) (Si
 	case "mysql", "tidb":
 		return newMySQLSink(sinkURI, nil, filter, opts)
 	case "kafka":
-		return newKafkaSaramaSink(sinkURI, filter, opts)
+		return newKafkaSaramaSink(sinkURI, filter, config, opts)
+	case "pulsar", "pulsar+ssl":
+		return newPulsarSink(sinkURI, filter, config, opts)
+	case "file", "local":
+		return newFileSink(sinkURI, filter, opts)
+	case "s3":
+		return newS3Sink(sinkURI, filter, opts)
 	default:
 		return nil, errors.Errorf("the sink scheme (%s) is not supported", sinkURI.Scheme)
 	}
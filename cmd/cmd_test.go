@@ -74,3 +74,19 @@ func (s *decodeFileSuite) TestShouldReturnErrForUnknownCfgs(c *check.C) {
 	c.Assert(err, check.NotNil)
 	c.Assert(err, check.ErrorMatches, ".*unknown config.*")
 }
+
+type changefeedCommandSuite struct{}
+
+var _ = check.Suite(&changefeedCommandSuite{})
+
+func (s *changefeedCommandSuite) TestParseOpt(c *check.C) {
+	key, value := parseOpt("max-txn-row=10")
+	c.Assert(key, check.Equals, "max-txn-row")
+	c.Assert(value, check.Equals, "10")
+	key, value = parseOpt("key=a=b")
+	c.Assert(key, check.Equals, "key")
+	c.Assert(value, check.Equals, "a=b")
+	key, value = parseOpt("key")
+	c.Assert(key, check.Equals, "key")
+	c.Assert(value, check.Equals, "")
+}
@@ -16,20 +16,37 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/cdc/roles"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/pingcap/tidb/store/tikv/oracle"
 	"github.com/spf13/cobra"
 	"go.etcd.io/etcd/clientv3/concurrency"
 )
 
+const (
+	// ownerAckTimeout is the max duration to wait for the owner to acknowledge a request
+	ownerAckTimeout       = 30 * time.Second
+	ownerAckCheckInterval = 500 * time.Millisecond
+	ownerRequestTimeout   = 10 * time.Second
+)
+
 var (
-	changefeedID string
-	captureID    string
+	changefeedID  string
+	captureID     string
+	tableID       uint64
+	targetCapture string
 )
 
 // cf holds changefeed id, which is used for output only
@@ -195,3 +212,293 @@ func newDeleteMetaCommand() *cobra.Command {
 	}
 	return command
 }
+
+func newResignOwnerCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "resign-owner",
+		Short: "Resign the owner of TiCDC cluster, a new owner will be elected among the captures",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			owner, err := getOwnerCapture(ctx)
+			if err != nil {
+				return err
+			}
+			err = sendOwnerRequest(owner, "/capture/owner/resign", nil)
+			if err != nil {
+				return err
+			}
+			var newOwnerID string
+			err = waitOwnerAck(ctx, func() (bool, error) {
+				newOwnerID, err = roles.GetOwnerID(ctx, cdcEtcdCli, kv.CaptureOwnerKey)
+				if errors.Cause(err) == concurrency.ErrElectionNoLeader {
+					return false, nil
+				}
+				return err == nil, err
+			})
+			if err != nil {
+				return err
+			}
+			cmd.Printf("owner %s resigned, the current owner is %s\n", owner.ID, newOwnerID)
+			return nil
+		},
+	}
+	return command
+}
+
+func newAdminChangefeedCommand(use, short string, job model.AdminJobType) *cobra.Command {
+	command := &cobra.Command{
+		Use:   use,
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			info, err := cdcEtcdCli.GetChangeFeedInfo(ctx, changefeedID)
+			if err != nil {
+				return err
+			}
+			if err := info.VerifyAdminJob(job); err != nil {
+				return errors.Annotatef(err, "changefeed %s", changefeedID)
+			}
+			owner, err := getOwnerCapture(ctx)
+			if err != nil {
+				return err
+			}
+			form := url.Values{
+				"admin-job": {strconv.Itoa(int(job))},
+				"cf-id":     {changefeedID},
+			}
+			err = sendOwnerRequest(owner, "/capture/owner/admin", form)
+			if err != nil {
+				return err
+			}
+			err = waitOwnerAck(ctx, func() (bool, error) {
+				return isAdminJobApplied(ctx, changefeedID, job)
+			})
+			if err != nil {
+				return err
+			}
+			cmd.Printf("%s %s successfully\n", job, changefeedID)
+			return nil
+		},
+	}
+	command.PersistentFlags().StringVar(&changefeedID, "changefeed-id", "", "Replication task (changefeed) ID")
+	return command
+}
+
+// isAdminJobApplied checks whether the owner has applied the admin job to the changefeed
+func isAdminJobApplied(ctx context.Context, id string, job model.AdminJobType) (bool, error) {
+	if job == model.AdminRemove {
+		_, err := cdcEtcdCli.GetChangeFeedInfo(ctx, id)
+		if errors.Cause(err) == model.ErrChangeFeedNotExists {
+			return true, nil
+		}
+		return false, err
+	}
+	status, err := cdcEtcdCli.GetChangeFeedStatus(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return status.AdminJobType == job, nil
+}
+
+func newUpdateChangefeedCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "update",
+		Short: "Update the sink uri, opts and filter config of a paused replication task (changefeed)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			info, modRevision, err := cdcEtcdCli.GetChangeFeedInfoWithRevision(ctx, changefeedID)
+			if err != nil {
+				return err
+			}
+			if info.AdminJobType != model.AdminStop {
+				return errors.Errorf("changefeed %s must be paused before updating", changefeedID)
+			}
+			if cmd.Flags().Changed("sink-uri") {
+				if err := sink.ValidateSinkURI(sinkURI); err != nil {
+					return err
+				}
+				info.SinkURI = sinkURI
+			}
+			if cmd.Flags().Changed("opts") {
+				info.Opts = make(map[string]string)
+				for _, opt := range opts {
+					key, value := parseOpt(opt)
+					info.Opts[key] = value
+				}
+			}
+			if cmd.Flags().Changed("config") {
+				cfg := new(util.ReplicaConfig)
+				if err := strictDecodeFile(configFile, "cdc", cfg); err != nil {
+					return err
+				}
+				if _, err := util.NewFilter(cfg); err != nil {
+					return err
+				}
+				info.Config = cfg
+			}
+			d, err := info.Marshal()
+			if err != nil {
+				return err
+			}
+			newRevision, err := cdcEtcdCli.UpdateChangeFeedInfo(ctx, info, changefeedID, modRevision)
+			if err != nil {
+				return err
+			}
+			// the owner may fail or resume the changefeed at the same time, make
+			// sure it still keeps the changefeed paused with the updated info
+			err = waitOwnerAck(ctx, func() (bool, error) {
+				_, revision, err := cdcEtcdCli.GetChangeFeedInfoWithRevision(ctx, changefeedID)
+				if err != nil {
+					return false, err
+				}
+				if revision != newRevision {
+					return false, errors.Annotatef(model.ErrChangeFeedInfoConflict, "changefeed %s", changefeedID)
+				}
+				return isAdminJobApplied(ctx, changefeedID, model.AdminStop)
+			})
+			if err != nil {
+				return err
+			}
+			cmd.Printf("update changefeed ID: %s info %s\n", changefeedID, d)
+			return nil
+		},
+	}
+	command.PersistentFlags().StringVar(&changefeedID, "changefeed-id", "", "Replication task (changefeed) ID")
+	command.PersistentFlags().StringVar(&sinkURI, "sink-uri", "", "sink uri")
+	command.PersistentFlags().StringVar(&configFile, "config", "", "Path of the configuration file")
+	command.PersistentFlags().StringSliceVar(&opts, "opts", nil, "Extra options, in the `key=value` format")
+	return command
+}
+
+func newMoveTableCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "move-table",
+		Short: "Move a table of a replication task (changefeed) to the target capture",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			info, err := cdcEtcdCli.GetChangeFeedInfo(ctx, changefeedID)
+			if err != nil {
+				return err
+			}
+			if err := info.VerifyAdminJob(model.AdminMoveTable); err != nil {
+				return errors.Annotatef(err, "changefeed %s", changefeedID)
+			}
+			if _, err := cdcEtcdCli.GetCaptureInfo(ctx, targetCapture); err != nil {
+				return errors.Annotatef(err, "target capture %s", targetCapture)
+			}
+			owner, err := getOwnerCapture(ctx)
+			if err != nil {
+				return err
+			}
+			form := url.Values{
+				"cf-id":        {changefeedID},
+				"table-id":     {strconv.FormatUint(tableID, 10)},
+				"target-cp-id": {targetCapture},
+			}
+			err = sendOwnerRequest(owner, "/capture/owner/move_table", form)
+			if err != nil {
+				return err
+			}
+			err = waitOwnerAck(ctx, func() (bool, error) {
+				return isTableOnCapture(ctx, changefeedID, targetCapture, tableID)
+			})
+			if err != nil {
+				return err
+			}
+			cmd.Printf("move table %d of %s to capture %s successfully\n", tableID, changefeedID, targetCapture)
+			return nil
+		},
+	}
+	command.PersistentFlags().StringVar(&changefeedID, "changefeed-id", "", "Replication task (changefeed) ID")
+	command.PersistentFlags().Uint64Var(&tableID, "table-id", 0, "ID of the table to move")
+	command.PersistentFlags().StringVar(&targetCapture, "target-capture", "", "ID of the capture the table is moved to")
+	return command
+}
+
+// isTableOnCapture checks whether the table is dispatched to the capture
+func isTableOnCapture(ctx context.Context, id string, captureID string, tableID uint64) (bool, error) {
+	_, status, err := cdcEtcdCli.GetTaskStatus(ctx, id, captureID)
+	if err != nil {
+		if errors.Cause(err) == model.ErrTaskStatusNotExists {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, table := range status.TableInfos {
+		if table.ID == tableID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getOwnerCapture returns the capture info of the owner
+func getOwnerCapture(ctx context.Context) (*model.CaptureInfo, error) {
+	ownerID, err := roles.GetOwnerID(ctx, cdcEtcdCli, kv.CaptureOwnerKey)
+	if err != nil {
+		return nil, errors.Annotate(err, "get owner id")
+	}
+	_, captures, err := cdcEtcdCli.GetCaptures(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, c := range captures {
+		if c.ID == ownerID {
+			return c, nil
+		}
+	}
+	return nil, errors.Errorf("owner %s not found in captures", ownerID)
+}
+
+// sendOwnerRequest posts the form to the status server of the owner
+func sendOwnerRequest(owner *model.CaptureInfo, path string, form url.Values) error {
+	if owner.AdvertiseAddr == "" {
+		return errors.Errorf("the address of owner %s is unknown", owner.ID)
+	}
+	credential := getCredential()
+	client := &http.Client{Timeout: ownerRequestTimeout}
+	scheme := "http"
+	if credential.IsTLSEnabled() {
+		tlsConfig, err := credential.ToTLSConfig()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+		scheme = "https"
+	}
+	resp, err := client.PostForm(fmt.Sprintf("%s://%s%s", scheme, owner.AdvertiseAddr, path), form)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("owner %s responded %s: %s", owner.ID, resp.Status, body)
+	}
+	return nil
+}
+
+// waitOwnerAck waits until check returns true or the owner doesn't acknowledge in time
+func waitOwnerAck(ctx context.Context, check func() (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, ownerAckTimeout)
+	defer cancel()
+	ticker := time.NewTicker(ownerAckCheckInterval)
+	defer ticker.Stop()
+	for {
+		done, err := check()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.New("timeout waiting for the owner to acknowledge")
+		case <-ticker.C:
+		}
+	}
+}
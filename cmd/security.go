@@ -0,0 +1,53 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/pingcap/ticdc/pkg/security"
+	"github.com/spf13/cobra"
+)
+
+var (
+	caPath        string
+	certPath      string
+	keyPath       string
+	allowedCertCN string
+)
+
+// addSecurityFlags adds the flags of TLS credential to the command,
+// the allowed common names are only used by the server.
+func addSecurityFlags(command *cobra.Command, isServer bool) {
+	command.PersistentFlags().StringVar(&caPath, "ca", "", "CA certificate path for TLS connection")
+	command.PersistentFlags().StringVar(&certPath, "cert", "", "Certificate path for TLS connection")
+	command.PersistentFlags().StringVar(&keyPath, "key", "", "Private key path for TLS connection")
+	if isServer {
+		command.PersistentFlags().StringVar(&allowedCertCN, "cert-allowed-cn", "", "Verify caller's identity "+
+			"(cert Common Name). Use `,` to separate multiple CN")
+	}
+}
+
+func getCredential() *security.Credential {
+	var certAllowedCN []string
+	if len(allowedCertCN) != 0 {
+		certAllowedCN = strings.Split(allowedCertCN, ",")
+	}
+	return &security.Credential{
+		CAPath:        caPath,
+		CertPath:      certPath,
+		KeyPath:       keyPath,
+		CertAllowedCN: certAllowedCN,
+	}
+}
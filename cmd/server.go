@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
@@ -17,8 +18,11 @@ import (
 )
 
 var (
-	serverPdAddr string
-	statusAddr   string
+	serverPdAddr  string
+	statusAddr    string
+	advertiseAddr string
+	gcTTL         int64
+	drainTimeout  time.Duration
 
 	serverCmd = &cobra.Command{
 		Use:              "server",
@@ -33,6 +37,10 @@ func init() {
 
 	serverCmd.Flags().StringVar(&serverPdAddr, "pd", "http://127.0.0.1:2379", "PD address, separated by comma")
 	serverCmd.Flags().StringVar(&statusAddr, "status-addr", "127.0.0.1:8300", "Bind address for http status server")
+	serverCmd.Flags().StringVar(&advertiseAddr, "advertise-addr", "", "Status address for the clients to access, status-addr is used if not specified")
+	serverCmd.Flags().Int64Var(&gcTTL, "gc-ttl", cdc.DefaultGCTTL, "CDC GC safepoint TTL duration in seconds, the safepoint is not maintained if it's 0")
+	serverCmd.Flags().DurationVar(&drainTimeout, "drain-timeout", time.Minute, "Max duration to wait for the other captures to take over the tables on SIGTERM, 0 to exit immediately")
+	addSecurityFlags(serverCmd, true /* isServer */)
 }
 
 func preRunLogInfo(cmd *cobra.Command, args []string) {
@@ -50,7 +58,8 @@ func runEServer(cmd *cobra.Command, args []string) error {
 	}
 
 	var opts []cdc.ServerOption
-	opts = append(opts, cdc.PDEndpoints(serverPdAddr), cdc.StatusHost(addrs[0]), cdc.StatusPort(int(statusPort)))
+	opts = append(opts, cdc.PDEndpoints(serverPdAddr), cdc.StatusHost(addrs[0]), cdc.StatusPort(int(statusPort)),
+		cdc.AdvertiseAddr(advertiseAddr), cdc.GCTTL(gcTTL), cdc.Credential(getCredential()))
 
 	server, err := cdc.NewServer(opts...)
 	if err != nil {
@@ -68,6 +77,14 @@ func runEServer(cmd *cobra.Command, args []string) error {
 	go func() {
 		sig := <-sc
 		log.Info("got signal to exit", zap.Stringer("signal", sig))
+		if sig == syscall.SIGTERM && drainTimeout > 0 {
+			drainCtx, drainCancel := context.WithTimeout(ctx, drainTimeout)
+			err := server.Drain(drainCtx)
+			drainCancel()
+			if err != nil {
+				log.Warn("drain capture failed", zap.Error(err))
+			}
+		}
 		cancel()
 	}()
 
@@ -5,7 +5,7 @@ import (
 	"os"
 	"strings"
 
-	pd "github.com/pingcap/pd/client"
+	pd "github.com/pingcap/pd/v4/client"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/spf13/cobra"
 )
@@ -18,6 +18,7 @@ func init() {
 	rootCmd.AddCommand(testKVCmd)
 
 	testKVCmd.Flags().StringVar(&testPdAddr, "pd", "http://127.0.0.1:2379", "address of PD")
+	addSecurityFlags(testKVCmd, false /* isServer */)
 }
 
 type testingT struct {
@@ -40,7 +41,7 @@ var testKVCmd = &cobra.Command{
 	Long:   ``,
 	Run: func(cmd *cobra.Command, args []string) {
 		addrs := strings.Split(testPdAddr, ",")
-		cli, err := pd.NewClient(addrs, pd.SecurityOption{})
+		cli, err := pd.NewClient(addrs, getCredential().PDSecurityOption())
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -3,14 +3,12 @@ package main
 import (
 	"context"
 	"flag"
-	"math"
 	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -20,8 +18,8 @@ import (
 	"github.com/Shopify/sarama"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
-	"github.com/pingcap/ticdc/cdc/model"
-	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/cdc/sink/mqConsumer"
+	"github.com/pingcap/ticdc/cdc/sink/mqProducer"
 )
 
 // Sarama configuration options
@@ -31,6 +29,7 @@ var (
 	kafkaPartitionNum int32
 	kafkaGroupID      = "ticdc_kafka_consumer"
 	kafkaVersion      = "2.4.0"
+	kafkaSecurity     *mqProducer.KafkaSecurityConfig
 
 	downstreamURIStr string
 
@@ -80,6 +79,11 @@ func init() {
 	if s != "" {
 		kafkaGroupID = s
 	}
+	// the SASL and TLS parameters are the same as the sink-uri of the kafka sink
+	kafkaSecurity, err = mqProducer.NewKafkaSecurityConfig(upstreamURI.Query())
+	if err != nil {
+		log.Fatal("invalid security config of upstream-uri", zap.Error(err))
+	}
 	kafkaTopic = strings.TrimFunc(upstreamURI.Path, func(r rune) bool {
 		return r == '/'
 	})
@@ -95,6 +99,8 @@ func newSaramaConfig() (*sarama.Config, error) {
 	}
 
 	config.ClientID = "ticdc_kafka_sarama_consumer"
+	// the messages compressed by the producer are decompressed by sarama, the
+	// version must be 2.1.0 or later to consume the messages compressed by zstd
 	config.Version = version
 
 	config.Metadata.Retry.Max = 10000
@@ -102,6 +108,11 @@ func newSaramaConfig() (*sarama.Config, error) {
 	config.Consumer.Retry.Backoff = 500 * time.Millisecond
 	config.Consumer.Offsets.Initial = sarama.OffsetOldest
 
+	if kafkaSecurity != nil {
+		if err := kafkaSecurity.Apply(config); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
 	return config, err
 }
 
@@ -176,52 +187,18 @@ func main() {
 
 // Consumer represents a Sarama consumer group consumer
 type Consumer struct {
-	ready chan bool
-
-	ddlList          []*model.DDLEvent
-	maxDDLReceivedTs uint64
-	ddlListMu        sync.Mutex
-
-	sinks []*struct {
-		sink.Sink
-		resolvedTs uint64
-	}
-	sinksMu sync.Mutex
+	*mqConsumer.Consumer
 
-	ddlSink sink.Sink
-
-	globalResolvedTs uint64
+	ready chan bool
 }
 
 // NewConsumer creates a new cdc kafka consumer
 func NewConsumer() (*Consumer, error) {
-	// TODO support filter in downstream sink
-	filter, err := util.NewFilter(&util.ReplicaConfig{})
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
-	c := new(Consumer)
-	c.sinks = make([]*struct {
-		sink.Sink
-		resolvedTs uint64
-	}, kafkaPartitionNum)
-	for i := 0; i < int(kafkaPartitionNum); i++ {
-		s, err := sink.NewSink(downstreamURIStr, filter, nil)
-		if err != nil {
-			return nil, errors.Trace(err)
-		}
-		c.sinks[i] = &struct {
-			sink.Sink
-			resolvedTs uint64
-		}{Sink: s}
-	}
-	sink, err := sink.NewSink(downstreamURIStr, filter, nil)
+	c, err := mqConsumer.NewConsumer(downstreamURIStr, kafkaPartitionNum)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	c.ddlSink = sink
-	c.ready = make(chan bool)
-	return c, nil
+	return &Consumer{Consumer: c, ready: make(chan bool)}, nil
 }
 
 // Setup is run at the beginning of a new session, before ConsumeClaim
@@ -239,205 +216,12 @@ func (c *Consumer) Cleanup(sarama.ConsumerGroupSession) error {
 // ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages().
 func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	ctx := context.TODO()
-	partition := claim.Partition()
-	c.sinksMu.Lock()
-	sink := c.sinks[partition]
-	c.sinksMu.Unlock()
-	if sink == nil {
-		panic("sink should initialized")
-	}
 	for message := range claim.Messages() {
-		log.Debug("Message claimed", zap.Int32("partition", message.Partition), zap.ByteString("key", message.Key), zap.ByteString("value", message.Value))
-		key := new(model.MqMessageKey)
-		err := key.Decode(message.Key)
-		if err != nil {
-			log.Fatal("decode message key failed", zap.Error(err))
-		}
-
-		switch key.Type {
-		case model.MqMessageTypeDDL:
-			value := new(model.MqMessageDDL)
-			err := value.Decode(message.Value)
-			if err != nil {
-				log.Fatal("decode message value failed", zap.ByteString("value", message.Value))
-			}
-
-			ddl := new(model.DDLEvent)
-			ddl.FromMqMessage(key, value)
-			c.appendDDL(ddl)
-		case model.MqMessageTypeRow:
-			globalResolvedTs := atomic.LoadUint64(&c.globalResolvedTs)
-			if key.Ts <= globalResolvedTs || key.Ts <= sink.resolvedTs {
-				log.Info("filter fallback row", zap.ByteString("row", message.Key),
-					zap.Uint64("globalResolvedTs", globalResolvedTs),
-					zap.Uint64("sinkResolvedTs", sink.resolvedTs))
-				break
-			}
-			value := new(model.MqMessageRow)
-			err := value.Decode(message.Value)
-			if err != nil {
-				log.Fatal("decode message value failed", zap.ByteString("value", message.Value))
-			}
-			row := new(model.RowChangedEvent)
-			row.FromMqMessage(key, value)
-			err = sink.EmitRowChangedEvent(ctx, row)
-			if err != nil {
-				log.Fatal("emit row changed event failed", zap.Error(err))
-			}
-		case model.MqMessageTypeResolved:
-			err := sink.EmitRowChangedEvent(ctx, &model.RowChangedEvent{Ts: key.Ts, Resolved: true})
-			if err != nil {
-				log.Fatal("meit row changed event failed", zap.Error(err))
-			}
-			resolvedTs := atomic.LoadUint64(&sink.resolvedTs)
-			if resolvedTs < key.Ts {
-				atomic.StoreUint64(&sink.resolvedTs, key.Ts)
-			}
+		if err := c.ConsumeMessage(ctx, message.Partition, message.Key, message.Value); err != nil {
+			log.Fatal("Error consuming message", zap.Int32("partition", message.Partition), zap.Int64("offset", message.Offset), zap.Error(err))
 		}
 		session.MarkMessage(message, "")
 	}
 
 	return nil
 }
-
-func (c *Consumer) appendDDL(ddl *model.DDLEvent) {
-	c.ddlListMu.Lock()
-	defer c.ddlListMu.Unlock()
-	if ddl.Ts <= c.maxDDLReceivedTs {
-		return
-	}
-	globalResolvedTs := atomic.LoadUint64(&c.globalResolvedTs)
-	if ddl.Ts <= globalResolvedTs {
-		log.Error("unexpected ddl job", zap.Uint64("ddlts", ddl.Ts), zap.Uint64("globalResolvedTs", globalResolvedTs))
-		return
-	}
-	c.ddlList = append(c.ddlList, ddl)
-	c.maxDDLReceivedTs = ddl.Ts
-}
-
-func (c *Consumer) getFrontDDL() *model.DDLEvent {
-	c.ddlListMu.Lock()
-	defer c.ddlListMu.Unlock()
-	if len(c.ddlList) > 0 {
-		return c.ddlList[0]
-	}
-	return nil
-}
-
-func (c *Consumer) popDDL() *model.DDLEvent {
-	c.ddlListMu.Lock()
-	defer c.ddlListMu.Unlock()
-	if len(c.ddlList) > 0 {
-		ddl := c.ddlList[0]
-		c.ddlList = c.ddlList[1:]
-		return ddl
-	}
-	return nil
-}
-
-func (c *Consumer) forEachSink(fn func(sink *struct {
-	sink.Sink
-	resolvedTs uint64
-}) error) error {
-	c.sinksMu.Lock()
-	defer c.sinksMu.Unlock()
-	for _, sink := range c.sinks {
-		if err := fn(sink); err != nil {
-			return errors.Trace(err)
-		}
-	}
-	return nil
-}
-
-// Run runs the Consumer
-func (c *Consumer) Run(ctx context.Context) error {
-	wg := &sync.WaitGroup{}
-	err := c.forEachSink(func(sink *struct {
-		sink.Sink
-		resolvedTs uint64
-	}) error {
-		wg.Add(1)
-		go func() {
-			if err := sink.Run(ctx); err != nil {
-				log.Fatal("sink running error", zap.Error(err))
-			}
-			wg.Done()
-		}()
-		return nil
-	})
-	if err != nil {
-		return errors.Trace(err)
-	}
-
-	var lastGlobalResolvedTs uint64
-	for {
-		select {
-		case <-ctx.Done():
-			wg.Wait()
-			return ctx.Err()
-		default:
-		}
-		time.Sleep(100 * time.Millisecond)
-		// handle ddl
-		globalCheckpointTs := uint64(math.MaxUint64)
-		err = c.forEachSink(func(sink *struct {
-			sink.Sink
-			resolvedTs uint64
-		}) error {
-			checkpointTs := sink.CheckpointTs()
-			if checkpointTs < globalCheckpointTs {
-				globalCheckpointTs = checkpointTs
-			}
-			return nil
-		})
-		if err != nil {
-			return errors.Trace(err)
-		}
-		todoDDL := c.getFrontDDL()
-		if todoDDL != nil && globalCheckpointTs == todoDDL.Ts {
-			// execute ddl
-			err := c.ddlSink.EmitDDLEvent(ctx, todoDDL)
-			if err != nil {
-				return errors.Trace(err)
-			}
-			c.popDDL()
-		}
-
-		//handle global resolvedTs
-		globalResolvedTs := uint64(math.MaxUint64)
-		err = c.forEachSink(func(sink *struct {
-			sink.Sink
-			resolvedTs uint64
-		}) error {
-			resolvedTs := atomic.LoadUint64(&sink.resolvedTs)
-			if resolvedTs < globalResolvedTs {
-				globalResolvedTs = resolvedTs
-			}
-			return nil
-		})
-		if err != nil {
-			return errors.Trace(err)
-		}
-
-		todoDDL = c.getFrontDDL()
-		if todoDDL != nil && todoDDL.Ts < globalResolvedTs {
-			globalResolvedTs = todoDDL.Ts
-		}
-		if lastGlobalResolvedTs == globalResolvedTs {
-			continue
-		}
-		lastGlobalResolvedTs = globalResolvedTs
-		atomic.StoreUint64(&c.globalResolvedTs, globalResolvedTs)
-		log.Debug("update globalResolvedTs", zap.Uint64("ts", globalResolvedTs))
-
-		err = c.forEachSink(func(sink *struct {
-			sink.Sink
-			resolvedTs uint64
-		}) error {
-			return sink.EmitResolvedEvent(ctx, globalResolvedTs)
-		})
-		if err != nil {
-			return errors.Trace(err)
-		}
-	}
-}
@@ -0,0 +1,135 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/pingcap/errors"
+	pd "github.com/pingcap/pd/v4/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Credential holds necessary fields for TLS connections
+type Credential struct {
+	CAPath        string   `toml:"ca-path" json:"ca-path"`
+	CertPath      string   `toml:"cert-path" json:"cert-path"`
+	KeyPath       string   `toml:"key-path" json:"key-path"`
+	CertAllowedCN []string `toml:"cert-allowed-cn" json:"cert-allowed-cn"`
+}
+
+// IsTLSEnabled checks whether TLS is enabled or not
+func (s *Credential) IsTLSEnabled() bool {
+	return s != nil && len(s.CAPath) != 0
+}
+
+// PDSecurityOption creates a new pd SecurityOption from Credential
+func (s *Credential) PDSecurityOption() pd.SecurityOption {
+	if !s.IsTLSEnabled() {
+		return pd.SecurityOption{}
+	}
+	return pd.SecurityOption{
+		CAPath:   s.CAPath,
+		CertPath: s.CertPath,
+		KeyPath:  s.KeyPath,
+	}
+}
+
+// ToGRPCDialOption constructs a gRPC dial option
+func (s *Credential) ToGRPCDialOption() (grpc.DialOption, error) {
+	tlsCfg, err := s.ToTLSConfig()
+	if err != nil || tlsCfg == nil {
+		return grpc.WithInsecure(), errors.Trace(err)
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)), nil
+}
+
+// ToTLSConfig generates the tls config for the clients, it returns nil if TLS is not enabled
+func (s *Credential) ToTLSConfig() (*tls.Config, error) {
+	if !s.IsTLSEnabled() {
+		return nil, nil
+	}
+	certPool, err := s.loadCA()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tlsCfg := &tls.Config{
+		RootCAs:    certPool,
+		MinVersion: tls.VersionTLS12,
+	}
+	if len(s.CertPath) != 0 && len(s.KeyPath) != 0 {
+		cert, err := tls.LoadX509KeyPair(s.CertPath, s.KeyPath)
+		if err != nil {
+			return nil, errors.Annotate(err, "load x509 key pair")
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// ToTLSConfigWithVerify generates the tls config for the servers, which requires
+// the clients to present certificates signed by the CA. If CertAllowedCN is not
+// empty, the common name of the client certificate must be one of them.
+func (s *Credential) ToTLSConfigWithVerify() (*tls.Config, error) {
+	if !s.IsTLSEnabled() {
+		return nil, nil
+	}
+	certPool, err := s.loadCA()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	cert, err := tls.LoadX509KeyPair(s.CertPath, s.KeyPath)
+	if err != nil {
+		return nil, errors.Annotate(err, "load x509 key pair")
+	}
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    certPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if len(s.CertAllowedCN) != 0 {
+		allowedCN := make(map[string]struct{}, len(s.CertAllowedCN))
+		for _, cn := range s.CertAllowedCN {
+			allowedCN[cn] = struct{}{}
+		}
+		tlsCfg.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			for _, chain := range verifiedChains {
+				if len(chain) == 0 {
+					continue
+				}
+				if _, ok := allowedCN[chain[0].Subject.CommonName]; ok {
+					return nil
+				}
+			}
+			return errors.New("client certificate authentication failed, the common name is not allowed")
+		}
+	}
+	return tlsCfg, nil
+}
+
+func (s *Credential) loadCA() (*x509.CertPool, error) {
+	ca, err := ioutil.ReadFile(s.CAPath)
+	if err != nil {
+		return nil, errors.Annotatef(err, "read ca file %s", s.CAPath)
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(ca) {
+		return nil, errors.Errorf("failed to append ca certs from %s", s.CAPath)
+	}
+	return certPool, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"testing"
+
+	"github.com/pingcap/check"
+	pd "github.com/pingcap/pd/v4/client"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type credentialSuite struct{}
+
+var _ = check.Suite(&credentialSuite{})
+
+func (s *credentialSuite) TestTLSDisabled(c *check.C) {
+	var nilCredential *Credential
+	for _, credential := range []*Credential{nilCredential, {}} {
+		c.Assert(credential.IsTLSEnabled(), check.IsFalse)
+		c.Assert(credential.PDSecurityOption(), check.DeepEquals, pd.SecurityOption{})
+		tlsCfg, err := credential.ToTLSConfig()
+		c.Assert(err, check.IsNil)
+		c.Assert(tlsCfg, check.IsNil)
+		tlsCfg, err = credential.ToTLSConfigWithVerify()
+		c.Assert(err, check.IsNil)
+		c.Assert(tlsCfg, check.IsNil)
+		_, err = credential.ToGRPCDialOption()
+		c.Assert(err, check.IsNil)
+	}
+}
+
+func (s *credentialSuite) TestTLSEnabled(c *check.C) {
+	credential := &Credential{
+		CAPath:   "/path/not/exist/ca.pem",
+		CertPath: "/path/not/exist/cert.pem",
+		KeyPath:  "/path/not/exist/key.pem",
+	}
+	c.Assert(credential.IsTLSEnabled(), check.IsTrue)
+	c.Assert(credential.PDSecurityOption(), check.DeepEquals, pd.SecurityOption{
+		CAPath:   "/path/not/exist/ca.pem",
+		CertPath: "/path/not/exist/cert.pem",
+		KeyPath:  "/path/not/exist/key.pem",
+	})
+	_, err := credential.ToTLSConfig()
+	c.Assert(err, check.ErrorMatches, ".*read ca file.*")
+	_, err = credential.ToTLSConfigWithVerify()
+	c.Assert(err, check.ErrorMatches, ".*read ca file.*")
+}
@@ -17,6 +17,11 @@ type ReplicaConfig struct {
 	FilterCaseSensitive bool          `toml:"filter-case-sensitive" json:"filter-case-sensitive"`
 	FilterRules         *filter.Rules `toml:"filter-rules" json:"filter-rules"`
 	IgnoreTxnCommitTs   []uint64      `toml:"ignore-txn-commit-ts" json:"ignore-txn-commit-ts"`
+	// EnableOldValue makes the row changed events carry the values before the change,
+	// the old value of each changed row is read from TiKV by a point get
+	EnableOldValue bool `toml:"enable-old-value" json:"enable-old-value"`
+	// Sink is the config of the sink, it is nil if not set
+	Sink *SinkConfig `toml:"sink" json:"sink"`
 }
 
 // NewFilter creates a filter
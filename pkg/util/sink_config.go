@@ -0,0 +1,50 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"github.com/pingcap/tidb-tools/pkg/filter"
+)
+
+// SinkConfig represents the config of the sink of a changefeed
+type SinkConfig struct {
+	// DispatchRules are the rules to dispatch the rows to the partitions of
+	// the MQ sink, the first rule matching the table of a row is used.
+	DispatchRules []*DispatchRule `toml:"dispatch-rules" json:"dispatch-rules"`
+	// TopicRules are the rules to dispatch the events to the topics of the
+	// Kafka sink, the tables matching no rule use the topic of sink-uri.
+	TopicRules []*TopicRule `toml:"topic-rules" json:"topic-rules"`
+}
+
+// DispatchRule represents the dispatcher of the tables matched by the rule
+type DispatchRule struct {
+	// Matcher selects the tables in the syntax of the filter rules
+	Matcher *filter.Rules `toml:"matcher" json:"matcher"`
+	// Dispatcher is one of `default`, `table`, `primary-key`, `ts`, `columns`
+	// and `partition`.
+	Dispatcher string `toml:"dispatcher" json:"dispatcher"`
+	// Columns are the columns hashed by the `columns` dispatcher
+	Columns []string `toml:"columns" json:"columns"`
+	// Partition is the partition of the `partition` dispatcher
+	Partition int32 `toml:"partition" json:"partition"`
+}
+
+// TopicRule represents the topic of the tables matched by the rule
+type TopicRule struct {
+	// Matcher selects the tables in the syntax of the filter rules
+	Matcher *filter.Rules `toml:"matcher" json:"matcher"`
+	// Topic is the topic name, in which `{schema}` and `{table}` are replaced
+	// by the schema and the table of the event.
+	Topic string `toml:"topic" json:"topic"`
+}
@@ -0,0 +1,273 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: CanalProtocol.proto
+
+package canal
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Compression int32
+
+const (
+	Compression_COMPRESSIONCOMPATIBLEPROTO2 Compression = 0
+	Compression_NONE                        Compression = 1
+	Compression_ZLIB                        Compression = 2
+	Compression_GZIP                        Compression = 3
+	Compression_LZF                         Compression = 4
+)
+
+var Compression_name = map[int32]string{
+	0: "COMPRESSIONCOMPATIBLEPROTO2",
+	1: "NONE",
+	2: "ZLIB",
+	3: "GZIP",
+	4: "LZF",
+}
+
+var Compression_value = map[string]int32{
+	"COMPRESSIONCOMPATIBLEPROTO2": 0,
+	"NONE":                        1,
+	"ZLIB":                        2,
+	"GZIP":                        3,
+	"LZF":                         4,
+}
+
+func (x Compression) String() string {
+	return proto.EnumName(Compression_name, int32(x))
+}
+
+func (Compression) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_638f57be23f1b015, []int{0}
+}
+
+type PacketType int32
+
+const (
+	PacketType_PACKAGETYPECOMPATIBLEPROTO2 PacketType = 0
+	PacketType_HANDSHAKE                   PacketType = 1
+	PacketType_CLIENTAUTHENTICATION        PacketType = 2
+	PacketType_ACK                         PacketType = 3
+	PacketType_SUBSCRIPTION                PacketType = 4
+	PacketType_UNSUBSCRIPTION              PacketType = 5
+	PacketType_GET                         PacketType = 6
+	PacketType_MESSAGES                    PacketType = 7
+	PacketType_CLIENTACK                   PacketType = 8
+	PacketType_SHUTDOWN                    PacketType = 9
+	PacketType_DUMP                        PacketType = 10
+	PacketType_HEARTBEAT                   PacketType = 11
+	PacketType_CLIENTROLLBACK              PacketType = 12
+)
+
+var PacketType_name = map[int32]string{
+	0:  "PACKAGETYPECOMPATIBLEPROTO2",
+	1:  "HANDSHAKE",
+	2:  "CLIENTAUTHENTICATION",
+	3:  "ACK",
+	4:  "SUBSCRIPTION",
+	5:  "UNSUBSCRIPTION",
+	6:  "GET",
+	7:  "MESSAGES",
+	8:  "CLIENTACK",
+	9:  "SHUTDOWN",
+	10: "DUMP",
+	11: "HEARTBEAT",
+	12: "CLIENTROLLBACK",
+}
+
+var PacketType_value = map[string]int32{
+	"PACKAGETYPECOMPATIBLEPROTO2": 0,
+	"HANDSHAKE":                   1,
+	"CLIENTAUTHENTICATION":        2,
+	"ACK":                         3,
+	"SUBSCRIPTION":                4,
+	"UNSUBSCRIPTION":              5,
+	"GET":                         6,
+	"MESSAGES":                    7,
+	"CLIENTACK":                   8,
+	"SHUTDOWN":                    9,
+	"DUMP":                        10,
+	"HEARTBEAT":                   11,
+	"CLIENTROLLBACK":              12,
+}
+
+func (x PacketType) String() string {
+	return proto.EnumName(PacketType_name, int32(x))
+}
+
+func (PacketType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_638f57be23f1b015, []int{1}
+}
+
+type Packet struct {
+	MagicNumber          int32       `protobuf:"varint,1,opt,name=magicNumber,proto3" json:"magicNumber,omitempty"`
+	Version              int32       `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Type                 PacketType  `protobuf:"varint,3,opt,name=type,proto3,enum=com.alibaba.otter.canal.protocol.PacketType" json:"type,omitempty"`
+	Compression          Compression `protobuf:"varint,4,opt,name=compression,proto3,enum=com.alibaba.otter.canal.protocol.Compression" json:"compression,omitempty"`
+	Body                 []byte      `protobuf:"bytes,5,opt,name=body,proto3" json:"body,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *Packet) Reset()         { *m = Packet{} }
+func (m *Packet) String() string { return proto.CompactTextString(m) }
+func (*Packet) ProtoMessage()    {}
+func (*Packet) Descriptor() ([]byte, []int) {
+	return fileDescriptor_638f57be23f1b015, []int{0}
+}
+
+func (m *Packet) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Packet.Unmarshal(m, b)
+}
+func (m *Packet) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Packet.Marshal(b, m, deterministic)
+}
+func (m *Packet) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Packet.Merge(m, src)
+}
+func (m *Packet) XXX_Size() int {
+	return xxx_messageInfo_Packet.Size(m)
+}
+func (m *Packet) XXX_DiscardUnknown() {
+	xxx_messageInfo_Packet.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Packet proto.InternalMessageInfo
+
+func (m *Packet) GetMagicNumber() int32 {
+	if m != nil {
+		return m.MagicNumber
+	}
+	return 0
+}
+
+func (m *Packet) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Packet) GetType() PacketType {
+	if m != nil {
+		return m.Type
+	}
+	return PacketType_PACKAGETYPECOMPATIBLEPROTO2
+}
+
+func (m *Packet) GetCompression() Compression {
+	if m != nil {
+		return m.Compression
+	}
+	return Compression_COMPRESSIONCOMPATIBLEPROTO2
+}
+
+func (m *Packet) GetBody() []byte {
+	if m != nil {
+		return m.Body
+	}
+	return nil
+}
+
+type Messages struct {
+	BatchId              int64    `protobuf:"varint,1,opt,name=batchId,proto3" json:"batchId,omitempty"`
+	Messages             [][]byte `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Messages) Reset()         { *m = Messages{} }
+func (m *Messages) String() string { return proto.CompactTextString(m) }
+func (*Messages) ProtoMessage()    {}
+func (*Messages) Descriptor() ([]byte, []int) {
+	return fileDescriptor_638f57be23f1b015, []int{1}
+}
+
+func (m *Messages) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Messages.Unmarshal(m, b)
+}
+func (m *Messages) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Messages.Marshal(b, m, deterministic)
+}
+func (m *Messages) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Messages.Merge(m, src)
+}
+func (m *Messages) XXX_Size() int {
+	return xxx_messageInfo_Messages.Size(m)
+}
+func (m *Messages) XXX_DiscardUnknown() {
+	xxx_messageInfo_Messages.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Messages proto.InternalMessageInfo
+
+func (m *Messages) GetBatchId() int64 {
+	if m != nil {
+		return m.BatchId
+	}
+	return 0
+}
+
+func (m *Messages) GetMessages() [][]byte {
+	if m != nil {
+		return m.Messages
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("com.alibaba.otter.canal.protocol.Compression", Compression_name, Compression_value)
+	proto.RegisterEnum("com.alibaba.otter.canal.protocol.PacketType", PacketType_name, PacketType_value)
+	proto.RegisterType((*Packet)(nil), "com.alibaba.otter.canal.protocol.Packet")
+	proto.RegisterType((*Messages)(nil), "com.alibaba.otter.canal.protocol.Messages")
+}
+
+func init() { proto.RegisterFile("CanalProtocol.proto", fileDescriptor_638f57be23f1b015) }
+
+var fileDescriptor_638f57be23f1b015 = []byte{
+	// 453 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x92, 0x4f, 0x8f, 0x9b, 0x30,
+	0x10, 0xc5, 0x4b, 0x20, 0x7f, 0x76, 0xa0, 0x2b, 0xcb, 0xed, 0x01, 0xb5, 0x87, 0xa2, 0x3d, 0x45,
+	0xab, 0x96, 0xc3, 0xf6, 0xd2, 0xe3, 0x1a, 0xe2, 0x06, 0x14, 0x62, 0x90, 0x31, 0xaa, 0x9a, 0x1b,
+	0xb0, 0x68, 0x1b, 0x35, 0xac, 0x23, 0xa0, 0x95, 0xf2, 0x85, 0x7b, 0xe9, 0x97, 0xa8, 0xec, 0xec,
+	0x76, 0x53, 0xa9, 0x52, 0x6e, 0x6f, 0xfc, 0x66, 0x7e, 0xcf, 0x63, 0x19, 0x5e, 0x85, 0xe5, 0x43,
+	0xb9, 0xcb, 0x3a, 0x39, 0xc8, 0x5a, 0xee, 0xfc, 0xbd, 0x12, 0xd8, 0xab, 0x65, 0xeb, 0x97, 0xbb,
+	0x6d, 0x55, 0x56, 0xa5, 0x2f, 0x87, 0xa1, 0xe9, 0xfc, 0x5a, 0xb5, 0x1d, 0xed, 0x5a, 0xee, 0xae,
+	0x7e, 0x1b, 0x30, 0xc9, 0xca, 0xfa, 0x7b, 0x33, 0x60, 0x0f, 0xec, 0xb6, 0xbc, 0xdf, 0xd6, 0xec,
+	0x47, 0x5b, 0x35, 0x9d, 0x6b, 0x78, 0xc6, 0x7c, 0xcc, 0x4f, 0x8f, 0xb0, 0x0b, 0xd3, 0x9f, 0x4d,
+	0xd7, 0x6f, 0xe5, 0x83, 0x3b, 0xd2, 0xee, 0x53, 0x89, 0x6f, 0xc1, 0x1a, 0x0e, 0xfb, 0xc6, 0x35,
+	0x3d, 0x63, 0x7e, 0x79, 0xf3, 0xde, 0x3f, 0x97, 0xeb, 0x1f, 0x33, 0xc5, 0x61, 0xdf, 0x70, 0x3d,
+	0x89, 0x53, 0xb0, 0x6b, 0xd9, 0xee, 0xbb, 0xa6, 0xd7, 0x7c, 0x4b, 0x83, 0x3e, 0x9c, 0x07, 0x85,
+	0xcf, 0x43, 0xfc, 0x94, 0x80, 0x31, 0x58, 0x95, 0xbc, 0x3b, 0xb8, 0x63, 0xcf, 0x98, 0x3b, 0x5c,
+	0xeb, 0xab, 0x5b, 0x98, 0xad, 0x9b, 0xbe, 0x2f, 0xef, 0x9b, 0x5e, 0x2d, 0x53, 0x95, 0x43, 0xfd,
+	0x2d, 0xbe, 0xd3, 0xab, 0x9a, 0xfc, 0xa9, 0xc4, 0x6f, 0x60, 0xd6, 0x3e, 0x76, 0xb9, 0x23, 0xcf,
+	0x9c, 0x3b, 0xfc, 0x6f, 0x7d, 0x5d, 0x80, 0x7d, 0x92, 0x88, 0xdf, 0xc1, 0xdb, 0x30, 0x5d, 0x67,
+	0x9c, 0xe6, 0x79, 0x9c, 0x32, 0x25, 0x89, 0x88, 0x83, 0x84, 0x66, 0x3c, 0x15, 0xe9, 0x0d, 0x7a,
+	0x81, 0x67, 0x60, 0xb1, 0x94, 0x51, 0x64, 0x28, 0xb5, 0x49, 0xe2, 0x00, 0x8d, 0x94, 0x5a, 0x6e,
+	0xe2, 0x0c, 0x99, 0x78, 0x0a, 0x66, 0xb2, 0xf9, 0x8c, 0xac, 0xeb, 0x5f, 0x06, 0xc0, 0xf3, 0x93,
+	0x28, 0x6c, 0x46, 0xc2, 0x15, 0x59, 0x52, 0xf1, 0x35, 0xa3, 0xff, 0xc1, 0xbe, 0x84, 0x8b, 0x88,
+	0xb0, 0x45, 0x1e, 0x91, 0x95, 0x62, 0xbb, 0xf0, 0x3a, 0x4c, 0x62, 0xca, 0x04, 0x29, 0x44, 0x44,
+	0x99, 0x88, 0x43, 0x22, 0xe2, 0x94, 0xa1, 0x91, 0x4a, 0x20, 0xe1, 0x0a, 0x99, 0x18, 0x81, 0x93,
+	0x17, 0x41, 0x1e, 0xf2, 0x38, 0xd3, 0x96, 0x85, 0x31, 0x5c, 0x16, 0xec, 0x9f, 0xb3, 0xb1, 0x6a,
+	0x5f, 0x52, 0x81, 0x26, 0xd8, 0x81, 0xd9, 0x9a, 0xe6, 0x39, 0x59, 0xd2, 0x1c, 0x4d, 0x55, 0xdc,
+	0x23, 0x3f, 0x5c, 0xa1, 0x99, 0x32, 0xf3, 0xa8, 0x10, 0x8b, 0xf4, 0x0b, 0x43, 0x17, 0x6a, 0x9d,
+	0x45, 0xb1, 0xce, 0x10, 0xe8, 0x5b, 0x51, 0xc2, 0x45, 0x40, 0x89, 0x40, 0xb6, 0x0a, 0x38, 0x4e,
+	0xf1, 0x34, 0x49, 0x02, 0x35, 0xea, 0x04, 0x9f, 0xe0, 0xec, 0x9f, 0x0c, 0xec, 0xe3, 0x57, 0xd6,
+	0xcf, 0x11, 0x19, 0x9b, 0xb1, 0xb6, 0xab, 0x89, 0xf6, 0x3f, 0xfe, 0x19, 0x00, 0xeb, 0x26, 0xf5,
+	0x41, 0xe9, 0x02, 0x00, 0x00,
+}
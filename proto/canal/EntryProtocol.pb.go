@@ -0,0 +1,722 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: EntryProtocol.proto
+
+package canal
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type EntryType int32
+
+const (
+	EntryType_ENTRYTYPECOMPATIBLEPROTO2 EntryType = 0
+	EntryType_TRANSACTIONBEGIN          EntryType = 1
+	EntryType_ROWDATA                   EntryType = 2
+	EntryType_TRANSACTIONEND            EntryType = 3
+	EntryType_HEARTBEAT                 EntryType = 4
+	EntryType_GTIDLOG                   EntryType = 5
+)
+
+var EntryType_name = map[int32]string{
+	0: "ENTRYTYPECOMPATIBLEPROTO2",
+	1: "TRANSACTIONBEGIN",
+	2: "ROWDATA",
+	3: "TRANSACTIONEND",
+	4: "HEARTBEAT",
+	5: "GTIDLOG",
+}
+
+var EntryType_value = map[string]int32{
+	"ENTRYTYPECOMPATIBLEPROTO2": 0,
+	"TRANSACTIONBEGIN":          1,
+	"ROWDATA":                   2,
+	"TRANSACTIONEND":            3,
+	"HEARTBEAT":                 4,
+	"GTIDLOG":                   5,
+}
+
+func (x EntryType) String() string {
+	return proto.EnumName(EntryType_name, int32(x))
+}
+
+func (EntryType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_237ce6ff565bd62b, []int{0}
+}
+
+type EventType int32
+
+const (
+	EventType_EVENTTYPECOMPATIBLEPROTO2 EventType = 0
+	EventType_INSERT                    EventType = 1
+	EventType_UPDATE                    EventType = 2
+	EventType_DELETE                    EventType = 3
+	EventType_CREATE                    EventType = 4
+	EventType_ALTER                     EventType = 5
+	EventType_ERASE                     EventType = 6
+	EventType_QUERY                     EventType = 7
+	EventType_TRUNCATE                  EventType = 8
+	EventType_RENAME                    EventType = 9
+	EventType_CINDEX                    EventType = 10
+	EventType_DINDEX                    EventType = 11
+	EventType_GTID                      EventType = 12
+	EventType_XACOMMIT                  EventType = 13
+	EventType_XAROLLBACK                EventType = 14
+	EventType_MHEARTBEAT                EventType = 15
+)
+
+var EventType_name = map[int32]string{
+	0:  "EVENTTYPECOMPATIBLEPROTO2",
+	1:  "INSERT",
+	2:  "UPDATE",
+	3:  "DELETE",
+	4:  "CREATE",
+	5:  "ALTER",
+	6:  "ERASE",
+	7:  "QUERY",
+	8:  "TRUNCATE",
+	9:  "RENAME",
+	10: "CINDEX",
+	11: "DINDEX",
+	12: "GTID",
+	13: "XACOMMIT",
+	14: "XAROLLBACK",
+	15: "MHEARTBEAT",
+}
+
+var EventType_value = map[string]int32{
+	"EVENTTYPECOMPATIBLEPROTO2": 0,
+	"INSERT":                    1,
+	"UPDATE":                    2,
+	"DELETE":                    3,
+	"CREATE":                    4,
+	"ALTER":                     5,
+	"ERASE":                     6,
+	"QUERY":                     7,
+	"TRUNCATE":                  8,
+	"RENAME":                    9,
+	"CINDEX":                    10,
+	"DINDEX":                    11,
+	"GTID":                      12,
+	"XACOMMIT":                  13,
+	"XAROLLBACK":                14,
+	"MHEARTBEAT":                15,
+}
+
+func (x EventType) String() string {
+	return proto.EnumName(EventType_name, int32(x))
+}
+
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_237ce6ff565bd62b, []int{1}
+}
+
+type Type int32
+
+const (
+	Type_TYPECOMPATIBLEPROTO2 Type = 0
+	Type_ORACLE               Type = 1
+	Type_MYSQL                Type = 2
+	Type_PGSQL                Type = 3
+)
+
+var Type_name = map[int32]string{
+	0: "TYPECOMPATIBLEPROTO2",
+	1: "ORACLE",
+	2: "MYSQL",
+	3: "PGSQL",
+}
+
+var Type_value = map[string]int32{
+	"TYPECOMPATIBLEPROTO2": 0,
+	"ORACLE":               1,
+	"MYSQL":                2,
+	"PGSQL":                3,
+}
+
+func (x Type) String() string {
+	return proto.EnumName(Type_name, int32(x))
+}
+
+func (Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_237ce6ff565bd62b, []int{2}
+}
+
+type Entry struct {
+	Header               *Header   `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	EntryType            EntryType `protobuf:"varint,2,opt,name=entryType,proto3,enum=com.alibaba.otter.canal.protocol.EntryType" json:"entryType,omitempty"`
+	StoreValue           []byte    `protobuf:"bytes,3,opt,name=storeValue,proto3" json:"storeValue,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *Entry) Reset()         { *m = Entry{} }
+func (m *Entry) String() string { return proto.CompactTextString(m) }
+func (*Entry) ProtoMessage()    {}
+func (*Entry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_237ce6ff565bd62b, []int{0}
+}
+
+func (m *Entry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Entry.Unmarshal(m, b)
+}
+func (m *Entry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Entry.Marshal(b, m, deterministic)
+}
+func (m *Entry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Entry.Merge(m, src)
+}
+func (m *Entry) XXX_Size() int {
+	return xxx_messageInfo_Entry.Size(m)
+}
+func (m *Entry) XXX_DiscardUnknown() {
+	xxx_messageInfo_Entry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Entry proto.InternalMessageInfo
+
+func (m *Entry) GetHeader() *Header {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *Entry) GetEntryType() EntryType {
+	if m != nil {
+		return m.EntryType
+	}
+	return EntryType_ENTRYTYPECOMPATIBLEPROTO2
+}
+
+func (m *Entry) GetStoreValue() []byte {
+	if m != nil {
+		return m.StoreValue
+	}
+	return nil
+}
+
+type Header struct {
+	Version              int32     `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	LogfileName          string    `protobuf:"bytes,2,opt,name=logfileName,proto3" json:"logfileName,omitempty"`
+	LogfileOffset        int64     `protobuf:"varint,3,opt,name=logfileOffset,proto3" json:"logfileOffset,omitempty"`
+	ServerId             int64     `protobuf:"varint,4,opt,name=serverId,proto3" json:"serverId,omitempty"`
+	ServerenCode         string    `protobuf:"bytes,5,opt,name=serverenCode,proto3" json:"serverenCode,omitempty"`
+	ExecuteTime          int64     `protobuf:"varint,6,opt,name=executeTime,proto3" json:"executeTime,omitempty"`
+	SourceType           Type      `protobuf:"varint,7,opt,name=sourceType,proto3,enum=com.alibaba.otter.canal.protocol.Type" json:"sourceType,omitempty"`
+	SchemaName           string    `protobuf:"bytes,8,opt,name=schemaName,proto3" json:"schemaName,omitempty"`
+	TableName            string    `protobuf:"bytes,9,opt,name=tableName,proto3" json:"tableName,omitempty"`
+	EventLength          int64     `protobuf:"varint,10,opt,name=eventLength,proto3" json:"eventLength,omitempty"`
+	EventType            EventType `protobuf:"varint,11,opt,name=eventType,proto3,enum=com.alibaba.otter.canal.protocol.EventType" json:"eventType,omitempty"`
+	Props                []*Pair   `protobuf:"bytes,12,rep,name=props,proto3" json:"props,omitempty"`
+	Gtid                 string    `protobuf:"bytes,13,opt,name=gtid,proto3" json:"gtid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *Header) Reset()         { *m = Header{} }
+func (m *Header) String() string { return proto.CompactTextString(m) }
+func (*Header) ProtoMessage()    {}
+func (*Header) Descriptor() ([]byte, []int) {
+	return fileDescriptor_237ce6ff565bd62b, []int{1}
+}
+
+func (m *Header) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Header.Unmarshal(m, b)
+}
+func (m *Header) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Header.Marshal(b, m, deterministic)
+}
+func (m *Header) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Header.Merge(m, src)
+}
+func (m *Header) XXX_Size() int {
+	return xxx_messageInfo_Header.Size(m)
+}
+func (m *Header) XXX_DiscardUnknown() {
+	xxx_messageInfo_Header.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Header proto.InternalMessageInfo
+
+func (m *Header) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Header) GetLogfileName() string {
+	if m != nil {
+		return m.LogfileName
+	}
+	return ""
+}
+
+func (m *Header) GetLogfileOffset() int64 {
+	if m != nil {
+		return m.LogfileOffset
+	}
+	return 0
+}
+
+func (m *Header) GetServerId() int64 {
+	if m != nil {
+		return m.ServerId
+	}
+	return 0
+}
+
+func (m *Header) GetServerenCode() string {
+	if m != nil {
+		return m.ServerenCode
+	}
+	return ""
+}
+
+func (m *Header) GetExecuteTime() int64 {
+	if m != nil {
+		return m.ExecuteTime
+	}
+	return 0
+}
+
+func (m *Header) GetSourceType() Type {
+	if m != nil {
+		return m.SourceType
+	}
+	return Type_TYPECOMPATIBLEPROTO2
+}
+
+func (m *Header) GetSchemaName() string {
+	if m != nil {
+		return m.SchemaName
+	}
+	return ""
+}
+
+func (m *Header) GetTableName() string {
+	if m != nil {
+		return m.TableName
+	}
+	return ""
+}
+
+func (m *Header) GetEventLength() int64 {
+	if m != nil {
+		return m.EventLength
+	}
+	return 0
+}
+
+func (m *Header) GetEventType() EventType {
+	if m != nil {
+		return m.EventType
+	}
+	return EventType_EVENTTYPECOMPATIBLEPROTO2
+}
+
+func (m *Header) GetProps() []*Pair {
+	if m != nil {
+		return m.Props
+	}
+	return nil
+}
+
+func (m *Header) GetGtid() string {
+	if m != nil {
+		return m.Gtid
+	}
+	return ""
+}
+
+type Column struct {
+	Index                int32    `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	SqlType              int32    `protobuf:"varint,2,opt,name=sqlType,proto3" json:"sqlType,omitempty"`
+	Name                 string   `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	IsKey                bool     `protobuf:"varint,4,opt,name=isKey,proto3" json:"isKey,omitempty"`
+	Updated              bool     `protobuf:"varint,5,opt,name=updated,proto3" json:"updated,omitempty"`
+	IsNull               bool     `protobuf:"varint,6,opt,name=isNull,proto3" json:"isNull,omitempty"`
+	Props                []*Pair  `protobuf:"bytes,7,rep,name=props,proto3" json:"props,omitempty"`
+	Value                string   `protobuf:"bytes,8,opt,name=value,proto3" json:"value,omitempty"`
+	Length               int32    `protobuf:"varint,9,opt,name=length,proto3" json:"length,omitempty"`
+	MysqlType            string   `protobuf:"bytes,10,opt,name=mysqlType,proto3" json:"mysqlType,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Column) Reset()         { *m = Column{} }
+func (m *Column) String() string { return proto.CompactTextString(m) }
+func (*Column) ProtoMessage()    {}
+func (*Column) Descriptor() ([]byte, []int) {
+	return fileDescriptor_237ce6ff565bd62b, []int{2}
+}
+
+func (m *Column) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Column.Unmarshal(m, b)
+}
+func (m *Column) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Column.Marshal(b, m, deterministic)
+}
+func (m *Column) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Column.Merge(m, src)
+}
+func (m *Column) XXX_Size() int {
+	return xxx_messageInfo_Column.Size(m)
+}
+func (m *Column) XXX_DiscardUnknown() {
+	xxx_messageInfo_Column.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Column proto.InternalMessageInfo
+
+func (m *Column) GetIndex() int32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *Column) GetSqlType() int32 {
+	if m != nil {
+		return m.SqlType
+	}
+	return 0
+}
+
+func (m *Column) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Column) GetIsKey() bool {
+	if m != nil {
+		return m.IsKey
+	}
+	return false
+}
+
+func (m *Column) GetUpdated() bool {
+	if m != nil {
+		return m.Updated
+	}
+	return false
+}
+
+func (m *Column) GetIsNull() bool {
+	if m != nil {
+		return m.IsNull
+	}
+	return false
+}
+
+func (m *Column) GetProps() []*Pair {
+	if m != nil {
+		return m.Props
+	}
+	return nil
+}
+
+func (m *Column) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *Column) GetLength() int32 {
+	if m != nil {
+		return m.Length
+	}
+	return 0
+}
+
+func (m *Column) GetMysqlType() string {
+	if m != nil {
+		return m.MysqlType
+	}
+	return ""
+}
+
+type RowData struct {
+	BeforeColumns        []*Column `protobuf:"bytes,1,rep,name=beforeColumns,proto3" json:"beforeColumns,omitempty"`
+	AfterColumns         []*Column `protobuf:"bytes,2,rep,name=afterColumns,proto3" json:"afterColumns,omitempty"`
+	Props                []*Pair   `protobuf:"bytes,3,rep,name=props,proto3" json:"props,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *RowData) Reset()         { *m = RowData{} }
+func (m *RowData) String() string { return proto.CompactTextString(m) }
+func (*RowData) ProtoMessage()    {}
+func (*RowData) Descriptor() ([]byte, []int) {
+	return fileDescriptor_237ce6ff565bd62b, []int{3}
+}
+
+func (m *RowData) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RowData.Unmarshal(m, b)
+}
+func (m *RowData) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RowData.Marshal(b, m, deterministic)
+}
+func (m *RowData) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RowData.Merge(m, src)
+}
+func (m *RowData) XXX_Size() int {
+	return xxx_messageInfo_RowData.Size(m)
+}
+func (m *RowData) XXX_DiscardUnknown() {
+	xxx_messageInfo_RowData.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RowData proto.InternalMessageInfo
+
+func (m *RowData) GetBeforeColumns() []*Column {
+	if m != nil {
+		return m.BeforeColumns
+	}
+	return nil
+}
+
+func (m *RowData) GetAfterColumns() []*Column {
+	if m != nil {
+		return m.AfterColumns
+	}
+	return nil
+}
+
+func (m *RowData) GetProps() []*Pair {
+	if m != nil {
+		return m.Props
+	}
+	return nil
+}
+
+type RowChange struct {
+	TableId              int64      `protobuf:"varint,1,opt,name=tableId,proto3" json:"tableId,omitempty"`
+	EventType            EventType  `protobuf:"varint,2,opt,name=eventType,proto3,enum=com.alibaba.otter.canal.protocol.EventType" json:"eventType,omitempty"`
+	IsDdl                bool       `protobuf:"varint,10,opt,name=isDdl,proto3" json:"isDdl,omitempty"`
+	Sql                  string     `protobuf:"bytes,11,opt,name=sql,proto3" json:"sql,omitempty"`
+	RowDatas             []*RowData `protobuf:"bytes,12,rep,name=rowDatas,proto3" json:"rowDatas,omitempty"`
+	Props                []*Pair    `protobuf:"bytes,13,rep,name=props,proto3" json:"props,omitempty"`
+	DdlSchemaName        string     `protobuf:"bytes,14,opt,name=ddlSchemaName,proto3" json:"ddlSchemaName,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *RowChange) Reset()         { *m = RowChange{} }
+func (m *RowChange) String() string { return proto.CompactTextString(m) }
+func (*RowChange) ProtoMessage()    {}
+func (*RowChange) Descriptor() ([]byte, []int) {
+	return fileDescriptor_237ce6ff565bd62b, []int{4}
+}
+
+func (m *RowChange) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RowChange.Unmarshal(m, b)
+}
+func (m *RowChange) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RowChange.Marshal(b, m, deterministic)
+}
+func (m *RowChange) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RowChange.Merge(m, src)
+}
+func (m *RowChange) XXX_Size() int {
+	return xxx_messageInfo_RowChange.Size(m)
+}
+func (m *RowChange) XXX_DiscardUnknown() {
+	xxx_messageInfo_RowChange.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RowChange proto.InternalMessageInfo
+
+func (m *RowChange) GetTableId() int64 {
+	if m != nil {
+		return m.TableId
+	}
+	return 0
+}
+
+func (m *RowChange) GetEventType() EventType {
+	if m != nil {
+		return m.EventType
+	}
+	return EventType_EVENTTYPECOMPATIBLEPROTO2
+}
+
+func (m *RowChange) GetIsDdl() bool {
+	if m != nil {
+		return m.IsDdl
+	}
+	return false
+}
+
+func (m *RowChange) GetSql() string {
+	if m != nil {
+		return m.Sql
+	}
+	return ""
+}
+
+func (m *RowChange) GetRowDatas() []*RowData {
+	if m != nil {
+		return m.RowDatas
+	}
+	return nil
+}
+
+func (m *RowChange) GetProps() []*Pair {
+	if m != nil {
+		return m.Props
+	}
+	return nil
+}
+
+func (m *RowChange) GetDdlSchemaName() string {
+	if m != nil {
+		return m.DdlSchemaName
+	}
+	return ""
+}
+
+type Pair struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value                string   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Pair) Reset()         { *m = Pair{} }
+func (m *Pair) String() string { return proto.CompactTextString(m) }
+func (*Pair) ProtoMessage()    {}
+func (*Pair) Descriptor() ([]byte, []int) {
+	return fileDescriptor_237ce6ff565bd62b, []int{5}
+}
+
+func (m *Pair) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Pair.Unmarshal(m, b)
+}
+func (m *Pair) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Pair.Marshal(b, m, deterministic)
+}
+func (m *Pair) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Pair.Merge(m, src)
+}
+func (m *Pair) XXX_Size() int {
+	return xxx_messageInfo_Pair.Size(m)
+}
+func (m *Pair) XXX_DiscardUnknown() {
+	xxx_messageInfo_Pair.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Pair proto.InternalMessageInfo
+
+func (m *Pair) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Pair) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterEnum("com.alibaba.otter.canal.protocol.EntryType", EntryType_name, EntryType_value)
+	proto.RegisterEnum("com.alibaba.otter.canal.protocol.EventType", EventType_name, EventType_value)
+	proto.RegisterEnum("com.alibaba.otter.canal.protocol.Type", Type_name, Type_value)
+	proto.RegisterType((*Entry)(nil), "com.alibaba.otter.canal.protocol.Entry")
+	proto.RegisterType((*Header)(nil), "com.alibaba.otter.canal.protocol.Header")
+	proto.RegisterType((*Column)(nil), "com.alibaba.otter.canal.protocol.Column")
+	proto.RegisterType((*RowData)(nil), "com.alibaba.otter.canal.protocol.RowData")
+	proto.RegisterType((*RowChange)(nil), "com.alibaba.otter.canal.protocol.RowChange")
+	proto.RegisterType((*Pair)(nil), "com.alibaba.otter.canal.protocol.Pair")
+}
+
+func init() { proto.RegisterFile("EntryProtocol.proto", fileDescriptor_237ce6ff565bd62b) }
+
+var fileDescriptor_237ce6ff565bd62b = []byte{
+	// 919 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x56, 0xc1, 0x8e, 0xe2, 0x46,
+	0x10, 0x8d, 0x31, 0x06, 0x5c, 0xc0, 0xa4, 0xd5, 0x19, 0x45, 0x4e, 0x94, 0x44, 0x08, 0xad, 0xa2,
+	0xc9, 0x46, 0xe2, 0x30, 0x39, 0xe4, 0x92, 0x43, 0x8c, 0xe9, 0xcc, 0x58, 0x6b, 0x6c, 0xb6, 0xf1,
+	0x6c, 0x66, 0x72, 0x33, 0xb8, 0x99, 0x41, 0x31, 0x36, 0x6b, 0x9b, 0xd9, 0xe5, 0x90, 0x8f, 0xc8,
+	0x0f, 0xe4, 0x2b, 0xf2, 0x07, 0xf9, 0x96, 0xfd, 0x8f, 0xa8, 0xcb, 0x18, 0xc3, 0x61, 0xc5, 0x64,
+	0x72, 0x7b, 0xaf, 0xba, 0x5f, 0x75, 0x55, 0xbd, 0x32, 0x02, 0x3e, 0x63, 0x71, 0x9e, 0x6e, 0x27,
+	0x69, 0x92, 0x27, 0xf3, 0x24, 0x1a, 0xac, 0x25, 0xa0, 0xbd, 0x79, 0xb2, 0x1a, 0x04, 0xd1, 0x72,
+	0x16, 0xcc, 0x82, 0x41, 0x92, 0xe7, 0x22, 0x1d, 0xcc, 0x83, 0x38, 0xd8, 0x1d, 0xcf, 0x93, 0xa8,
+	0xff, 0xb7, 0x02, 0x1a, 0x2a, 0xe9, 0xcf, 0xd0, 0x78, 0x10, 0x41, 0x28, 0x52, 0x43, 0xe9, 0x29,
+	0x17, 0xed, 0xcb, 0x8b, 0xc1, 0x29, 0xf1, 0xe0, 0x1a, 0xef, 0xf3, 0x9d, 0x8e, 0xda, 0xa0, 0x0b,
+	0x99, 0xca, 0xdf, 0xae, 0x85, 0x51, 0xeb, 0x29, 0x17, 0x67, 0x97, 0xdf, 0x9f, 0x4e, 0xc2, 0x4a,
+	0x09, 0xaf, 0xd4, 0xf4, 0x1b, 0x80, 0x2c, 0x4f, 0x52, 0xf1, 0x26, 0x88, 0x36, 0xc2, 0x50, 0x7b,
+	0xca, 0x45, 0x87, 0x1f, 0x44, 0xfa, 0x7f, 0xd6, 0xa1, 0x51, 0xbc, 0x4e, 0x0d, 0x68, 0x3e, 0x8a,
+	0x34, 0x5b, 0x26, 0x31, 0x16, 0xae, 0xf1, 0x92, 0xd2, 0x1e, 0xb4, 0xa3, 0xe4, 0x7e, 0xb1, 0x8c,
+	0x84, 0x1b, 0xac, 0x8a, 0x8a, 0x74, 0x7e, 0x18, 0xa2, 0x2f, 0xa0, 0xbb, 0xa3, 0xde, 0x62, 0x91,
+	0x89, 0x1c, 0x5f, 0x52, 0xf9, 0x71, 0x90, 0x7e, 0x09, 0xad, 0x4c, 0xa4, 0x8f, 0x22, 0xb5, 0x43,
+	0xa3, 0x8e, 0x17, 0xf6, 0x9c, 0xf6, 0xa1, 0x53, 0x60, 0x11, 0x5b, 0x49, 0x28, 0x0c, 0x0d, 0x1f,
+	0x39, 0x8a, 0xc9, 0x3a, 0xc4, 0x7b, 0x31, 0xdf, 0xe4, 0xc2, 0x5f, 0xae, 0x84, 0xd1, 0xc0, 0x14,
+	0x87, 0x21, 0xfa, 0x0b, 0x40, 0x96, 0x6c, 0xd2, 0xb9, 0xc0, 0xd1, 0x35, 0x71, 0x74, 0xdf, 0x9e,
+	0x1e, 0x1d, 0x4e, 0xed, 0x40, 0x89, 0x63, 0x9b, 0x3f, 0x88, 0x55, 0x80, 0x0d, 0xb7, 0xb0, 0x96,
+	0x83, 0x08, 0xfd, 0x0a, 0xf4, 0x3c, 0x98, 0xed, 0xe6, 0xa1, 0xe3, 0x71, 0x15, 0xc0, 0x3a, 0x1f,
+	0x45, 0x9c, 0x3b, 0x22, 0xbe, 0xcf, 0x1f, 0x0c, 0xd8, 0xd5, 0x59, 0x85, 0xd0, 0x61, 0x49, 0xb1,
+	0xcc, 0xf6, 0x93, 0x1d, 0x2e, 0x25, 0xbc, 0x52, 0xd3, 0x9f, 0x40, 0x5b, 0xa7, 0xc9, 0x3a, 0x33,
+	0x3a, 0x3d, 0xf5, 0xa2, 0xfd, 0x94, 0x6e, 0x27, 0xc1, 0x32, 0xe5, 0x85, 0x88, 0x52, 0xa8, 0xdf,
+	0xe7, 0xcb, 0xd0, 0xe8, 0x62, 0x0f, 0x88, 0xfb, 0x7f, 0xd5, 0xa0, 0x61, 0x25, 0xd1, 0x66, 0x15,
+	0xd3, 0x73, 0xd0, 0x96, 0x71, 0x28, 0xde, 0xef, 0x36, 0xa2, 0x20, 0x72, 0x53, 0xb2, 0xb7, 0xd1,
+	0x7e, 0x3b, 0x35, 0x5e, 0x52, 0x99, 0x2e, 0x96, 0x23, 0x51, 0x8b, 0x74, 0x12, 0x63, 0x8e, 0xec,
+	0x95, 0xd8, 0xa2, 0xe5, 0x2d, 0x5e, 0x10, 0x99, 0x63, 0xb3, 0x0e, 0x83, 0x5c, 0x84, 0x68, 0x75,
+	0x8b, 0x97, 0x94, 0x7e, 0x0e, 0x8d, 0x65, 0xe6, 0x6e, 0xa2, 0x08, 0x0d, 0x6e, 0xf1, 0x1d, 0xab,
+	0x1a, 0x6d, 0x3e, 0xa7, 0xd1, 0x73, 0xd0, 0x1e, 0xf1, 0x1b, 0x28, 0xcc, 0x2c, 0x88, 0x7c, 0x2b,
+	0x2a, 0x4c, 0xd2, 0xb1, 0x91, 0x1d, 0x93, 0xfe, 0xae, 0xb6, 0x65, 0x8f, 0x50, 0xf8, 0xbb, 0x0f,
+	0xf4, 0x3f, 0x28, 0xd0, 0xe4, 0xc9, 0xbb, 0x51, 0x90, 0x07, 0xd4, 0x85, 0xee, 0x4c, 0x2c, 0x92,
+	0x54, 0x14, 0x13, 0xcb, 0x0c, 0xa5, 0xa7, 0x3e, 0xed, 0xa3, 0x2f, 0x04, 0xfc, 0x58, 0x4e, 0x1d,
+	0xe8, 0x04, 0x8b, 0x5c, 0xa4, 0x65, 0xba, 0xda, 0x7f, 0x4c, 0x77, 0xa4, 0xae, 0x66, 0xa6, 0x3e,
+	0x63, 0x66, 0xfd, 0x7f, 0x6a, 0xa0, 0xf3, 0xe4, 0x9d, 0xf5, 0x10, 0xc4, 0xf7, 0x42, 0x3a, 0x86,
+	0x2b, 0x6e, 0x87, 0xb8, 0x0d, 0x2a, 0x2f, 0xe9, 0xf1, 0x36, 0xd7, 0xfe, 0xd7, 0x36, 0xe3, 0xb2,
+	0x8c, 0xc2, 0xc8, 0x80, 0x72, 0x59, 0x46, 0x61, 0x44, 0x09, 0xa8, 0xd9, 0xdb, 0x08, 0x3f, 0x14,
+	0x9d, 0x4b, 0x48, 0x19, 0xb4, 0xd2, 0xc2, 0x81, 0x72, 0xf1, 0xbf, 0x3b, 0xfd, 0xe2, 0xce, 0x33,
+	0xbe, 0x97, 0x56, 0xf3, 0xe9, 0x3e, 0x67, 0xa7, 0x5e, 0x40, 0x37, 0x0c, 0xa3, 0x69, 0xf5, 0x43,
+	0x71, 0x86, 0x05, 0x1e, 0x07, 0xfb, 0x03, 0xa8, 0x4b, 0x91, 0x6c, 0xe2, 0x77, 0xb1, 0xc5, 0xd9,
+	0xe9, 0x5c, 0xc2, 0x6a, 0x27, 0x6b, 0x07, 0x3b, 0xf9, 0xf2, 0x0f, 0xd0, 0xf7, 0x3f, 0xe5, 0xf4,
+	0x6b, 0xf8, 0x82, 0xb9, 0x3e, 0xbf, 0xf3, 0xef, 0x26, 0xcc, 0xf2, 0xc6, 0x13, 0xd3, 0xb7, 0x87,
+	0x0e, 0x9b, 0x70, 0xcf, 0xf7, 0x2e, 0xc9, 0x27, 0xf4, 0x1c, 0x88, 0xcf, 0x4d, 0x77, 0x6a, 0x5a,
+	0xbe, 0xed, 0xb9, 0x43, 0x76, 0x65, 0xbb, 0x44, 0xa1, 0x6d, 0x68, 0x72, 0xef, 0xd7, 0x91, 0xe9,
+	0x9b, 0xa4, 0x46, 0x29, 0x9c, 0x1d, 0x5c, 0x61, 0xee, 0x88, 0xa8, 0xb4, 0x0b, 0xfa, 0x35, 0x33,
+	0xb9, 0x3f, 0x64, 0xa6, 0x4f, 0xea, 0xf2, 0xfe, 0x95, 0x6f, 0x8f, 0x1c, 0xef, 0x8a, 0x68, 0x2f,
+	0x3f, 0x28, 0xa0, 0xef, 0xad, 0xc1, 0xf7, 0xdf, 0x30, 0xd7, 0xff, 0xc8, 0xfb, 0x00, 0x0d, 0xdb,
+	0x9d, 0x32, 0xee, 0x13, 0x45, 0xe2, 0x9b, 0xc9, 0xc8, 0xf4, 0x19, 0xa9, 0x49, 0x3c, 0x62, 0x0e,
+	0xf3, 0x19, 0x51, 0x25, 0xb6, 0x38, 0x93, 0xf1, 0x3a, 0xd5, 0x41, 0x33, 0x1d, 0x9f, 0x71, 0xa2,
+	0x49, 0xc8, 0xb8, 0x39, 0x65, 0xa4, 0x21, 0xe1, 0xeb, 0x1b, 0xc6, 0xef, 0x48, 0x93, 0x76, 0xa0,
+	0xe5, 0xf3, 0x1b, 0xd7, 0x92, 0xd7, 0x5b, 0x52, 0xca, 0x99, 0x6b, 0x8e, 0x19, 0xd1, 0x31, 0x8d,
+	0xed, 0x8e, 0xd8, 0x2d, 0x01, 0x4c, 0x5f, 0xe0, 0x36, 0x6d, 0x41, 0x5d, 0x16, 0x4f, 0x3a, 0x52,
+	0x7b, 0x6b, 0x5a, 0xde, 0x78, 0x6c, 0xfb, 0xa4, 0x4b, 0xcf, 0x00, 0x6e, 0x4d, 0xee, 0x39, 0xce,
+	0xd0, 0xb4, 0x5e, 0x91, 0x33, 0xc9, 0xc7, 0x55, 0xd3, 0x9f, 0xbe, 0x1c, 0x42, 0x1d, 0x3b, 0x34,
+	0xe0, 0xfc, 0xe3, 0xcd, 0x79, 0xdc, 0xb4, 0x1c, 0x46, 0x14, 0x59, 0xe2, 0xf8, 0x6e, 0xfa, 0xda,
+	0x21, 0x35, 0x09, 0x27, 0x57, 0x12, 0xaa, 0xc3, 0x1f, 0xe1, 0xe4, 0x1f, 0x83, 0x21, 0x58, 0x92,
+	0xa3, 0xa3, 0xd7, 0xca, 0x6f, 0x1a, 0x9e, 0xce, 0x1a, 0x78, 0xfc, 0xc3, 0xbf, 0x03, 0x00, 0x84,
+	0x02, 0x6a, 0x4f, 0x6d, 0x08, 0x00, 0x00,
+}
@@ -0,0 +1,180 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/sink/mqConsumer"
+	"github.com/pingcap/ticdc/pkg/util"
+	"go.uber.org/zap"
+)
+
+// Pulsar configuration options
+var (
+	pulsarURL              string
+	pulsarTopic            string
+	pulsarPartitionNum     int32
+	pulsarSubscriptionName = "ticdc_pulsar_consumer"
+
+	downstreamURIStr string
+
+	logPath  string
+	logLevel string
+)
+
+func init() {
+	var upstreamURIStr string
+
+	flag.StringVar(&upstreamURIStr, "upstream-uri", "", "Pulsar uri")
+	flag.StringVar(&downstreamURIStr, "downstream-uri", "", "downstream sink uri")
+	flag.StringVar(&logPath, "log-file", "cdc_pulsar_consumer.log", "log file path")
+	flag.StringVar(&logLevel, "log-level", "info", "log level")
+	flag.Parse()
+
+	err := util.InitLogger(&util.Config{
+		Level: logLevel,
+		File:  logPath,
+	})
+	if err != nil {
+		log.Fatal("init logger failed", zap.Error(err))
+	}
+
+	upstreamURI, err := url.Parse(upstreamURIStr)
+	if err != nil {
+		log.Fatal("invalid upstream-uri", zap.Error(err))
+	}
+	scheme := strings.ToLower(upstreamURI.Scheme)
+	if scheme != "pulsar" && scheme != "pulsar+ssl" {
+		log.Fatal("invalid upstream-uri scheme, the scheme of upstream-uri must be `pulsar` or `pulsar+ssl`")
+	}
+	s := upstreamURI.Query().Get("partition-num")
+	if s != "" {
+		c, err := strconv.Atoi(s)
+		if err != nil {
+			log.Fatal("invalid partition-num of upstream-uri")
+		}
+		pulsarPartitionNum = int32(c)
+	}
+	s = upstreamURI.Query().Get("subscription-name")
+	if s != "" {
+		pulsarSubscriptionName = s
+	}
+	pulsarTopic = strings.TrimFunc(upstreamURI.Path, func(r rune) bool {
+		return r == '/'
+	})
+	pulsarURL = scheme + "://" + upstreamURI.Host
+}
+
+func main() {
+	log.Info("Starting a new TiCDC open protocol consumer for pulsar")
+
+	client, err := pulsar.NewClient(pulsar.ClientOptions{URL: pulsarURL})
+	if err != nil {
+		log.Fatal("Error creating pulsar client", zap.Error(err))
+	}
+	defer client.Close()
+
+	// the messages are dispatched to the partitions by the producer,
+	// so each partition is consumed separately to keep the order.
+	partitions, err := client.TopicPartitions(pulsarTopic)
+	if err != nil {
+		log.Fatal("Error getting partitions of topic", zap.Error(err))
+	}
+	if pulsarPartitionNum == 0 {
+		pulsarPartitionNum = int32(len(partitions))
+	} else if int(pulsarPartitionNum) > len(partitions) {
+		log.Fatal("partition-num of upstream-uri is more than that of topic",
+			zap.Int32("partition-num", pulsarPartitionNum), zap.Int("topic partition-num", len(partitions)))
+	}
+
+	consumer, err := NewConsumer()
+	if err != nil {
+		log.Fatal("Error creating consumer", zap.Error(err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	for i := int32(0); i < pulsarPartitionNum; i++ {
+		pc, err := client.Subscribe(pulsar.ConsumerOptions{
+			Topic:                       partitions[i],
+			SubscriptionName:            pulsarSubscriptionName,
+			Type:                        pulsar.Failover,
+			SubscriptionInitialPosition: pulsar.SubscriptionPositionEarliest,
+		})
+		if err != nil {
+			log.Fatal("Error subscribing partition", zap.String("topic", partitions[i]), zap.Error(err))
+		}
+		wg.Add(1)
+		go func(partition int32) {
+			defer wg.Done()
+			defer pc.Close()
+			if err := consumePartition(ctx, consumer, partition, pc); err != nil && errors.Cause(err) != context.Canceled {
+				log.Fatal("Error from consumer", zap.Error(err))
+			}
+		}(i)
+	}
+
+	go func() {
+		if err := consumer.Run(ctx); err != nil && errors.Cause(err) != context.Canceled {
+			log.Fatal("Error running consumer", zap.Error(err))
+		}
+	}()
+
+	log.Info("TiCDC open protocol consumer for pulsar up and running!...")
+
+	sigterm := make(chan os.Signal, 1)
+	signal.Notify(sigterm, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-ctx.Done():
+		log.Info("terminating: context cancelled")
+	case <-sigterm:
+		log.Info("terminating: via signal")
+	}
+	cancel()
+	wg.Wait()
+}
+
+// NewConsumer creates a new cdc pulsar consumer
+func NewConsumer() (*mqConsumer.Consumer, error) {
+	return mqConsumer.NewConsumer(downstreamURIStr, pulsarPartitionNum)
+}
+
+// consumePartition receives and replays the messages of the partition until the context is done
+func consumePartition(ctx context.Context, consumer *mqConsumer.Consumer, partition int32, pc pulsar.Consumer) error {
+	for {
+		message, err := pc.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errors.Trace(err)
+		}
+		if err := consumer.ConsumeMessage(ctx, partition, []byte(message.Key()), message.Payload()); err != nil {
+			return errors.Annotatef(err, "consume message %s", message.ID())
+		}
+		pc.Ack(message)
+	}
+}